@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultMaxUpload is the largest file uploaded, in bytes, when not set
+	defaultMaxUpload = 100 << 20
+	defaultPageSize  = 50
+	maxPageSize      = 500
+)
+
+// Options changes how the API behaves
+type Options struct {
+	// MaxUpload is the largest file uploaded, in bytes
+	MaxUpload int64
+}
+
+// TransactionReader is what the API reads the transactions from
+type TransactionReader interface {
+	GetByID(ctx context.Context, id string) (*model.Transaction, error)
+	List(ctx context.Context, page int64, size int64, filter model.TransactionFilter) ([]model.Transaction, error)
+	Count(ctx context.Context, filter model.TransactionFilter) (int64, error)
+	Occupancy(ctx context.Context, parking int64, from time.Time, to time.Time) ([]model.HourOccupancy, error)
+}
+
+type handler struct {
+	queue        business.JobQueue
+	transactions TransactionReader
+	parks        business.Parks
+	visits       business.VisitHistory
+	cfg          *config.Config
+	options      Options
+	routes       []route
+	logger       *zap.Logger
+	// pseudonymizer finds the plates stored hashed
+	pseudonymizer *business.Pseudonymizer
+}
+
+// NewHandler returns the HTTP API importing the files uploaded through
+// queue for the parks of the registry, read in the dialects of cfg, and
+// querying transactions and the visits of the plates. Its
+// OpenAPI document is served at /openapi.json
+func NewHandler(queue business.JobQueue, transactions TransactionReader, parks business.Parks, visits business.VisitHistory, cfg *config.Config, options Options) http.Handler {
+	log, _ := zap.NewProduction()
+
+	if options.MaxUpload <= 0 {
+		options.MaxUpload = defaultMaxUpload
+	}
+
+	h := &handler{
+		queue:        queue,
+		transactions: transactions,
+		parks:        parks,
+		visits:       visits,
+		cfg:          cfg,
+		options:      options,
+		logger:       log,
+
+		pseudonymizer: business.NewPseudonymizer(cfg.Privacy),
+	}
+
+	h.routes = append(h.routes, h.jobRoutes()...)
+	h.routes = append(h.routes, h.transactionRoutes()...)
+	h.routes = append(h.routes, h.visitRoutes()...)
+
+	document := openAPI(h.routes)
+	h.routes = append(h.routes, route{
+		method:  http.MethodGet,
+		pattern: "/openapi.json",
+		handle: func(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+			h.json(w, http.StatusOK, document)
+		},
+	})
+
+	return h
+}
+
+func (h *handler) json(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		h.logger.Info(err.Error())
+	}
+}
+
+// errorResponse is the body of the responses of failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *handler) error(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	h.json(w, status, errorResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+// queryInt returns the integer query parameter name, def when missing,
+// checking it is between min and max
+func queryInt(r *http.Request, name string, def int64, min int64, max int64) (int64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s [%s] must be a number from %d to %d", name, value, min, max)
+	}
+
+	return n, nil
+}
+
+// queryDay returns the YYYY-MM-DD query parameter name, nil when missing
+func queryDay(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s [%s] must be a YYYY-MM-DD day", name, value)
+	}
+
+	return &day, nil
+}
+
+// queryString returns the query parameter name, nil when missing
+func queryString(r *http.Request, name string) *string {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
+
+// parkID parses the park ID of a path
+func parkID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("parkid [%s] is not valid", value)
+	}
+
+	return id, nil
+}
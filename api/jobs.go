@@ -0,0 +1,143 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/csv-processor/business"
+)
+
+func (h *handler) jobRoutes() []route {
+	jobID := pathParam("id", "string", "ID of the job")
+
+	return []route{
+		{
+			method:  http.MethodPost,
+			pattern: "/jobs",
+			summary: "uploads a file of a park and queues its import",
+			upload: []param{
+				{name: "park", kind: "string", required: true, description: "slug of the park in the registry, which picks the dialect"},
+				{name: "filetype", kind: "string", description: "type of the file, transactions when empty"},
+			},
+			response: business.Job{},
+			status:   http.StatusAccepted,
+			handle:   h.submitJob,
+		},
+		{
+			method:   http.MethodGet,
+			pattern:  "/jobs/{id}",
+			summary:  "returns the status and summary of a job",
+			params:   []param{jobID},
+			response: business.Job{},
+			handle:   h.getJob,
+		},
+		{
+			method:   http.MethodDelete,
+			pattern:  "/jobs/{id}",
+			summary:  "cancels a queued or running job",
+			params:   []param{jobID},
+			response: business.Job{},
+			handle:   h.cancelJob,
+		},
+		{
+			method:   http.MethodGet,
+			pattern:  "/jobs/{id}/rejects",
+			summary:  "downloads the rows a job rejected",
+			params:   []param{jobID},
+			response: "text/csv",
+			handle:   h.jobRejects,
+		},
+	}
+}
+
+func (h *handler) submitJob(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.options.MaxUpload)
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "error reading file: [%s]", err.Error())
+		return
+	}
+	defer upload.Close()
+
+	slug := r.FormValue("park")
+	if slug == "" {
+		h.error(w, http.StatusBadRequest, "park is required")
+		return
+	}
+
+	park, err := business.FindPark(r.Context(), h.parks, slug)
+	if _, unknown := err.(*business.ErrUnknownPark); unknown {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+	if err != nil {
+		h.error(w, http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+
+	file := business.ImportFile{
+		Filetype: r.FormValue("filetype"),
+		Parking:  park.Parking(),
+	}
+	if file.Filetype == "" {
+		file.Filetype = "transactions"
+	}
+	file.Dialect = h.cfg.DialectOf(file.Parking.Slug)
+
+	job, err := h.queue.Submit(header.Filename, upload, file)
+	if err == business.ErrQueueFull {
+		h.error(w, http.StatusServiceUnavailable, "%s", err.Error())
+		return
+	}
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	h.logger.Sugar().Infow("job queued", "job", job.ID, "name", job.Name, "park", job.Parking.Slug)
+	h.json(w, http.StatusAccepted, job)
+}
+
+func (h *handler) getJob(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	job, err := h.queue.Get(vars["id"])
+	if err != nil {
+		h.jobError(w, err)
+		return
+	}
+
+	h.json(w, http.StatusOK, job)
+}
+
+func (h *handler) cancelJob(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	job, err := h.queue.Cancel(vars["id"])
+	if err != nil {
+		h.jobError(w, err)
+		return
+	}
+
+	h.json(w, http.StatusOK, job)
+}
+
+func (h *handler) jobRejects(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	rejects, err := h.queue.Rejects(vars["id"])
+	if err != nil {
+		h.jobError(w, err)
+		return
+	}
+	defer rejects.Close()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+vars["id"]+`-rejects.csv"`)
+	io.Copy(w, rejects)
+}
+
+func (h *handler) jobError(w http.ResponseWriter, err error) {
+	switch err {
+	case business.ErrJobNotFound:
+		h.error(w, http.StatusNotFound, "%s", err.Error())
+	case business.ErrJobFinished:
+		h.error(w, http.StatusConflict, "%s", err.Error())
+	default:
+		h.error(w, http.StatusInternalServerError, "%s", err.Error())
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rejectingImporter rejects every row of the files it imports
+type rejectingImporter struct {
+	files chan business.ImportFile
+}
+
+func (i rejectingImporter) Import(ctx context.Context, file business.ImportFile) (*model.Import, bool, error) {
+	i.files <- file
+
+	content, err := ioutil.ReadFile(file.Path)
+	if err != nil {
+		return nil, false, err
+	}
+	file.Rejects.Write(business.Row{Line: 1, Raw: content}, fmt.Errorf("unknown table [X]"))
+
+	return &model.Import{ID: primitive.NewObjectID(), Summary: model.ImportSummary{Rows: 1, Rejected: 1}}, true, nil
+}
+
+// memoryParks is a registry of parks in memory
+type memoryParks []model.Park
+
+func (m memoryParks) GetBySlug(ctx context.Context, slug string) (*model.Park, error) {
+	for _, park := range m {
+		if park.Slug == slug {
+			return &park, nil
+		}
+	}
+
+	return &model.Park{}, nil
+}
+
+func (m memoryParks) GetByID(ctx context.Context, id int64) (*model.Park, error) {
+	for _, park := range m {
+		if park.ID == id {
+			return &park, nil
+		}
+	}
+
+	return &model.Park{}, nil
+}
+
+func upload(t *testing.T, url string, fields map[string]string, content string) *http.Response {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	part, err := writer.CreateFormFile("file", "monza.csv")
+	require.Nil(t, err)
+	part.Write([]byte(content))
+	writer.Close()
+
+	response, err := http.Post(url+"/jobs", writer.FormDataContentType(), body)
+	require.Nil(t, err)
+
+	return response
+}
+
+func decode(t *testing.T, response *http.Response, value interface{}) {
+	defer response.Body.Close()
+	require.Nil(t, json.NewDecoder(response.Body).Decode(value))
+}
+
+func TestHandler_Jobs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	importer := rejectingImporter{files: make(chan business.ImportFile, 1)}
+	queue := business.NewJobQueue(importer, dir, 4, 1, 0)
+	go queue.Run(ctx)
+
+	cfg := &config.Config{ParkDialects: map[string]config.Dialect{"monza": {Delimiter: ";"}}}
+	parks := memoryParks{{ID: 6, Name: "Monza", Slug: "monza"}}
+	server := httptest.NewServer(NewHandler(queue, nil, parks, nil, cfg, Options{}))
+	defer server.Close()
+
+	response := upload(t, server.URL, map[string]string{}, "a;b")
+	require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	response.Body.Close()
+
+	response = upload(t, server.URL, map[string]string{"park": "centro"}, "a;b")
+	require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	response.Body.Close()
+
+	response = upload(t, server.URL, map[string]string{"park": "monza"}, "a;b")
+	require.Equal(t, http.StatusAccepted, response.StatusCode)
+	job := business.Job{}
+	decode(t, response, &job)
+	require.Equal(t, "monza.csv", job.Name)
+	require.Equal(t, model.Parking{ID: 6, Slug: "monza", Name: "Monza"}, job.Parking)
+
+	file := <-importer.files
+	require.Equal(t, "transactions", file.Filetype)
+	require.Equal(t, ";", file.Dialect.Delimiter)
+
+	for i := 0; i < 200 && job.Status != business.JobDone; i++ {
+		time.Sleep(10 * time.Millisecond)
+		response, err = http.Get(server.URL + "/jobs/" + job.ID)
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		decode(t, response, &job)
+	}
+	require.Equal(t, business.JobDone, job.Status)
+	require.Equal(t, model.ImportSummary{Rows: 1, Rejected: 1}, job.Summary)
+
+	response, err = http.Get(server.URL + "/jobs/" + job.ID + "/rejects")
+	require.Nil(t, err)
+	rejects, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	require.Equal(t, "text/csv", response.Header.Get("Content-Type"))
+	require.Equal(t, "line,error,row\n1,unknown table [X],a;b\n", string(rejects))
+
+	request, _ := http.NewRequest(http.MethodDelete, server.URL+"/jobs/"+job.ID, nil)
+	response, err = http.DefaultClient.Do(request)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusConflict, response.StatusCode)
+	response.Body.Close()
+
+	response, err = http.Get(server.URL + "/jobs/unknown")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+	response.Body.Close()
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document follows
+const openAPIVersion = "3.0.3"
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// openAPI returns the OpenAPI document of routes, the schemas of their
+// responses generated from the types returned
+func openAPI(routes []route) map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]interface{}{}
+
+	for _, rt := range routes {
+		operation := map[string]interface{}{
+			"summary":   rt.summary,
+			"responses": responses(rt, schemas),
+		}
+
+		parameters := []interface{}{}
+		for _, p := range rt.params {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"required":    p.required,
+				"description": p.description,
+				"schema":      map[string]interface{}{"type": p.kind},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if len(rt.upload) > 0 {
+			operation["requestBody"] = uploadBody(rt.upload)
+		}
+
+		path, ok := paths[rt.pattern].(map[string]interface{})
+		if !ok {
+			path = map[string]interface{}{}
+			paths[rt.pattern] = path
+		}
+		path[strings.ToLower(rt.method)] = operation
+	}
+
+	schemas["Error"] = schemaOf(reflect.TypeOf(errorResponse{}), schemas)
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "csv-processor",
+			"version": "1",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+func responses(rt route, schemas map[string]interface{}) map[string]interface{} {
+	status := rt.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	content := map[string]interface{}{}
+	if t := rt.responseType(); t != nil {
+		content["application/json"] = map[string]interface{}{"schema": schemaOf(t, schemas)}
+	} else if contentType, ok := rt.response.(string); ok {
+		content[contentType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+	}
+
+	return map[string]interface{}{
+		strconv.Itoa(status): map[string]interface{}{
+			"description": http.StatusText(status),
+			"content":     content,
+		},
+		"default": map[string]interface{}{
+			"description": "error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": ref("Error")},
+			},
+		},
+	}
+}
+
+func uploadBody(fields []param) map[string]interface{} {
+	properties := map[string]interface{}{
+		"file": map[string]interface{}{"type": "string", "format": "binary"},
+	}
+	required := []string{"file"}
+	for _, field := range fields {
+		properties[field.name] = map[string]interface{}{"type": field.kind, "description": field.description}
+		if field.required {
+			required = append(required, field.name)
+		}
+	}
+
+	return map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			"multipart/form-data": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type":       "object",
+					"properties": properties,
+					"required":   required,
+				},
+			},
+		},
+	}
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaOf returns the schema of the JSON encoding of t. Named structs are
+// added to schemas and referenced
+func schemaOf(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case objectIDType:
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if _, ok := schemas[t.Name()]; ok {
+			return ref(t.Name())
+		}
+		// the reference is taken before the fields, which may refer back
+		schemas[t.Name()] = nil
+		schemas[t.Name()] = structSchema(t, schemas)
+		return ref(t.Name())
+	}
+
+	return map[string]interface{}{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if tag[0] != "" {
+			name = tag[0]
+		}
+
+		properties[name] = schemaOf(field.Type, schemas)
+	}
+
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
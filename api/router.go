@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// param is a path or query parameter of a route
+type param struct {
+	name string
+	// in is path or query
+	in          string
+	kind        string
+	required    bool
+	description string
+}
+
+func pathParam(name string, kind string, description string) param {
+	return param{name: name, in: "path", kind: kind, required: true, description: description}
+}
+
+func queryParam(name string, kind string, description string) param {
+	return param{name: name, in: "query", kind: kind, description: description}
+}
+
+// route is an endpoint of the API, which is also how its OpenAPI document
+// describes it
+type route struct {
+	method  string
+	pattern string
+	summary string
+	params  []param
+	// upload lists the fields of the multipart form of the request, along
+	// with the file
+	upload []param
+	// response is a value of the type of the JSON response, or a content
+	// type for other responses
+	response interface{}
+	status   int
+	handle   func(w http.ResponseWriter, r *http.Request, vars map[string]string)
+}
+
+// match returns the path parameters of path when it matches the pattern
+// of the route, whose {name} segments match any segment
+func (rt route) match(path string) (map[string]string, bool) {
+	patterns := strings.Split(strings.Trim(rt.pattern, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patterns) != len(segments) {
+		return nil, false
+	}
+
+	vars := map[string]string{}
+	for i, pattern := range patterns {
+		if strings.HasPrefix(pattern, "{") && strings.HasSuffix(pattern, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			vars[strings.Trim(pattern, "{}")] = segments[i]
+		} else if pattern != segments[i] {
+			return nil, false
+		}
+	}
+
+	return vars, true
+}
+
+// responseType returns the type of the JSON response, nil for others
+func (rt route) responseType() reflect.Type {
+	if _, ok := rt.response.(string); ok || rt.response == nil {
+		return nil
+	}
+
+	return reflect.TypeOf(rt.response)
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// a path matched with another method is not allowed rather than not found
+	matched := false
+	for _, rt := range h.routes {
+		vars, ok := rt.match(r.URL.Path)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			matched = true
+			continue
+		}
+
+		rt.handle(w, r, vars)
+		return
+	}
+
+	if matched {
+		h.error(w, http.StatusMethodNotAllowed, "method [%s] is not allowed", r.Method)
+		return
+	}
+
+	h.error(w, http.StatusNotFound, "path [%s] not found", r.URL.Path)
+}
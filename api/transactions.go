@@ -0,0 +1,255 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxOccupancyDays is the longest period the occupancy is returned for
+const maxOccupancyDays = 31
+
+// TransactionPage is a page of the transactions matching a query
+type TransactionPage struct {
+	Items []model.Transaction `json:"items"`
+	Page  int64               `json:"page"`
+	Size  int64               `json:"size"`
+	// Total is how many transactions match, on every page
+	Total int64 `json:"total"`
+}
+
+// Occupancy is how many vehicles were parked in a park hour by hour
+type Occupancy struct {
+	ParkID int64     `json:"parkid"`
+	From   time.Time `json:"from"`
+	// To is the first hour after the period
+	To    time.Time             `json:"to"`
+	Hours []model.HourOccupancy `json:"hours"`
+}
+
+func (h *handler) transactionRoutes() []route {
+	park := pathParam("parkid", "integer", "ID of the park")
+	page := []param{
+		queryParam("page", "integer", "page to return, from 1"),
+		queryParam("size", "integer", fmt.Sprintf("transactions per page, %d by default and %d at most", defaultPageSize, maxPageSize)),
+	}
+	checkout := []param{
+		queryParam("from", "string", "first checkout day included, YYYY-MM-DD"),
+		queryParam("to", "string", "last checkout day included, YYYY-MM-DD"),
+	}
+
+	return []route{
+		{
+			method:  http.MethodGet,
+			pattern: "/transactions",
+			summary: "lists the transactions matching the filters, ordered by checkout",
+			params: append(append([]param{
+				queryParam("parkid", "integer", "ID of the park"),
+				queryParam("plate", "string", "plate of the vehicle"),
+				queryParam("payment_method", "string", "payment method"),
+				queryParam("use_type", "string", "use type"),
+			}, checkout...), page...),
+			response: TransactionPage{},
+			handle:   h.listTransactions,
+		},
+		{
+			method:   http.MethodGet,
+			pattern:  "/transactions/{id}",
+			summary:  "returns a transaction",
+			params:   []param{pathParam("id", "string", "ID of the transaction")},
+			response: model.Transaction{},
+			handle:   h.getTransaction,
+		},
+		{
+			method:   http.MethodGet,
+			pattern:  "/parks/{parkid}/plates/{plate}/transactions",
+			summary:  "lists the stays of a vehicle in a park, ordered by checkout",
+			params:   append(append([]param{park, pathParam("plate", "string", "plate of the vehicle")}, checkout...), page...),
+			response: TransactionPage{},
+			handle:   h.plateHistory,
+		},
+		{
+			method:  http.MethodGet,
+			pattern: "/parks/{parkid}/occupancy",
+			summary: fmt.Sprintf("returns how many vehicles were parked each hour, for up to %d days", maxOccupancyDays),
+			params: []param{
+				park,
+				{name: "from", in: "query", kind: "string", required: true, description: "first day included, YYYY-MM-DD"},
+				{name: "to", in: "query", kind: "string", required: true, description: "last day included, YYYY-MM-DD"},
+			},
+			response: Occupancy{},
+			handle:   h.occupancy,
+		},
+	}
+}
+
+// transactionFilter reads the checkout days of a query into filter
+func transactionFilter(r *http.Request, filter model.TransactionFilter) (model.TransactionFilter, error) {
+	from, err := queryDay(r, "from")
+	if err != nil {
+		return filter, err
+	}
+
+	to, err := queryDay(r, "to")
+	if err != nil {
+		return filter, err
+	}
+
+	if from != nil && to != nil && to.Before(*from) {
+		return filter, fmt.Errorf("to [%s] is before from [%s]", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+
+	filter.From = from
+	if to != nil {
+		// the last day is included
+		next := to.AddDate(0, 0, 1)
+		filter.To = &next
+	}
+
+	return filter, nil
+}
+
+func (h *handler) listTransactions(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	filter := model.TransactionFilter{
+		PaymentMethod: queryString(r, "payment_method"),
+		UseType:       queryString(r, "use_type"),
+	}
+	if plate := r.URL.Query().Get("plate"); plate != "" {
+		filter.Matriculas = h.pseudonymizer.Plates(plate)
+	}
+
+	if value := r.URL.Query().Get("parkid"); value != "" {
+		id, err := parkID(value)
+		if err != nil {
+			h.error(w, http.StatusBadRequest, "%s", err.Error())
+			return
+		}
+		filter.ParkingID = &id
+	}
+
+	filter, err := transactionFilter(r, filter)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	h.transactionPage(w, r, filter)
+}
+
+func (h *handler) plateHistory(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	id, err := parkID(vars["parkid"])
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	plates := h.pseudonymizer.Plates(vars["plate"])
+	filter, err := transactionFilter(r, model.TransactionFilter{ParkingID: &id, Matriculas: plates})
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	h.transactionPage(w, r, filter)
+}
+
+// transactionPage responds with the page of the query of the transactions
+// matching filter
+func (h *handler) transactionPage(w http.ResponseWriter, r *http.Request, filter model.TransactionFilter) {
+	page, err := queryInt(r, "page", 1, 1, 1<<31)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	size, err := queryInt(r, "size", defaultPageSize, 1, maxPageSize)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	total, err := h.transactions.Count(r.Context(), filter)
+	if err != nil {
+		h.error(w, http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+
+	items, err := h.transactions.List(r.Context(), page, size, filter)
+	if err != nil {
+		h.error(w, http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+	if items == nil {
+		items = []model.Transaction{}
+	}
+	for i := range items {
+		h.mask(&items[i])
+	}
+
+	h.json(w, http.StatusOK, TransactionPage{Items: items, Page: page, Size: size, Total: total})
+}
+
+func (h *handler) getTransaction(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	if _, err := primitive.ObjectIDFromHex(vars["id"]); err != nil {
+		h.error(w, http.StatusBadRequest, "id [%s] is not valid", vars["id"])
+		return
+	}
+
+	transaction, err := h.transactions.GetByID(r.Context(), vars["id"])
+	if err != nil {
+		h.error(w, http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+
+	if transaction.ID.IsZero() {
+		h.error(w, http.StatusNotFound, "transaction [%s] not found", vars["id"])
+		return
+	}
+
+	h.mask(transaction)
+	h.json(w, http.StatusOK, transaction)
+}
+
+// mask masks the plate and identity of transaction when the privacy
+// settings say so
+func (h *handler) mask(transaction *model.Transaction) {
+	if h.cfg.Privacy.MaskPlates {
+		business.MaskTransaction(transaction)
+	}
+}
+
+func (h *handler) occupancy(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	id, err := parkID(vars["parkid"])
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	filter, err := transactionFilter(r, model.TransactionFilter{})
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	if filter.From == nil || filter.To == nil {
+		h.error(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+
+	if filter.To.Sub(*filter.From) > maxOccupancyDays*24*time.Hour {
+		h.error(w, http.StatusBadRequest, "occupancy is returned for up to %d days", maxOccupancyDays)
+		return
+	}
+
+	hours, err := h.transactions.Occupancy(r.Context(), id, *filter.From, *filter.To)
+	if err != nil {
+		h.error(w, http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+
+	h.json(w, http.StatusOK, Occupancy{ParkID: id, From: *filter.From, To: *filter.To, Hours: hours})
+}
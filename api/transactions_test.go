@@ -0,0 +1,296 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryTransactions is a TransactionReader over transactions in memory
+type memoryTransactions struct {
+	transactions []model.Transaction
+	filters      []model.TransactionFilter
+}
+
+func (m *memoryTransactions) matching(filter model.TransactionFilter) []model.Transaction {
+	m.filters = append(m.filters, filter)
+
+	matching := []model.Transaction{}
+	for _, transaction := range m.transactions {
+		if filter.ParkingID != nil && transaction.ParkingInfo.ID != *filter.ParkingID {
+			continue
+		}
+		if len(filter.Matriculas) > 0 && !contains(filter.Matriculas, transaction.Matricula) {
+			continue
+		}
+		matching = append(matching, transaction)
+	}
+
+	return matching
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *memoryTransactions) GetByID(ctx context.Context, id string) (*model.Transaction, error) {
+	for _, transaction := range m.transactions {
+		if transaction.ID.Hex() == id {
+			return &transaction, nil
+		}
+	}
+
+	return &model.Transaction{}, nil
+}
+
+func (m *memoryTransactions) List(ctx context.Context, page int64, size int64, filter model.TransactionFilter) ([]model.Transaction, error) {
+	matching := m.matching(filter)
+
+	start := (page - 1) * size
+	if start > int64(len(matching)) {
+		return nil, nil
+	}
+	end := start + size
+	if end > int64(len(matching)) {
+		end = int64(len(matching))
+	}
+
+	return matching[start:end], nil
+}
+
+func (m *memoryTransactions) Count(ctx context.Context, filter model.TransactionFilter) (int64, error) {
+	return int64(len(m.matching(filter))), nil
+}
+
+func (m *memoryTransactions) Occupancy(ctx context.Context, parking int64, from time.Time, to time.Time) ([]model.HourOccupancy, error) {
+	return []model.HourOccupancy{{Hour: from.Add(10 * time.Hour), Vehicles: 2}}, nil
+}
+
+func TestHandler_Transactions(t *testing.T) {
+	transactions := &memoryTransactions{transactions: []model.Transaction{
+		{ID: primitive.NewObjectID(), ParkingInfo: model.Parking{ID: 6}, Matricula: "ABC1234"},
+		{ID: primitive.NewObjectID(), ParkingInfo: model.Parking{ID: 6}, Matricula: "DEF4567"},
+		{ID: primitive.NewObjectID(), ParkingInfo: model.Parking{ID: 6}, Matricula: "ABC1234"},
+		{ID: primitive.NewObjectID(), ParkingInfo: model.Parking{ID: 7}, Matricula: "ABC1234"},
+	}}
+
+	server := httptest.NewServer(NewHandler(nil, transactions, memoryParks{}, nil, &config.Config{}, Options{}))
+	defer server.Close()
+
+	type TestRun struct {
+		name           string
+		path           string
+		expectedStatus int
+		expected       interface{}
+		response       interface{}
+	}
+
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tt := []TestRun{
+		{
+			name:           "list",
+			path:           "/transactions?parkid=6&size=2&page=2",
+			expectedStatus: http.StatusOK,
+			response:       &TransactionPage{},
+			expected:       &TransactionPage{Items: transactions.transactions[2:3], Page: 2, Size: 2, Total: 3},
+		},
+		{
+			name:           "plate history",
+			path:           "/parks/6/plates/abc-1234/transactions",
+			expectedStatus: http.StatusOK,
+			response:       &TransactionPage{},
+			expected: &TransactionPage{
+				Items: []model.Transaction{transactions.transactions[0], transactions.transactions[2]},
+				Page:  1, Size: defaultPageSize, Total: 2,
+			},
+		},
+		{
+			name:           "list by raw plate",
+			path:           "/transactions?parkid=7&plate=abc%201234",
+			expectedStatus: http.StatusOK,
+			response:       &TransactionPage{},
+			expected:       &TransactionPage{Items: transactions.transactions[3:4], Page: 1, Size: defaultPageSize, Total: 1},
+		},
+		{
+			name:           "transaction",
+			path:           "/transactions/" + transactions.transactions[1].ID.Hex(),
+			expectedStatus: http.StatusOK,
+			response:       &model.Transaction{},
+			expected:       &transactions.transactions[1],
+		},
+		{
+			name:           "occupancy",
+			path:           "/parks/6/occupancy?from=2020-10-01&to=2020-10-01",
+			expectedStatus: http.StatusOK,
+			response:       &Occupancy{},
+			expected: &Occupancy{ParkID: 6, From: day, To: day.AddDate(0, 0, 1), Hours: []model.HourOccupancy{
+				{Hour: day.Add(10 * time.Hour), Vehicles: 2},
+			}},
+		},
+		{
+			name:           "transaction not found",
+			path:           "/transactions/" + primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid transaction id",
+			path:           "/transactions/1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "page size too large",
+			path:           "/transactions?size=501",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid day",
+			path:           "/transactions?from=01/10/2020",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "to before from",
+			path:           "/transactions?from=2020-10-02&to=2020-10-01",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid park",
+			path:           "/parks/monza/occupancy?from=2020-10-01&to=2020-10-01",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "occupancy period too long",
+			path:           "/parks/6/occupancy?from=2020-10-01&to=2020-12-01",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown path",
+			path:           "/parks/6",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := http.Get(server.URL + tc.path)
+			require.Nil(t, err)
+			defer response.Body.Close()
+
+			require.Equal(t, tc.expectedStatus, response.StatusCode)
+			require.Equal(t, "application/json", response.Header.Get("Content-Type"))
+
+			if tc.response == nil {
+				body := errorResponse{}
+				require.Nil(t, json.NewDecoder(response.Body).Decode(&body))
+				require.NotEqual(t, "", body.Error)
+				return
+			}
+
+			require.Nil(t, json.NewDecoder(response.Body).Decode(tc.response))
+			require.Equal(t, tc.expected, tc.response)
+		})
+	}
+}
+
+func TestHandler_TransactionsMasked(t *testing.T) {
+	transaction := model.Transaction{ID: primitive.NewObjectID(), ParkingInfo: model.Parking{ID: 6}, Matricula: "ABC1234", MatriculaRaw: "abc-1234", Identity: "12345678901"}
+	masked := transaction
+	masked.Matricula, masked.MatriculaRaw, masked.Identity = "ABC**34", "abc***34", "123******01"
+	transactions := &memoryTransactions{transactions: []model.Transaction{transaction}}
+
+	cfg := &config.Config{Privacy: config.Privacy{MaskPlates: true}}
+	server := httptest.NewServer(NewHandler(nil, transactions, memoryParks{}, nil, cfg, Options{}))
+	defer server.Close()
+
+	type TestRun struct {
+		name     string
+		path     string
+		expected interface{}
+		response interface{}
+	}
+
+	tt := []TestRun{
+		{
+			name:     "list",
+			path:     "/transactions?plate=abc-1234",
+			response: &TransactionPage{},
+			expected: &TransactionPage{Items: []model.Transaction{masked}, Page: 1, Size: defaultPageSize, Total: 1},
+		},
+		{
+			name:     "plate history",
+			path:     "/parks/6/plates/abc-1234/transactions",
+			response: &TransactionPage{},
+			expected: &TransactionPage{Items: []model.Transaction{masked}, Page: 1, Size: defaultPageSize, Total: 1},
+		},
+		{
+			name:     "transaction",
+			path:     "/transactions/" + transaction.ID.Hex(),
+			response: &model.Transaction{},
+			expected: &masked,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := http.Get(server.URL + tc.path)
+			require.Nil(t, err)
+			defer response.Body.Close()
+
+			require.Equal(t, http.StatusOK, response.StatusCode)
+			require.Nil(t, json.NewDecoder(response.Body).Decode(tc.response))
+			require.Equal(t, tc.expected, tc.response)
+		})
+	}
+}
+
+func TestHandler_OpenAPI(t *testing.T) {
+	server := httptest.NewServer(NewHandler(nil, &memoryTransactions{}, memoryParks{}, nil, &config.Config{}, Options{}))
+	defer server.Close()
+
+	response, err := http.Post(server.URL+"/openapi.json", "application/json", nil)
+	require.Nil(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+
+	response, err = http.Get(server.URL + "/openapi.json")
+	require.Nil(t, err)
+	defer response.Body.Close()
+
+	document := struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}{}
+	require.Nil(t, json.NewDecoder(response.Body).Decode(&document))
+
+	require.Equal(t, openAPIVersion, document.OpenAPI)
+	require.Contains(t, document.Paths["/jobs"], "post")
+	require.Contains(t, document.Paths["/jobs/{id}"], "get")
+	require.Contains(t, document.Paths["/jobs/{id}"], "delete")
+	require.Contains(t, document.Paths["/transactions"], "get")
+	require.Contains(t, document.Paths["/parks/{parkid}/occupancy"], "get")
+	require.Contains(t, document.Paths["/plates/{plate}/profile"], "get")
+
+	require.Contains(t, document.Components.Schemas, "TransactionPage")
+	require.Contains(t, document.Components.Schemas["Transaction"].Properties, "checkout_date")
+	require.Contains(t, document.Components.Schemas["Job"].Properties, "summary")
+	require.Contains(t, document.Components.Schemas, "Error")
+}
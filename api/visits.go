@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/csv-processor/business"
+)
+
+func (h *handler) visitRoutes() []route {
+	return []route{
+		{
+			method:   http.MethodGet,
+			pattern:  "/plates/{plate}/profile",
+			summary:  "returns the visits of a vehicle across the parks, its plate masked when the privacy settings say so",
+			params:   []param{pathParam("plate", "string", "plate of the vehicle")},
+			response: business.PlateProfile{},
+			handle:   h.plateProfile,
+		},
+	}
+}
+
+func (h *handler) plateProfile(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	profile, err := h.visits.Profile(r.Context(), vars["plate"])
+	if err != nil {
+		h.error(w, http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+
+	if profile.Visits == 0 {
+		h.error(w, http.StatusNotFound, "plate [%s] has no visits", profile.Plate)
+		return
+	}
+
+	h.json(w, http.StatusOK, profile)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/config"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryVisits returns the profiles in memory, by normalized plate
+type memoryVisits map[string]business.PlateProfile
+
+func (m memoryVisits) Profile(ctx context.Context, plate string) (*business.PlateProfile, error) {
+	plate = business.NormalizePlate(plate)
+	profile, ok := m[plate]
+	if !ok {
+		return &business.PlateProfile{Plate: plate, Parks: []business.ParkVisits{}}, nil
+	}
+
+	return &profile, nil
+}
+
+func TestHandler_PlateProfile(t *testing.T) {
+	visits := memoryVisits{"ABC1234": {
+		Plate:  "ABC**34",
+		Visits: 2,
+		Parks:  []business.ParkVisits{{ParkID: 6, Slug: "monza", Visits: 2}},
+	}}
+
+	server := httptest.NewServer(NewHandler(nil, nil, memoryParks{}, visits, &config.Config{}, Options{}))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/plates/abc-1234/profile")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	profile := business.PlateProfile{}
+	decode(t, response, &profile)
+	require.Equal(t, visits["ABC1234"], profile)
+
+	response, err = http.Get(server.URL + "/plates/DEF4567/profile")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+	body := errorResponse{}
+	decode(t, response, &body)
+	require.Equal(t, "plate [DEF4567] has no visits", body.Error)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/csv-processor/business"
+)
+
+// bucketingFlags are the flags overriding the configured hour bucketing
+type bucketingFlags struct {
+	policy    *string
+	fraction  *int
+	tolerance *int
+}
+
+func addBucketingFlags(cmd *command) *bucketingFlags {
+	return &bucketingFlags{
+		policy:    cmd.flags.String("bucketing", "", "hour bucketing policy: touched, started or fractional, defaults to the config"),
+		fraction:  cmd.flags.Int("fraction", 0, "minutes the fractional bucketing rounds stays up to, defaults to the config or 15"),
+		tolerance: cmd.flags.Int("tolerance", 0, "minutes of the stays the fractional bucketing does not bill, defaults to the config"),
+	}
+}
+
+// bucketing returns the configured bucketing with the flags given on the
+// command line over it
+func (f *bucketingFlags) bucketing(cmd *command) (business.Bucketing, error) {
+	policy := cmd.cfg.Bucketing
+	if cmd.seen["bucketing"] {
+		policy = *f.policy
+	}
+
+	fractional := cmd.cfg.Fractional
+	if cmd.seen["fraction"] {
+		fractional.Fraction = *f.fraction
+	}
+	if cmd.seen["tolerance"] {
+		fractional.Tolerance = *f.tolerance
+	}
+
+	bucket, err := business.NewBucketing(policy, fractional)
+	if err != nil {
+		return nil, fmt.Errorf("error choosing bucketing: [%s]", err.Error())
+	}
+
+	return bucket, nil
+}
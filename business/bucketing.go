@@ -0,0 +1,131 @@
+package business
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/csv-processor/config"
+)
+
+const (
+	// BucketingHoursTouched charges every clock hour the stay overlaps
+	BucketingHoursTouched = "touched"
+	// BucketingHoursStarted charges every started hour counted from checkin
+	BucketingHoursStarted = "started"
+	// BucketingFractional charges the stay in fractions of an hour
+	BucketingFractional = "fractional"
+)
+
+// Bucketing splits a stay into the hourly buckets it occupies and the
+// amount of hours it is billed for
+type Bucketing interface {
+	Buckets(checkin, checkout time.Time) (hours []time.Time, count float64)
+}
+
+// NewBucketing returns the bucketing policy registered under name, the
+// fractional one billing as told by fractional
+func NewBucketing(name string, fractional config.Fractional) (Bucketing, error) {
+	switch name {
+	case BucketingHoursTouched:
+		return HoursTouched{}, nil
+	case BucketingHoursStarted:
+		return HoursStarted{}, nil
+	case BucketingFractional:
+		if fractional.Fraction <= 0 {
+			return nil, fmt.Errorf("fraction [%d] of bucketing [%s] must be positive", fractional.Fraction, name)
+		}
+		if fractional.Tolerance < 0 {
+			return nil, fmt.Errorf("tolerance [%d] of bucketing [%s] can not be negative", fractional.Tolerance, name)
+		}
+
+		return FractionalHours{
+			Fraction:  time.Duration(fractional.Fraction) * time.Minute,
+			Tolerance: time.Duration(fractional.Tolerance) * time.Minute,
+		}, nil
+	default:
+		return nil, fmt.Errorf("bucketing [%s] does not exists", name)
+	}
+}
+
+// HoursTouched buckets a stay into every clock hour it overlaps, so
+// 10:20-10:50 is one bucket and 10:50-11:10 is two
+type HoursTouched struct{}
+
+// Buckets implements Bucketing
+func (HoursTouched) Buckets(checkin, checkout time.Time) ([]time.Time, float64) {
+	if checkout.Before(checkin) {
+		return []time.Time{}, 0
+	}
+
+	hours := []time.Time{floorHour(checkin)}
+	for h := nextHour(hours[0]); h.Before(checkout); h = nextHour(h) {
+		hours = append(hours, h)
+	}
+
+	return hours, float64(len(hours))
+}
+
+// HoursStarted buckets a stay into the hours started since checkin, so
+// 10:50-11:10 is one bucket and 10:50-11:51 is two
+type HoursStarted struct{}
+
+// Buckets implements Bucketing
+func (HoursStarted) Buckets(checkin, checkout time.Time) ([]time.Time, float64) {
+	if checkout.Before(checkin) {
+		return []time.Time{}, 0
+	}
+
+	started := int(math.Ceil(float64(checkout.Sub(checkin)) / float64(time.Hour)))
+	if started == 0 {
+		started = 1
+	}
+
+	hours := make([]time.Time, 0, started)
+	for i := 0; i < started; i++ {
+		hours = append(hours, floorHour(checkin.Add(time.Duration(i)*time.Hour)))
+	}
+
+	return hours, float64(started)
+}
+
+// FractionalHours bills a stay in fractions of an hour after a tolerance,
+// while the buckets are the clock hours it overlaps
+type FractionalHours struct {
+	// Fraction is the billing unit, a stay is rounded up to it
+	Fraction time.Duration
+	// Tolerance is the free period, stays up to it are not billed
+	Tolerance time.Duration
+}
+
+// Buckets implements Bucketing
+func (f FractionalHours) Buckets(checkin, checkout time.Time) ([]time.Time, float64) {
+	hours, _ := HoursTouched{}.Buckets(checkin, checkout)
+
+	stay := checkout.Sub(checkin)
+	if stay <= f.Tolerance {
+		return hours, 0
+	}
+
+	fraction := f.Fraction
+	if fraction <= 0 {
+		fraction = time.Minute
+	}
+
+	units := math.Ceil(float64(stay) / float64(fraction))
+
+	return hours, units * float64(fraction) / float64(time.Hour)
+}
+
+// floorHour returns the start of the clock hour of t, keeping the same
+// offset so repeated hours on DST changes are kept apart
+func floorHour(t time.Time) time.Time {
+	return t.Add(-(time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())))
+}
+
+// nextHour returns the start of the clock hour after h
+func nextHour(h time.Time) time.Time {
+	return floorHour(h.Add(time.Hour))
+}
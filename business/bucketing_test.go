@@ -0,0 +1,313 @@
+package business
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/csv-processor/config"
+	"github.com/stretchr/testify/require"
+)
+
+// stay is a random checkin/checkout pair used by the property tests,
+// checkins are spread over years and zones so midnights and DST changes
+// are hit often
+type stay struct {
+	Checkin  time.Time
+	Checkout time.Time
+}
+
+var stayZones = []string{"UTC", "America/Sao_Paulo", "America/New_York", "Europe/London", "Asia/Kolkata"}
+
+// Generate implements quick.Generator
+func (stay) Generate(r *rand.Rand, size int) reflect.Value {
+	loc, err := time.LoadLocation(stayZones[r.Intn(len(stayZones))])
+	if err != nil {
+		panic(err)
+	}
+
+	base := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+	checkin := time.Unix(base+r.Int63n(5*365*24*3600), 0).In(loc)
+	checkout := checkin.Add(time.Duration(r.Int63n(3*24*3600)) * time.Second)
+
+	return reflect.ValueOf(stay{Checkin: checkin, Checkout: checkout})
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func checkClockHours(hours []time.Time) bool {
+	for i, h := range hours {
+		if h.Minute() != 0 || h.Second() != 0 || h.Nanosecond() != 0 {
+			return false
+		}
+		if i > 0 && h.Sub(hours[i-1]) != time.Hour {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHoursTouched_Properties(t *testing.T) {
+	policy := HoursTouched{}
+
+	property := func(s stay) bool {
+		hours, count := policy.Buckets(s.Checkin, s.Checkout)
+
+		if len(hours) == 0 || float64(len(hours)) != count {
+			return false
+		}
+		if !checkClockHours(hours) {
+			return false
+		}
+		if !hours[0].Equal(floorHour(s.Checkin)) {
+			return false
+		}
+
+		// every bucket overlaps the stay and the stay ends in the last one
+		last := hours[len(hours)-1]
+		if s.Checkout.After(s.Checkin) {
+			return last.Before(s.Checkout) && !last.Add(time.Hour).Before(s.Checkout)
+		}
+		return len(hours) == 1
+	}
+
+	require.Nil(t, quick.Check(property, &quick.Config{MaxCount: 5000}))
+}
+
+func TestHoursStarted_Properties(t *testing.T) {
+	policy := HoursStarted{}
+
+	property := func(s stay) bool {
+		hours, count := policy.Buckets(s.Checkin, s.Checkout)
+
+		expected := math.Ceil(s.Checkout.Sub(s.Checkin).Hours())
+		if expected == 0 {
+			expected = 1
+		}
+
+		if count != expected || float64(len(hours)) != count {
+			return false
+		}
+
+		return checkClockHours(hours) && hours[0].Equal(floorHour(s.Checkin))
+	}
+
+	require.Nil(t, quick.Check(property, &quick.Config{MaxCount: 5000}))
+}
+
+func TestFractionalHours_Properties(t *testing.T) {
+	policy := FractionalHours{Fraction: 15 * time.Minute, Tolerance: 10 * time.Minute}
+
+	property := func(s stay) bool {
+		hours, count := policy.Buckets(s.Checkin, s.Checkout)
+		touched, _ := HoursTouched{}.Buckets(s.Checkin, s.Checkout)
+
+		if !reflect.DeepEqual(hours, touched) {
+			return false
+		}
+
+		stayed := s.Checkout.Sub(s.Checkin)
+		if stayed <= policy.Tolerance {
+			return count == 0
+		}
+
+		// billed at least what was stayed and less than one fraction more
+		billed := time.Duration(count * float64(time.Hour))
+		return billed >= stayed && billed-stayed < policy.Fraction
+	}
+
+	require.Nil(t, quick.Check(property, &quick.Config{MaxCount: 5000}))
+}
+
+func TestBucketing_EdgeCases(t *testing.T) {
+	utc := time.UTC
+	saoPaulo := mustLocation(t, "America/Sao_Paulo")
+	newYork := mustLocation(t, "America/New_York")
+
+	type TestRun struct {
+		name          string
+		policy        Bucketing
+		checkin       time.Time
+		checkout      time.Time
+		expectedHours []time.Time
+		expectedCount float64
+	}
+
+	tt := []TestRun{
+		{
+			name:          "touched within the same hour",
+			policy:        HoursTouched{},
+			checkin:       time.Date(2020, 10, 1, 10, 20, 0, 0, utc),
+			checkout:      time.Date(2020, 10, 1, 10, 50, 0, 0, utc),
+			expectedHours: []time.Time{time.Date(2020, 10, 1, 10, 0, 0, 0, utc)},
+			expectedCount: 1,
+		},
+		{
+			name:          "touched checkout on the hour",
+			policy:        HoursTouched{},
+			checkin:       time.Date(2020, 10, 1, 10, 0, 0, 0, utc),
+			checkout:      time.Date(2020, 10, 1, 11, 0, 0, 0, utc),
+			expectedHours: []time.Time{time.Date(2020, 10, 1, 10, 0, 0, 0, utc)},
+			expectedCount: 1,
+		},
+		{
+			name:     "touched across midnight",
+			policy:   HoursTouched{},
+			checkin:  time.Date(2020, 12, 31, 23, 40, 0, 0, utc),
+			checkout: time.Date(2021, 1, 1, 0, 10, 0, 0, utc),
+			expectedHours: []time.Time{
+				time.Date(2020, 12, 31, 23, 0, 0, 0, utc),
+				time.Date(2021, 1, 1, 0, 0, 0, 0, utc),
+			},
+			expectedCount: 2,
+		},
+		{
+			name:          "touched checkout before checkin",
+			policy:        HoursTouched{},
+			checkin:       time.Date(2020, 10, 1, 11, 0, 0, 0, utc),
+			checkout:      time.Date(2020, 10, 1, 10, 0, 0, 0, utc),
+			expectedHours: []time.Time{},
+			expectedCount: 0,
+		},
+		{
+			// clocks jumped from 00:00 to 01:00, midnight did not exist
+			name:     "touched on DST start at midnight",
+			policy:   HoursTouched{},
+			checkin:  time.Date(2018, 11, 3, 23, 30, 0, 0, saoPaulo),
+			checkout: time.Date(2018, 11, 4, 1, 30, 0, 0, saoPaulo),
+			expectedHours: []time.Time{
+				time.Date(2018, 11, 3, 23, 0, 0, 0, saoPaulo),
+				time.Date(2018, 11, 4, 1, 0, 0, 0, saoPaulo),
+			},
+			expectedCount: 2,
+		},
+		{
+			// clocks went back from 00:00 to 23:00, the 23h happened twice
+			name:     "touched on DST end at midnight",
+			policy:   HoursTouched{},
+			checkin:  time.Date(2019, 2, 16, 22, 30, 0, 0, saoPaulo),
+			checkout: time.Date(2019, 2, 16, 22, 30, 0, 0, saoPaulo).Add(2 * time.Hour),
+			expectedHours: []time.Time{
+				time.Date(2019, 2, 16, 22, 0, 0, 0, saoPaulo),
+				time.Date(2019, 2, 17, 1, 0, 0, 0, utc),
+				time.Date(2019, 2, 17, 2, 0, 0, 0, utc),
+			},
+			expectedCount: 3,
+		},
+		{
+			name:     "started on DST start",
+			policy:   HoursStarted{},
+			checkin:  time.Date(2020, 3, 8, 1, 30, 0, 0, newYork),
+			checkout: time.Date(2020, 3, 8, 3, 45, 0, 0, newYork),
+			expectedHours: []time.Time{
+				time.Date(2020, 3, 8, 1, 0, 0, 0, newYork),
+				time.Date(2020, 3, 8, 3, 0, 0, 0, newYork),
+			},
+			expectedCount: 2,
+		},
+		{
+			name:          "started zero length stay",
+			policy:        HoursStarted{},
+			checkin:       time.Date(2020, 10, 1, 10, 20, 0, 0, utc),
+			checkout:      time.Date(2020, 10, 1, 10, 20, 0, 0, utc),
+			expectedHours: []time.Time{time.Date(2020, 10, 1, 10, 0, 0, 0, utc)},
+			expectedCount: 1,
+		},
+		{
+			name:          "fractional within the same hour",
+			policy:        FractionalHours{Fraction: 15 * time.Minute},
+			checkin:       time.Date(2020, 10, 1, 10, 20, 0, 0, utc),
+			checkout:      time.Date(2020, 10, 1, 10, 50, 0, 0, utc),
+			expectedHours: []time.Time{time.Date(2020, 10, 1, 10, 0, 0, 0, utc)},
+			expectedCount: 0.5,
+		},
+		{
+			name:          "fractional within tolerance",
+			policy:        FractionalHours{Fraction: 15 * time.Minute, Tolerance: 15 * time.Minute},
+			checkin:       time.Date(2020, 10, 1, 10, 20, 0, 0, utc),
+			checkout:      time.Date(2020, 10, 1, 10, 35, 0, 0, utc),
+			expectedHours: []time.Time{time.Date(2020, 10, 1, 10, 0, 0, 0, utc)},
+			expectedCount: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hours, count := tc.policy.Buckets(tc.checkin, tc.checkout)
+
+			require.Equal(t, tc.expectedCount, count)
+			require.Equal(t, len(tc.expectedHours), len(hours))
+			for i := range hours {
+				require.True(t, tc.expectedHours[i].Equal(hours[i]), "bucket %d: expected %s got %s", i, tc.expectedHours[i], hours[i])
+			}
+		})
+	}
+}
+
+func TestNewBucketing(t *testing.T) {
+	type TestRun struct {
+		name          string
+		policy        string
+		fractional    config.Fractional
+		expected      Bucketing
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:     "touched",
+			policy:   BucketingHoursTouched,
+			expected: HoursTouched{},
+		},
+		{
+			name:     "started",
+			policy:   BucketingHoursStarted,
+			expected: HoursStarted{},
+		},
+		{
+			name:       "fractional",
+			policy:     BucketingFractional,
+			fractional: config.Fractional{Fraction: 30, Tolerance: 10},
+			expected:   FractionalHours{Fraction: 30 * time.Minute, Tolerance: 10 * time.Minute},
+		},
+		{
+			name:          "fractional without fraction",
+			policy:        BucketingFractional,
+			expectedError: true,
+		},
+		{
+			name:          "fractional with negative tolerance",
+			policy:        BucketingFractional,
+			fractional:    config.Fractional{Fraction: 15, Tolerance: -1},
+			expectedError: true,
+		},
+		{
+			name:          "unknown",
+			policy:        "error",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := NewBucketing(tc.policy, tc.fractional)
+
+			if tc.expectedError {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, tc.expected, policy)
+			}
+		})
+	}
+}
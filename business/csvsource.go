@@ -0,0 +1,108 @@
+package business
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+
+	"github.com/csv-processor/config"
+)
+
+// recordingReader counts the bytes read from r and keeps them until taken
+type recordingReader struct {
+	r    io.Reader
+	n    int64
+	kept []byte
+}
+
+func (c *recordingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	c.kept = append(c.kept, p[:n]...)
+	return n, err
+}
+
+// take returns a copy of the first n bytes kept and forgets them
+func (c *recordingReader) take(n int64) []byte {
+	taken := append([]byte(nil), c.kept[:n]...)
+	c.kept = c.kept[n:]
+	return taken
+}
+
+// csvSource is the source of the rows of a CSV file
+type csvSource struct {
+	reader   *csv.Reader
+	recorder *recordingReader
+	buffered *bufio.Reader
+	comment  byte
+
+	// start is the offset of the bytes kept by the recorder and line the
+	// lines before them
+	start int64
+	line  int64
+}
+
+// NewCSVSource returns the source of the rows of the CSV file read from r
+// in dialect, without its encoding settings, which r is already converted from
+func NewCSVSource(r io.Reader, dialect config.Dialect) (RowSource, error) {
+	return newCSVSource(r, 0, dialect)
+}
+
+// newCSVSource returns the source of the CSV file read from r, which
+// starts base bytes into the file. The csv reader reads straight from the
+// buffered reader given to it, so the bytes it has not consumed yet are
+// the ones buffered
+func newCSVSource(r io.Reader, base int64, dialect config.Dialect) (RowSource, error) {
+	recorder := &recordingReader{r: r, n: base}
+	buffered := bufio.NewReader(recorder)
+
+	reader := csv.NewReader(buffered)
+	err := configure(reader, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &csvSource{
+		reader:   reader,
+		recorder: recorder,
+		buffered: buffered,
+		start:    base,
+	}
+	if reader.Comment > 0 && reader.Comment < 0x80 {
+		source.comment = byte(reader.Comment)
+	}
+
+	return newFrameSource(source, dialect.HeaderRows, dialect.FooterRows, dialect.Fields), nil
+}
+
+func (s *csvSource) Next() (Row, error) {
+	fields, err := s.reader.Read()
+	if err == io.EOF {
+		return Row{}, io.EOF
+	}
+
+	end := s.recorder.n - int64(s.buffered.Buffered())
+	raw := s.recorder.take(end - s.start)
+	s.start = end
+
+	// the comments and blank lines skipped before the row were read too
+	for len(raw) > 0 && (raw[0] == '\n' || raw[0] == '\r' || (s.comment > 0 && raw[0] == s.comment)) {
+		skipped := bytes.IndexByte(raw, '\n')
+		if skipped < 0 {
+			break
+		}
+		raw = raw[skipped+1:]
+		s.line++
+	}
+
+	row := Row{
+		Fields: fields,
+		Line:   s.line + 1,
+		Raw:    bytes.TrimRight(raw, "\r\n"),
+		Offset: end,
+	}
+	s.line += int64(bytes.Count(raw, []byte("\n")))
+
+	return row, err
+}
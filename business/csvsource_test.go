@@ -0,0 +1,57 @@
+package business
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"testing"
+
+	"github.com/csv-processor/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVSource_Offset(t *testing.T) {
+	file, _ := crashFile(10)
+
+	records, err := csv.NewReader(bytes.NewReader(file)).ReadAll()
+	require.Nil(t, err)
+
+	source, err := NewCSVSource(bytes.NewReader(file), config.Dialect{})
+	require.Nil(t, err)
+
+	for i := range records {
+		row, err := source.Next()
+		require.Nil(t, err)
+
+		// the rest of the file read from the offset gives the remaining records
+		rest, err := csv.NewReader(bytes.NewReader(file[row.Offset:])).ReadAll()
+		require.Nil(t, err)
+		require.Equal(t, len(records)-i-1, len(rest))
+		if len(rest) > 0 {
+			require.Equal(t, records[i+1], rest[0])
+		}
+	}
+}
+
+func TestCSVSource_Next(t *testing.T) {
+	file := "unit,ticket\n# a comment\na,1\n\n\"b\nnorte\",2\r\nc,3"
+
+	source, err := NewCSVSource(bytes.NewBufferString(file), config.Dialect{Comment: "#", HeaderRows: 1})
+	require.Nil(t, err)
+
+	rows := []Row{}
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		rows = append(rows, row)
+	}
+
+	require.Equal(t, []Row{
+		{Fields: []string{"a", "1"}, Line: 3, Raw: []byte("a,1"), Offset: 28},
+		{Fields: []string{"b\nnorte", "2"}, Line: 5, Raw: []byte("\"b\nnorte\",2"), Offset: 42},
+		{Fields: []string{"c", "3"}, Line: 7, Raw: []byte("c,3"), Offset: 45},
+	}, rows)
+}
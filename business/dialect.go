@@ -0,0 +1,145 @@
+package business
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/csv-processor/config"
+)
+
+// sniffSample is how many bytes from the start of a file are looked at to
+// guess its dialect
+const sniffSample = 8 * 1024
+
+// sniffDelimiters are the delimiters a sniffed file is tried with
+var sniffDelimiters = []string{",", ";", "\t", "|"}
+
+// dialectRune returns the single character of a dialect setting, def when empty
+func dialectRune(name string, value string, def rune) (rune, error) {
+	switch value {
+	case "":
+		return def, nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+
+	if utf8.RuneCountInString(value) != 1 {
+		return 0, fmt.Errorf("%s [%s] must be a single character", name, value)
+	}
+
+	r, _ := utf8.DecodeRuneInString(value)
+
+	return r, nil
+}
+
+// configure sets the options of dialect on reader
+func configure(reader *csv.Reader, dialect config.Dialect) error {
+	delimiter, err := dialectRune("delimiter", dialect.Delimiter, ',')
+	if err != nil {
+		return err
+	}
+
+	comment, err := dialectRune("comment", dialect.Comment, 0)
+	if err != nil {
+		return err
+	}
+
+	reader.Comma = delimiter
+	reader.Comment = comment
+	reader.LazyQuotes = dialect.LazyQuotes
+	reader.TrimLeadingSpace = dialect.TrimLeadingSpace
+	// the number of fields is checked once the header is dropped
+	reader.FieldsPerRecord = -1
+
+	return nil
+}
+
+// SniffDialect guesses the dialect of a file starting with sample. The
+// delimiter is the one splitting the most lines into the same number of
+// fields, the first line is a header when it has no digits and the
+// following ones do, and lines starting with # are comments
+func SniffDialect(sample []byte) config.Dialect {
+	// only whole lines are looked at
+	if end := bytes.LastIndexByte(sample, '\n'); end > 0 {
+		sample = sample[:end+1]
+	}
+
+	dialect := config.Dialect{}
+	for _, line := range bytes.Split(sample, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("#")) {
+			dialect.Comment = "#"
+			break
+		}
+	}
+
+	best, bestFields, bestLines := [][]string(nil), 0, 0
+	for _, delimiter := range sniffDelimiters {
+		reader := csv.NewReader(bytes.NewReader(sample))
+		configure(reader, config.Dialect{Delimiter: delimiter, Comment: dialect.Comment, LazyQuotes: true, Fields: -1})
+
+		records := [][]string{}
+		for {
+			record, err := reader.Read()
+			if err != nil {
+				break
+			}
+			records = append(records, record)
+		}
+
+		fields, lines := modeFields(records)
+		if fields < 2 {
+			continue
+		}
+
+		if lines > bestLines || (lines == bestLines && fields > bestFields) {
+			best, bestFields, bestLines = records, fields, lines
+			dialect.Delimiter = delimiter
+		}
+	}
+
+	if len(best) == 0 {
+		return dialect
+	}
+
+	if bestLines < len(best) {
+		dialect.Fields = -1
+	}
+
+	if len(best) > 1 && !hasDigits(best[0]) && hasDigits(best[1]) {
+		dialect.HeaderRows = 1
+	}
+
+	return dialect
+}
+
+// modeFields returns the most common number of fields of records and how
+// many records have it, preferring more fields
+func modeFields(records [][]string) (int, int) {
+	counts := map[int]int{}
+	for _, record := range records {
+		counts[len(record)]++
+	}
+
+	fields, lines := 0, 0
+	for count, n := range counts {
+		if n > lines || (n == lines && count > fields) {
+			fields, lines = count, n
+		}
+	}
+
+	return fields, lines
+}
+
+func hasDigits(record []string) bool {
+	for _, field := range record {
+		if strings.IndexFunc(field, unicode.IsDigit) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
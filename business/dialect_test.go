@@ -0,0 +1,167 @@
+package business
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSniffDialect(t *testing.T) {
+	type TestRun struct {
+		name     string
+		sample   string
+		expected config.Dialect
+	}
+
+	tt := []TestRun{
+		{
+			name:     "comma",
+			sample:   "Monza,1001,12.50\nMonza,1002,5.00\n",
+			expected: config.Dialect{Delimiter: ","},
+		},
+		{
+			name:     "semicolon with decimal commas",
+			sample:   "Monza;1001;12,50\nMonza;1002;5,00\n",
+			expected: config.Dialect{Delimiter: ";"},
+		},
+		{
+			name:     "tab",
+			sample:   "Monza\t1001\t12.50\nMonza\t1002\t5.00\n",
+			expected: config.Dialect{Delimiter: "\t"},
+		},
+		{
+			name:     "header",
+			sample:   "Unidade|Ticket|Valor\nMonza|1001|12.50\nMonza|1002|5.00\n",
+			expected: config.Dialect{Delimiter: "|", HeaderRows: 1},
+		},
+		{
+			name:     "comment",
+			sample:   "# export\nMonza,1001,12.50\nMonza,1002,5.00\n",
+			expected: config.Dialect{Delimiter: ",", Comment: "#"},
+		},
+		{
+			name:     "ragged",
+			sample:   "Monza,1001,12.50\nMonza,1002,5.00\nMonza,1003,5.00\nTotal,3\n",
+			expected: config.Dialect{Delimiter: ",", Fields: -1},
+		},
+		{
+			name:     "partial last line",
+			sample:   "Monza;1001;12,50\nMonza;1002;5,00\nMonza;10",
+			expected: config.Dialect{Delimiter: ";"},
+		},
+		{
+			name:     "single column",
+			sample:   "Monza\nMonza\n",
+			expected: config.Dialect{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, SniffDialect([]byte(tc.sample)))
+		})
+	}
+}
+
+func TestInput_RowsDialect(t *testing.T) {
+	type TestRun struct {
+		name          string
+		dialect       config.Dialect
+		expected      *ValidationReport
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:     "configured",
+			dialect:  config.Dialect{Delimiter: ";", Comment: "#", HeaderRows: 1, FooterRows: 1},
+			expected: &ValidationReport{Rows: 3, Valid: 3},
+		},
+		{
+			name:     "sniffed",
+			dialect:  config.Dialect{Sniff: true, FooterRows: 1},
+			expected: &ValidationReport{Rows: 3, Valid: 3},
+		},
+		{
+			name:    "footer kept",
+			dialect: config.Dialect{Delimiter: ";", Comment: "#", HeaderRows: 1},
+			expected: &ValidationReport{Rows: 4, Valid: 3, Invalid: 1, Errors: []string{
+				"record 4: record on line 6: wrong number of fields",
+			}},
+		},
+		{
+			name:          "invalid delimiter",
+			dialect:       config.Dialect{Delimiter: ";;"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := Inputs("testdata/transactions-semicolon.csv", nil)
+			require.Nil(t, err)
+
+			source, in, err := inputs[0].Rows(tc.dialect)
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			defer in.Close()
+
+			processor := NewVP(nil, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NilObjectID)
+			report, err := processor.Validate(context.Background())
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, report)
+		})
+	}
+}
+
+func TestOpenRows_ResumeWithHeaderAndFooter(t *testing.T) {
+	dir := tempDir(t, map[string]string{
+		"file.csv": "unit,ticket\na,1\nb,2\nc,3\ntotal,3\n",
+	})
+	dialect := config.Dialect{HeaderRows: 1, FooterRows: 1, Fields: -1}
+
+	inputs, err := Inputs(filepath.Join(dir, "file.csv"), nil)
+	require.Nil(t, err)
+
+	source, in, err := openRows(inputs[0], model.Checkpoint{}, dialect)
+	require.Nil(t, err)
+
+	checkpoints := []model.Checkpoint{}
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		require.NotEqual(t, "total", row.Fields[0])
+		checkpoints = append(checkpoints, model.Checkpoint{Line: int64(len(checkpoints) + 1), Offset: row.Offset})
+	}
+	in.Close()
+	require.Equal(t, 3, len(checkpoints))
+
+	// resuming after the first row neither skips the header again nor
+	// returns the footer
+	source, in, err = openRows(inputs[0], checkpoints[0], dialect)
+	require.Nil(t, err)
+	defer in.Close()
+
+	records := [][]string{}
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		records = append(records, row.Fields)
+	}
+	require.Equal(t, [][]string{{"b", "2"}, {"c", "3"}}, records)
+}
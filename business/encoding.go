@@ -0,0 +1,88 @@
+package business
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// EncodingAuto detects the encoding from the start of the file
+	EncodingAuto = "auto"
+	EncodingUTF8 = "utf-8"
+	// EncodingWindows1252 is what most operator systems on Windows export
+	EncodingWindows1252 = "windows-1252"
+	EncodingISO88591    = "iso-8859-1"
+)
+
+// encodingSample is how many bytes are looked at to detect the encoding
+const encodingSample = 64 * 1024
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// encodings are the single byte encodings files are converted from
+var encodings = map[string]encoding.Encoding{
+	EncodingWindows1252: charmap.Windows1252,
+	EncodingISO88591:    charmap.ISO8859_1,
+}
+
+// resolveEncoding returns the encoding of a file starting with sample,
+// which is detected when name is auto or empty
+func resolveEncoding(name string, sample []byte) (string, error) {
+	name = strings.ToLower(name)
+	switch name {
+	case "", EncodingAuto:
+		return DetectEncoding(sample), nil
+	case EncodingUTF8, "utf8":
+		return EncodingUTF8, nil
+	case "cp1252":
+		return EncodingWindows1252, nil
+	case "latin1", "latin-1":
+		return EncodingISO88591, nil
+	}
+
+	if _, ok := encodings[name]; ok {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("encoding [%s] does not exists", name)
+}
+
+// DetectEncoding guesses the encoding of a file starting with sample. It
+// is UTF-8 when sample is valid UTF-8 and Windows-1252 otherwise, which
+// decodes the printable ISO-8859-1 characters alike
+func DetectEncoding(sample []byte) string {
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		if r == utf8.RuneError && size == 1 {
+			// the sample may end in the middle of a character
+			if !utf8.FullRune(sample) {
+				break
+			}
+			return EncodingWindows1252
+		}
+		sample = sample[size:]
+	}
+
+	return EncodingUTF8
+}
+
+// decode returns r converted from the encoding name to UTF-8
+func decode(r io.Reader, name string) io.Reader {
+	enc, ok := encodings[name]
+	if !ok {
+		return r
+	}
+
+	return enc.NewDecoder().Reader(r)
+}
+
+// hasBOM tells whether sample starts with the UTF-8 byte order mark
+func hasBOM(sample []byte) bool {
+	return bytes.HasPrefix(sample, utf8BOM)
+}
@@ -0,0 +1,126 @@
+package business
+
+import (
+	"context"
+	"testing"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	type TestRun struct {
+		name     string
+		sample   []byte
+		expected string
+	}
+
+	tt := []TestRun{
+		{
+			name:     "ascii",
+			sample:   []byte("Monza,1001"),
+			expected: EncodingUTF8,
+		},
+		{
+			name:     "utf-8",
+			sample:   []byte("São Paulo,CRÉDITO"),
+			expected: EncodingUTF8,
+		},
+		{
+			name:     "utf-8 cut in the middle of a character",
+			sample:   []byte("São Paulo,CR\xc3"),
+			expected: EncodingUTF8,
+		},
+		{
+			name:     "windows-1252",
+			sample:   []byte("S\xe3o Paulo,CR\xc9DITO"),
+			expected: EncodingWindows1252,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, DetectEncoding(tc.sample))
+		})
+	}
+}
+
+func TestInput_CSV(t *testing.T) {
+	type TestRun struct {
+		name          string
+		path          string
+		encoding      string
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:     "windows-1252 detected",
+			path:     "testdata/transactions-windows-1252.csv",
+			encoding: EncodingAuto,
+		},
+		{
+			name:     "windows-1252",
+			path:     "testdata/transactions-windows-1252.csv",
+			encoding: EncodingWindows1252,
+		},
+		{
+			name:     "iso-8859-1 detected",
+			path:     "testdata/transactions-iso-8859-1.csv",
+			encoding: EncodingAuto,
+		},
+		{
+			name:     "iso-8859-1",
+			path:     "testdata/transactions-iso-8859-1.csv",
+			encoding: EncodingISO88591,
+		},
+		{
+			name:     "utf-8 byte order mark",
+			path:     "testdata/transactions-utf-8-bom.csv",
+			encoding: EncodingAuto,
+		},
+		{
+			name:     "utf-8 byte order mark given",
+			path:     "testdata/transactions-utf-8-bom.csv",
+			encoding: EncodingUTF8,
+		},
+		{
+			name:          "unknown encoding",
+			path:          "testdata/transactions-utf-8-bom.csv",
+			encoding:      "ebcdic",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := Inputs(tc.path, nil)
+			require.Nil(t, err)
+
+			source, in, err := inputs[0].Rows(config.Dialect{Encoding: tc.encoding})
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			defer in.Close()
+
+			// the first field would carry the byte order mark
+			row, err := source.Next()
+			require.Nil(t, err)
+			require.Equal(t, "São Paulo", row.Fields[0])
+			require.Equal(t, "CRÉDITO", row.Fields[11])
+
+			source, in, err = inputs[0].Rows(config.Dialect{Encoding: tc.encoding})
+			require.Nil(t, err)
+			defer in.Close()
+
+			processor := NewVP(nil, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NilObjectID)
+			report, err := processor.Validate(context.Background())
+			require.Nil(t, err)
+			require.Equal(t, &ValidationReport{Rows: 3, Valid: 3}, report)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package business
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+	"go.uber.org/zap"
+)
+
+const (
+	// ExportCSV writes the same CSV layout the importer reads
+	ExportCSV = "csv"
+	// ExportJSONL writes one JSON transaction per line
+	ExportJSONL = "jsonl"
+	// ExportParquet writes a parquet file
+	ExportParquet = "parquet"
+
+	// importDateLayout is the date layout read by parseDate
+	importDateLayout = "02/01/2006 15:04:05"
+)
+
+type Export interface {
+	Export(ctx context.Context, filter model.TransactionFilter) (int64, error)
+}
+
+type exportImpl struct {
+	dbAcess *mongo.DB
+	writer  io.Writer
+	format  string
+	logger  *zap.Logger
+}
+
+func NewExport(dbAcess *mongo.DB, writer io.Writer, format string) Export {
+	log, _ := zap.NewProduction()
+
+	return &exportImpl{
+		dbAcess: dbAcess,
+		writer:  writer,
+		format:  format,
+		logger:  log,
+	}
+}
+
+// Export streams the transactions matching filter to the writer, their
+// plates and identities masked, and returns how many were written
+func (s *exportImpl) Export(ctx context.Context, filter model.TransactionFilter) (int64, error) {
+	records, err := newRecordWriter(s.writer, s.format)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	err = s.dbAcess.TransactionCollection.Iterate(ctx, filter, func(transaction *model.Transaction) error {
+		total++
+		if total%10000 == 0 {
+			s.logger.Sugar().Infow("exporting...", "total", total)
+		}
+		MaskTransaction(transaction)
+		return records.Write(transaction)
+	})
+	if err != nil {
+		return total, err
+	}
+
+	return total, records.Close()
+}
+
+// recordWriter writes transactions in an export format
+type recordWriter interface {
+	Write(transaction *model.Transaction) error
+	Close() error
+}
+
+func newRecordWriter(w io.Writer, format string) (recordWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &csvRecordWriter{writer: csv.NewWriter(w)}, nil
+	case ExportJSONL:
+		buffered := bufio.NewWriter(w)
+		return &jsonlRecordWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case ExportParquet:
+		pw, err := writer.NewParquetWriterFromWriter(w, new(parquetTransaction), 4)
+		if err != nil {
+			return nil, err
+		}
+		pw.CompressionType = parquet.CompressionCodec_SNAPPY
+		return &parquetRecordWriter{writer: pw}, nil
+	default:
+		return nil, fmt.Errorf("export format [%s] does not exists", format)
+	}
+}
+
+type csvRecordWriter struct {
+	writer *csv.Writer
+}
+
+// Write writes the transaction in the import layout, which reads it back
+// as it is. Transactions stored before their unit was kept have the name
+// of their park in its place, the agreement is not kept and left empty
+func (r *csvRecordWriter) Write(transaction *model.Transaction) error {
+	unit := transaction.Unit
+	if unit == "" {
+		unit = transaction.ParkingInfo.Name
+	}
+
+	return r.writer.Write([]string{
+		unit,
+		transaction.Sequence,
+		transaction.Identity,
+		transaction.Matricula,
+		transaction.UseType,
+		"",
+		importDate(transaction.CheckinDate),
+		importDate(transaction.CheckoutDate),
+		strconv.FormatInt(transaction.Duration, 10),
+		strconv.FormatFloat(transaction.FareAmount, 'f', 2, 64),
+		strconv.FormatFloat(transaction.PaidAmount, 'f', 2, 64),
+		paymentMethodCode(transaction.PaymentMethod),
+		useTypeCode(transaction.UseType),
+	})
+}
+
+// importDate formats a date the way parseDate reads it, missing dates of
+// open stays and lost tickets are left empty
+func importDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+
+	return date.UTC().Format(importDateLayout)
+}
+
+func (r *csvRecordWriter) Close() error {
+	r.writer.Flush()
+	return r.writer.Error()
+}
+
+type jsonlRecordWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (r *jsonlRecordWriter) Write(transaction *model.Transaction) error {
+	return r.encoder.Encode(transaction)
+}
+
+func (r *jsonlRecordWriter) Close() error {
+	return r.buffered.Flush()
+}
+
+// parquetTransaction is the flat parquet schema of a transaction
+type parquetTransaction struct {
+	ID            string  `parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ParkingID     int64   `parquet:"name=parking_id, type=INT64"`
+	ParkingSlug   string  `parquet:"name=parking_slug, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	CheckinDate   int64   `parquet:"name=checkin_date, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	CheckoutDate  int64   `parquet:"name=checkout_date, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	FareAmount    float64 `parquet:"name=fare_amount, type=DOUBLE"`
+	FareName      string  `parquet:"name=fare_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	PaidAmount    float64 `parquet:"name=paid_amount, type=DOUBLE"`
+	Discount      float64 `parquet:"name=discount, type=DOUBLE"`
+	PaymentMethod string  `parquet:"name=payment_method, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	UseType       string  `parquet:"name=use_type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	OfferType     string  `parquet:"name=offer_type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Duration      int64   `parquet:"name=duration, type=INT64"`
+	Buckets       float64 `parquet:"name=buckets, type=DOUBLE"`
+	IsValid       bool    `parquet:"name=is_valid, type=BOOLEAN"`
+	Status        int32   `parquet:"name=status, type=INT32"`
+	Sequence      string  `parquet:"name=sequence, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Matricula     string  `parquet:"name=matricula, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Categoria     string  `parquet:"name=categoria, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+}
+
+type parquetRecordWriter struct {
+	writer *writer.ParquetWriter
+}
+
+func (r *parquetRecordWriter) Write(transaction *model.Transaction) error {
+	return r.writer.Write(parquetTransaction{
+		ID:            transaction.ID.Hex(),
+		ParkingID:     transaction.ParkingInfo.ID,
+		ParkingSlug:   transaction.ParkingInfo.Slug,
+		CheckinDate:   transaction.CheckinDate.UnixNano() / 1e6,
+		CheckoutDate:  transaction.CheckoutDate.UnixNano() / 1e6,
+		FareAmount:    transaction.FareAmount,
+		FareName:      transaction.FareName,
+		PaidAmount:    transaction.PaidAmount,
+		Discount:      transaction.Discount,
+		PaymentMethod: transaction.PaymentMethod,
+		UseType:       transaction.UseType,
+		OfferType:     transaction.OfferType,
+		Duration:      transaction.Duration,
+		Buckets:       transaction.Buckets,
+		IsValid:       transaction.IsValid,
+		Status:        int32(transaction.Status),
+		Sequence:      transaction.Sequence,
+		Matricula:     transaction.Matricula,
+		Categoria:     transaction.Categoria,
+	})
+}
+
+func (r *parquetRecordWriter) Close() error {
+	return r.writer.WriteStop()
+}
+
+// paymentMethodCode returns the operator payment code of a payment method,
+// the opposite of getPaymentMethod
+func paymentMethodCode(method string) string {
+	for code, value := range paymentMethods {
+		if value == method {
+			return code
+		}
+	}
+
+	return method
+}
+
+// useTypeCode returns an operator table of a use type, the opposite of getUseType
+func useTypeCode(useType string) string {
+	if useType == "Mensalista" {
+		return "MENSALISTA"
+	}
+
+	return "NORMAL"
+}
@@ -0,0 +1,156 @@
+package business
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func exportTransactions() []*model.Transaction {
+	return []*model.Transaction{
+		{
+			ID:            primitive.NewObjectID(),
+			ParkingInfo:   model.Parking{ID: 6, Name: "Monza", Slug: "monza"},
+			CheckinDate:   time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC),
+			CheckoutDate:  time.Date(2020, 10, 1, 10, 50, 30, 0, time.UTC),
+			Sequence:      "123",
+			Matricula:     "ABC1234",
+			Identity:      "12345678901",
+			Unit:          "Monza P1",
+			PaidAmount:    12.5,
+			FareAmount:    12.5,
+			PaymentMethod: "Transferência",
+			UseType:       "Avulso",
+			Duration:      30,
+			Buckets:       1,
+		},
+		{
+			ID:            primitive.NewObjectID(),
+			ParkingInfo:   model.Parking{ID: 6, Name: "Monza", Slug: "monza"},
+			CheckinDate:   time.Date(2020, 10, 1, 8, 0, 0, 0, time.UTC),
+			CheckoutDate:  time.Date(2020, 10, 1, 18, 0, 0, 0, time.UTC),
+			Sequence:      "124",
+			Matricula:     "ABC1D23",
+			PaymentMethod: "N/I",
+			UseType:       "Mensalista",
+			Duration:      600,
+			Buckets:       10,
+		},
+		{
+			ID:          primitive.NewObjectID(),
+			ParkingInfo: model.Parking{ID: 6, Name: "Monza", Slug: "monza"},
+			CheckinDate: time.Date(2020, 10, 1, 22, 0, 0, 0, time.UTC),
+			Sequence:    "125",
+			Matricula:   "DEF4567",
+			UseType:     "Avulso",
+			Status:      OPEN,
+		},
+	}
+}
+
+func TestExport_CSVRoundTrip(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	records, err := newRecordWriter(out, ExportCSV)
+	require.Nil(t, err)
+
+	transactions := exportTransactions()
+	for _, transaction := range transactions {
+		require.Nil(t, records.Write(transaction))
+	}
+	require.Nil(t, records.Close())
+
+	// the export is imported back as it is
+	source, err := NewCSVSource(out, config.Dialect{})
+	require.Nil(t, err)
+	store := &memoryStore{}
+	parking := model.Parking{ID: 6, Name: "Monza", Slug: "monza"}
+	summary, err := newVP(store, source, "transactions", parking, HoursTouched{}, primitive.NilObjectID).Process(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, model.ImportSummary{Rows: 3, Inserted: 3, Open: 1}, *summary)
+
+	for i, imported := range store.transactions {
+		expected := transactions[i]
+
+		require.Equal(t, expected.Sequence, imported.Sequence)
+		require.Equal(t, expected.Matricula, imported.Matricula)
+		require.Equal(t, expected.Identity, imported.Identity)
+		require.Equal(t, expected.CheckinDate, imported.CheckinDate)
+		require.Equal(t, expected.CheckoutDate, imported.CheckoutDate)
+		require.Equal(t, expected.PaidAmount, imported.PaidAmount)
+		require.Equal(t, expected.PaymentMethod, imported.PaymentMethod)
+		require.Equal(t, expected.UseType, imported.UseType)
+		require.Equal(t, expected.Status, imported.Status)
+	}
+	require.Equal(t, "Monza P1", store.transactions[0].Unit)
+	require.Equal(t, "Monza", store.transactions[1].Unit)
+}
+
+func TestExport_JSONL(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	records, err := newRecordWriter(out, ExportJSONL)
+	require.Nil(t, err)
+
+	transactions := exportTransactions()
+	for _, transaction := range transactions {
+		require.Nil(t, records.Write(transaction))
+	}
+	require.Nil(t, records.Close())
+
+	scanner := bufio.NewScanner(out)
+	i := 0
+	for scanner.Scan() {
+		decoded := model.Transaction{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &decoded))
+		require.Equal(t, *transactions[i], decoded)
+		i++
+	}
+	require.Equal(t, len(transactions), i)
+}
+
+func TestExport_Parquet(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	records, err := newRecordWriter(out, ExportParquet)
+	require.Nil(t, err)
+
+	transactions := exportTransactions()
+	for _, transaction := range transactions {
+		require.Nil(t, records.Write(transaction))
+	}
+	require.Nil(t, records.Close())
+
+	file, err := buffer.NewBufferFile(out.Bytes())
+	require.Nil(t, err)
+
+	pr, err := reader.NewParquetReader(file, new(parquetTransaction), 1)
+	require.Nil(t, err)
+	require.Equal(t, int64(len(transactions)), pr.GetNumRows())
+
+	rows := make([]parquetTransaction, len(transactions))
+	require.Nil(t, pr.Read(&rows))
+	pr.ReadStop()
+
+	for i, row := range rows {
+		require.Equal(t, transactions[i].ID.Hex(), row.ID)
+		require.Equal(t, transactions[i].Matricula, row.Matricula)
+		require.Equal(t, transactions[i].CheckoutDate.UnixNano()/1e6, row.CheckoutDate)
+		require.Equal(t, transactions[i].Duration, row.Duration)
+	}
+}
+
+func TestExport_UnknownFormat(t *testing.T) {
+	_, err := newRecordWriter(&bytes.Buffer{}, "error")
+	require.NotNil(t, err)
+}
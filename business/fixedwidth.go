@@ -0,0 +1,146 @@
+package business
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/csv-processor/config"
+)
+
+const (
+	TrimBoth  = "both"
+	TrimLeft  = "left"
+	TrimRight = "right"
+	TrimNone  = "none"
+)
+
+// fixedColumn is a column of a fixed-width file checked and resolved to
+// the index of its transaction field
+type fixedColumn struct {
+	index  int
+	start  int
+	length int
+	trim   string
+	pad    rune
+}
+
+// NewFixedWidthSource returns the source of the rows of a fixed-width file
+// whose fields are where columns say. Rows have the fields of transactions
+// files in their order, the ones without a column are empty
+func NewFixedWidthSource(r io.Reader, columns []config.Column) (RowSource, error) {
+	return newFixedWidthSource(r, 0, columns)
+}
+
+func newFixedWidthSource(r io.Reader, base int64, columns []config.Column) (*lineSource, error) {
+	fixed, err := fixedColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLineSource(r, base, func(line []byte) ([]string, error) {
+		return parseFixedWidth(line, fixed)
+	}), nil
+}
+
+// fixedColumns checks columns and resolves their transaction fields
+func fixedColumns(columns []config.Column) ([]fixedColumn, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("fixed-width files need their columns")
+	}
+
+	indexes := map[string]int{}
+	for i, name := range transactionColumns {
+		indexes[name] = i
+	}
+
+	fixed := make([]fixedColumn, len(columns))
+	seen := map[string]bool{}
+	for i, column := range columns {
+		index, ok := indexes[column.Name]
+		if !ok {
+			return nil, fmt.Errorf("column [%s] is not a transaction field", column.Name)
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("column [%s] is given twice", column.Name)
+		}
+		seen[column.Name] = true
+
+		if column.Start < 1 || column.Length < 1 {
+			return nil, fmt.Errorf("column [%s] must start at 1 or after and have a length", column.Name)
+		}
+
+		trim := column.Trim
+		switch trim {
+		case "":
+			trim = TrimBoth
+		case TrimBoth, TrimLeft, TrimRight, TrimNone:
+		default:
+			return nil, fmt.Errorf("trim [%s] of column [%s] is not both, left, right or none", column.Trim, column.Name)
+		}
+
+		pad, err := dialectRune("pad of column "+column.Name, column.Pad, ' ')
+		if err != nil {
+			return nil, err
+		}
+
+		fixed[i] = fixedColumn{
+			index:  index,
+			start:  column.Start - 1,
+			length: column.Length,
+			trim:   trim,
+			pad:    pad,
+		}
+	}
+
+	return fixed, nil
+}
+
+// parseFixedWidth cuts the fields of line, counted in characters. Lines
+// may be cut short of the last columns, whose fields are then shorter or
+// empty
+func parseFixedWidth(line []byte, columns []fixedColumn) ([]string, error) {
+	if !utf8.Valid(line) {
+		return nil, fmt.Errorf("invalid UTF-8")
+	}
+
+	runes := []rune(string(line))
+	fields := make([]string, len(transactionColumns))
+	for _, column := range columns {
+		start, end := column.start, column.start+column.length
+		if start > len(runes) {
+			start = len(runes)
+		}
+		if end > len(runes) {
+			end = len(runes)
+		}
+
+		fields[column.index] = trimPad(string(runes[start:end]), column.trim, column.pad)
+	}
+
+	return fields, nil
+}
+
+// trimPad trims the pad characters of field on the trim side
+func trimPad(field string, trim string, pad rune) string {
+	isPad := func(r rune) bool {
+		return r == pad
+	}
+
+	switch trim {
+	case TrimLeft:
+		field = strings.TrimLeftFunc(field, isPad)
+	case TrimRight:
+		field = strings.TrimRightFunc(field, isPad)
+	case TrimBoth:
+		field = strings.TrimFunc(field, isPad)
+	}
+
+	// fields padded with anything else may still be blank
+	if pad != ' ' && trim != TrimNone {
+		field = strings.TrimSpace(field)
+	}
+
+	return field
+}
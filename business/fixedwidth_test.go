@@ -0,0 +1,140 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// legacyColumns are the columns of the report of the controller of the
+// testdata fixed-width file
+var legacyColumns = []config.Column{
+	{Name: "unit", Start: 1, Length: 10},
+	{Name: "ticket", Start: 11, Length: 8, Trim: "left", Pad: "0"},
+	{Name: "plate", Start: 19, Length: 8},
+	{Name: "use_type", Start: 27, Length: 12},
+	{Name: "checkin", Start: 39, Length: 19},
+	{Name: "checkout", Start: 58, Length: 19},
+	{Name: "paid", Start: 77, Length: 10},
+	{Name: "payment_method", Start: 87, Length: 12},
+	{Name: "table", Start: 99, Length: 12},
+}
+
+func TestFixedWidthSource(t *testing.T) {
+	type TestRun struct {
+		name          string
+		columns       []config.Column
+		line          string
+		expected      []string
+		expectedError bool
+	}
+
+	fields := func(values map[int]string) []string {
+		record := make([]string, len(transactionColumns))
+		for i, value := range values {
+			record[i] = value
+		}
+		return record
+	}
+
+	tt := []TestRun{
+		{
+			name:     "trimmed both sides",
+			columns:  []config.Column{{Name: "unit", Start: 1, Length: 7}, {Name: "paid", Start: 8, Length: 6}},
+			line:     " Monza  12.50",
+			expected: fields(map[int]string{0: "Monza", 10: "12.50"}),
+		},
+		{
+			name: "trim rules",
+			columns: []config.Column{
+				{Name: "unit", Start: 1, Length: 7, Trim: "right"},
+				{Name: "ticket", Start: 8, Length: 6, Trim: "left", Pad: "0"},
+				{Name: "plate", Start: 14, Length: 5, Trim: "none"},
+				{Name: "table", Start: 19, Length: 6, Trim: "both", Pad: "*"},
+			},
+			line:     " São   001020 AB  **N***",
+			expected: fields(map[int]string{0: " São", 1: "1020", 3: " AB  ", 12: "N"}),
+		},
+		{
+			name:     "line cut short",
+			columns:  []config.Column{{Name: "unit", Start: 1, Length: 5}, {Name: "ticket", Start: 6, Length: 4}, {Name: "table", Start: 10, Length: 6}},
+			line:     "Monza12",
+			expected: fields(map[int]string{0: "Monza", 1: "12"}),
+		},
+		{
+			name:          "unknown field",
+			columns:       []config.Column{{Name: "unidade", Start: 1, Length: 5}},
+			expectedError: true,
+		},
+		{
+			name:          "field given twice",
+			columns:       []config.Column{{Name: "unit", Start: 1, Length: 5}, {Name: "unit", Start: 6, Length: 5}},
+			expectedError: true,
+		},
+		{
+			name:          "start from 0",
+			columns:       []config.Column{{Name: "unit", Start: 0, Length: 5}},
+			expectedError: true,
+		},
+		{
+			name:          "unknown trim",
+			columns:       []config.Column{{Name: "unit", Start: 1, Length: 5, Trim: "center"}},
+			expectedError: true,
+		},
+		{
+			name:          "long pad",
+			columns:       []config.Column{{Name: "unit", Start: 1, Length: 5, Pad: "00"}},
+			expectedError: true,
+		},
+		{
+			name:          "no columns",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			source, err := NewFixedWidthSource(bytes.NewBufferString(tc.line+"\n"), tc.columns)
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+
+			row, err := source.Next()
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, row.Fields)
+		})
+	}
+}
+
+func TestInput_RowsFixedWidth(t *testing.T) {
+	inputs, err := Inputs("testdata/transactions-fixed.txt", nil)
+	require.Nil(t, err)
+
+	source, in, err := inputs[0].Rows(config.Dialect{Columns: legacyColumns, HeaderRows: 1, FooterRows: 1})
+	require.Nil(t, err)
+
+	row, err := source.Next()
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"Monza", "5001", "", "ABC1234", "NORMAL", "", "01/10/2020 10:20:00",
+		"01/10/2020 10:50:00", "", "", "12.50", "DINHEIRO", "NORMAL",
+	}, row.Fields)
+	require.Equal(t, int64(2), row.Line)
+	in.Close()
+
+	source, in, err = inputs[0].Rows(config.Dialect{Columns: legacyColumns, HeaderRows: 1, FooterRows: 1})
+	require.Nil(t, err)
+	defer in.Close()
+
+	processor := NewVP(nil, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NilObjectID)
+	report, err := processor.Validate(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, &ValidationReport{Rows: 3, Valid: 3}, report)
+}
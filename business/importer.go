@@ -0,0 +1,387 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// manifestSuffix is appended to a file path to find its sidecar manifest
+const manifestSuffix = ".manifest.json"
+
+type Importer interface {
+	Import(ctx context.Context, file ImportFile) (record *model.Import, imported bool, err error)
+}
+
+// ImportFile is a file to import along with the park it belongs to
+type ImportFile struct {
+	Path     string
+	Filetype string
+	Parking  model.Parking
+	// Input is what is read, the plain file at Path when not set
+	Input Input
+	// Dialect is how the file is read, a UTF-8 or detected CSV by default
+	Dialect config.Dialect
+	// Rejects receives the rows that could not be imported, when set
+	Rejects *RejectsWriter
+}
+
+// Inputs returns a file to import per input of the file, which is one
+// per CSV member for zip archives and the file itself otherwise
+func (f ImportFile) Inputs(stdin io.Reader) ([]ImportFile, error) {
+	inputs, err := Inputs(f.Path, stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []ImportFile{}
+	for _, input := range inputs {
+		file := f
+		file.Path = input.Path
+		file.Input = input
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// ImportOptions changes how files are imported
+type ImportOptions struct {
+	// Operator is who runs the import, recorded in the ledger
+	Operator string
+	// Force imports files whose content was already imported
+	Force bool
+	// Resume continues the latest unfinished import of the same content
+	// from its checkpoint
+	Resume bool
+	// Pseudonymizer hashes the plates and identities before they are
+	// stored, nil keeps them in clear
+	Pseudonymizer *Pseudonymizer
+}
+
+type importerImpl struct {
+	dbAcess *mongo.DB
+	bucket  Bucketing
+	options ImportOptions
+	logger  *zap.Logger
+}
+
+func NewImporter(dbAcess *mongo.DB, bucket Bucketing, options ImportOptions) Importer {
+	log, _ := zap.NewProduction()
+
+	return &importerImpl{
+		dbAcess: dbAcess,
+		bucket:  bucket,
+		options: options,
+		logger:  log,
+	}
+}
+
+// Import processes a file and records it in the imports ledger. A file
+// whose content was already imported is refused, unless forced, and the
+// previous record returned with imported false. Streams are neither
+// refused nor resumed, their checksum is recorded once read
+func (s *importerImpl) Import(ctx context.Context, file ImportFile) (*model.Import, bool, error) {
+	if file.Input.open == nil {
+		inputs, err := Inputs(file.Path, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		file.Input = inputs[0]
+	}
+
+	if file.Input.Stream {
+		if s.options.Resume {
+			s.logger.Sugar().Warnw("streams can not be resumed, importing from the start", "path", file.Path)
+		}
+
+		return s.create(ctx, file, &model.Import{
+			Path:        file.Path,
+			Filetype:    file.Filetype,
+			ParkingInfo: file.Parking,
+			Status:      model.ImportRunning,
+			Operator:    s.options.Operator,
+			StartedAt:   time.Now(),
+			Checkpoint:  &model.Checkpoint{},
+		})
+	}
+
+	checksum, err := file.Input.Checksum()
+	if err != nil {
+		return nil, false, err
+	}
+
+	previous, err := s.dbAcess.ImportCollection.GetDoneByChecksum(ctx, checksum)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !previous.ID.IsZero() {
+		if !s.options.Force {
+			s.logger.Sugar().Infow("already imported", "path", file.Path, "import", previous.ID.Hex(), "previous", previous.Path)
+			return previous, false, nil
+		}
+		s.logger.Sugar().Infow("forcing import", "path", file.Path, "import", previous.ID.Hex(), "previous", previous.Path)
+	}
+
+	unfinished, err := s.dbAcess.ImportCollection.GetResumable(ctx, checksum)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if s.options.Resume && !unfinished.ID.IsZero() {
+		s.logger.Sugar().Infow("resuming import", "path", file.Path, "import", unfinished.ID.Hex(), "line", unfinished.Checkpoint.Line)
+
+		unfinished.Status = model.ImportRunning
+		unfinished.Error = ""
+		unfinished.Operator = s.options.Operator
+		record, err := s.dbAcess.ImportCollection.Update(ctx, unfinished)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return s.process(ctx, file, record, true)
+	}
+
+	if !unfinished.ID.IsZero() {
+		s.logger.Sugar().Warnw("an unfinished import of the same content exists, use resume to continue it",
+			"path", file.Path, "import", unfinished.ID.Hex(), "line", unfinished.Checkpoint.Line)
+	}
+
+	return s.create(ctx, file, &model.Import{
+		Path:        file.Path,
+		Checksum:    checksum,
+		Filetype:    file.Filetype,
+		ParkingInfo: file.Parking,
+		Status:      model.ImportRunning,
+		Operator:    s.options.Operator,
+		StartedAt:   time.Now(),
+		Checkpoint:  &model.Checkpoint{},
+	})
+}
+
+// create creates the ledger record of a new import and processes the file
+func (s *importerImpl) create(ctx context.Context, file ImportFile, record *model.Import) (*model.Import, bool, error) {
+	record, err := s.dbAcess.ImportCollection.Create(ctx, record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return s.process(ctx, file, record, false)
+}
+
+// process reads the file from the checkpoint of record and updates the
+// ledger once done
+func (s *importerImpl) process(ctx context.Context, file ImportFile, record *model.Import, resumed bool) (*model.Import, bool, error) {
+	start := model.Checkpoint{}
+	if record.Checkpoint != nil {
+		start = *record.Checkpoint
+	}
+
+	summary, processErr := s.read(ctx, file, record.ID, start, resumed)
+	if summary != nil {
+		record.Summary = *summary
+	}
+
+	if file.Input.Stream {
+		record.Checksum, _ = file.Input.Checksum()
+	}
+
+	// the processor keeps the stored checkpoint up to date
+	record.Checkpoint = nil
+
+	finished := time.Now()
+	record.FinishedAt = &finished
+	record.Status = model.ImportDone
+	if processErr != nil {
+		record.Status = model.ImportFailed
+		record.Error = processErr.Error()
+	}
+
+	// the ledger is updated even when the import was canceled
+	record, err := s.dbAcess.ImportCollection.Update(context.Background(), record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return record, true, processErr
+}
+
+func (s *importerImpl) read(ctx context.Context, file ImportFile, importID primitive.ObjectID, start model.Checkpoint, resumed bool) (*model.ImportSummary, error) {
+	source, in, err := openRows(file.Input, start, file.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	processor := newVP(mongoStore{dbAcess: s.dbAcess}, source, file.Filetype, file.Parking, s.bucket, importID)
+	processor.start = start
+	processor.resumed = resumed
+	processor.rejects = file.Rejects
+	processor.pseudonymizer = s.options.Pseudonymizer
+
+	return processor.Process(ctx)
+}
+
+// Checksum returns the hex SHA-256 of the file at path
+func Checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening file [%s]: [%s]", path, err.Error())
+	}
+	defer file.Close()
+
+	return checksum(path, file)
+}
+
+// ListFiles returns the files to import from target, which is the standard
+// input, a file, a directory whose plain, compressed and zipped CSV files
+// and spreadsheets are all imported or a glob pattern
+func ListFiles(target string) ([]string, error) {
+	if target == StdinPath {
+		return []string{StdinPath}, nil
+	}
+
+	info, err := os.Stat(target)
+	if err == nil && !info.IsDir() {
+		return []string{target}, nil
+	}
+
+	var files []string
+	if err == nil {
+		for _, pattern := range inputPatterns {
+			matches, _ := filepath.Glob(filepath.Join(target, pattern))
+			files = append(files, matches...)
+		}
+	} else if strings.ContainsAny(target, "*?[") {
+		files, err = filepath.Glob(target)
+	} else {
+		return nil, fmt.Errorf("error opening [%s]: [%s]", target, err.Error())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing [%s]: [%s]", target, err.Error())
+	}
+
+	listed := []string{}
+	for _, file := range files {
+		if strings.HasSuffix(file, manifestSuffix) {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			listed = append(listed, file)
+		}
+	}
+	sort.Strings(listed)
+
+	return listed, nil
+}
+
+// Manifest is the sidecar file describing the park and filetype of a
+// file, named after it with the .manifest.json suffix
+type Manifest struct {
+	ParkID   int64  `json:"parkid"`
+	ParkSlug string `json:"parkslug"`
+	Filetype string `json:"filetype"`
+}
+
+// ParkResolver finds out the park of a file from its sidecar manifest,
+// then from the named groups slug and id of Pattern matched against the
+// file name, then from Park, and looks it up in the registry
+type ParkResolver struct {
+	Pattern *regexp.Regexp
+	// Park is the slug of the park of files without a manifest
+	Park string
+	// ParkID is the ID of the park of files without a manifest, checked
+	// against the park of the slug when both are given
+	ParkID   int64
+	Filetype string
+	Parks    Parks
+}
+
+// Resolve returns the file to import at path with its park, refusing the
+// parks missing from the registry
+func (r ParkResolver) Resolve(ctx context.Context, path string) (ImportFile, error) {
+	file := ImportFile{
+		Path:     path,
+		Filetype: r.Filetype,
+	}
+
+	// the standard input has neither a manifest nor a file name
+	if path == StdinPath {
+		return r.complete(ctx, file, model.Parking{ID: r.ParkID, Slug: r.Park})
+	}
+
+	found := model.Parking{ID: r.ParkID, Slug: r.Park}
+	content, err := ioutil.ReadFile(path + manifestSuffix)
+	if err == nil {
+		manifest := Manifest{}
+		err = json.Unmarshal(content, &manifest)
+		if err != nil {
+			return file, fmt.Errorf("error parsing manifest of [%s]: [%s]", path, err.Error())
+		}
+
+		found = model.Parking{ID: manifest.ParkID, Slug: manifest.ParkSlug}
+		if manifest.Filetype != "" {
+			file.Filetype = manifest.Filetype
+		}
+	} else if !os.IsNotExist(err) {
+		return file, fmt.Errorf("error reading manifest of [%s]: [%s]", path, err.Error())
+	} else if r.Pattern != nil {
+		match := r.Pattern.FindStringSubmatch(filepath.Base(path))
+		if match == nil {
+			return file, fmt.Errorf("file [%s] does not match pattern [%s]", path, r.Pattern.String())
+		}
+
+		found = model.Parking{}
+		for i, name := range r.Pattern.SubexpNames() {
+			switch name {
+			case "id":
+				id, err := strconv.ParseInt(match[i], 10, 64)
+				if err != nil {
+					return file, fmt.Errorf("error parsing park id of [%s]: [%s]", path, err.Error())
+				}
+				found.ID = id
+			case "slug":
+				found.Slug = match[i]
+			}
+		}
+	}
+
+	return r.complete(ctx, file, found)
+}
+
+// complete sets the park of file to the park of the registry found by slug,
+// or by ID without one, which must agree with the ID found along with a slug
+func (r ParkResolver) complete(ctx context.Context, file ImportFile, found model.Parking) (ImportFile, error) {
+	if found.Slug == "" && found.ID == 0 {
+		return file, fmt.Errorf("could not find the park of [%s]", file.Path)
+	}
+
+	park, err := findParking(ctx, r.Parks, found)
+	if err != nil {
+		return file, err
+	}
+
+	if found.ID != 0 && found.ID != park.ID {
+		return file, fmt.Errorf("park [%s] of [%s] has id [%d], not [%d]", park.Slug, file.Path, park.ID, found.ID)
+	}
+
+	file.Parking = park.Parking()
+
+	return file, nil
+}
@@ -0,0 +1,221 @@
+package business
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestListFiles(t *testing.T) {
+	dir := tempDir(t, map[string]string{
+		"monza_6_20201002.csv":               "",
+		"monza_6_20201001.csv":               "",
+		"monza_6_20201001.csv.manifest.json": "",
+		"monza_6_20201003.csv.gz":            "",
+		"monza_6_202010.zip":                 "",
+		"notes.txt":                          "",
+	})
+
+	type TestRun struct {
+		name          string
+		target        string
+		expected      []string
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:     "file",
+			target:   filepath.Join(dir, "notes.txt"),
+			expected: []string{filepath.Join(dir, "notes.txt")},
+		},
+		{
+			name:   "directory",
+			target: dir,
+			expected: []string{
+				filepath.Join(dir, "monza_6_202010.zip"),
+				filepath.Join(dir, "monza_6_20201001.csv"),
+				filepath.Join(dir, "monza_6_20201002.csv"),
+				filepath.Join(dir, "monza_6_20201003.csv.gz"),
+			},
+		},
+		{
+			name:     "stdin",
+			target:   StdinPath,
+			expected: []string{StdinPath},
+		},
+		{
+			name:   "glob",
+			target: filepath.Join(dir, "monza_*.csv"),
+			expected: []string{
+				filepath.Join(dir, "monza_6_20201001.csv"),
+				filepath.Join(dir, "monza_6_20201002.csv"),
+			},
+		},
+		{
+			name:          "not found",
+			target:        filepath.Join(dir, "error.csv"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := ListFiles(tc.target)
+
+			if tc.expectedError {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, tc.expected, files)
+			}
+		})
+	}
+}
+
+func TestParkResolver_Resolve(t *testing.T) {
+	dir := tempDir(t, map[string]string{
+		"monza_6_20201001.csv":      "",
+		"interlagos_6_20201001.csv": "",
+		"export.csv":                "",
+		"export.csv.manifest.json":  `{"parkid": 7, "parkslug": "interlagos", "filetype": "transactions"}`,
+		"broken.csv":                "",
+		"broken.csv.manifest.json":  `{"parkid":`,
+	})
+
+	pattern := regexp.MustCompile(`^(?P<slug>[a-z]+)_(?P<id>\d+)_\d{8}\.csv$`)
+	idPattern := regexp.MustCompile(`^[a-z]+_(?P<id>\d+)_\d{8}\.csv$`)
+	parks := memoryParks{
+		{ID: 6, Name: "Monza", Slug: "monza"},
+		{ID: 7, Name: "Interlagos", Slug: "interlagos"},
+	}
+
+	type TestRun struct {
+		name          string
+		resolver      ParkResolver
+		path          string
+		expected      model.Parking
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:     "pattern",
+			resolver: ParkResolver{Pattern: pattern, Filetype: "transactions", Parks: parks},
+			path:     filepath.Join(dir, "monza_6_20201001.csv"),
+			expected: model.Parking{ID: 6, Name: "Monza", Slug: "monza"},
+		},
+		{
+			name:     "pattern id",
+			resolver: ParkResolver{Pattern: idPattern, Filetype: "transactions", Parks: parks},
+			path:     filepath.Join(dir, "monza_6_20201001.csv"),
+			expected: model.Parking{ID: 6, Name: "Monza", Slug: "monza"},
+		},
+		{
+			name:     "manifest over pattern",
+			resolver: ParkResolver{Pattern: pattern, Filetype: "transactions", Parks: parks},
+			path:     filepath.Join(dir, "export.csv"),
+			expected: model.Parking{ID: 7, Name: "Interlagos", Slug: "interlagos"},
+		},
+		{
+			name:     "park",
+			resolver: ParkResolver{Park: "monza", Filetype: "transactions", Parks: parks},
+			path:     filepath.Join(dir, "monza_6_20201001.csv"),
+			expected: model.Parking{ID: 6, Name: "Monza", Slug: "monza"},
+		},
+		{
+			name:     "park id",
+			resolver: ParkResolver{ParkID: 7, Filetype: "transactions", Parks: parks},
+			path:     filepath.Join(dir, "monza_6_20201001.csv"),
+			expected: model.Parking{ID: 7, Name: "Interlagos", Slug: "interlagos"},
+		},
+		{
+			name:          "park id of another park",
+			resolver:      ParkResolver{Park: "monza", ParkID: 7, Filetype: "transactions", Parks: parks},
+			path:          filepath.Join(dir, "monza_6_20201001.csv"),
+			expectedError: true,
+		},
+		{
+			name:          "pattern mismatch",
+			resolver:      ParkResolver{Pattern: pattern, Filetype: "transactions", Parks: parks},
+			path:          filepath.Join(dir, "other.csv"),
+			expectedError: true,
+		},
+		{
+			name:          "broken manifest",
+			resolver:      ParkResolver{Pattern: pattern, Filetype: "transactions", Parks: parks},
+			path:          filepath.Join(dir, "broken.csv"),
+			expectedError: true,
+		},
+		{
+			name:     "stdin ignores the pattern",
+			resolver: ParkResolver{Pattern: pattern, Park: "monza", Filetype: "transactions", Parks: parks},
+			path:     StdinPath,
+			expected: model.Parking{ID: 6, Name: "Monza", Slug: "monza"},
+		},
+		{
+			name:          "no park",
+			resolver:      ParkResolver{Filetype: "transactions", Parks: parks},
+			path:          filepath.Join(dir, "monza_6_20201001.csv"),
+			expectedError: true,
+		},
+		{
+			name:          "unknown park",
+			resolver:      ParkResolver{Park: "centro", Filetype: "transactions", Parks: parks},
+			path:          filepath.Join(dir, "monza_6_20201001.csv"),
+			expectedError: true,
+		},
+		{
+			name:          "id of another park",
+			resolver:      ParkResolver{Pattern: pattern, Filetype: "transactions", Parks: parks},
+			path:          filepath.Join(dir, "interlagos_6_20201001.csv"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := tc.resolver.Resolve(context.Background(), tc.path)
+
+			if tc.expectedError {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, tc.path, file.Path)
+				require.Equal(t, "transactions", file.Filetype)
+				require.Equal(t, tc.expected, file.Parking)
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	dir := tempDir(t, map[string]string{"file.csv": "abc"})
+
+	checksum, err := Checksum(filepath.Join(dir, "file.csv"))
+	require.Nil(t, err)
+	require.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", checksum)
+}
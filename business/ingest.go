@@ -0,0 +1,140 @@
+package business
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Ingester persists the transactions of parks pushed one at a time, as
+// they check out, instead of in files
+type Ingester interface {
+	// Ingest checks and converts a line of parking like the rows of its
+	// files and persists its transaction
+	Ingest(ctx context.Context, parking model.Parking, line *model.Line) (*model.Transaction, error)
+}
+
+// ValidationError is the error of a line that can not be converted
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+type ingesterImpl struct {
+	store         Store
+	parks         Parks
+	bucket        Bucketing
+	pseudonymizer *Pseudonymizer
+	logger        *zap.Logger
+}
+
+// NewIngester returns the ingester of the transactions pushed, whose
+// plates and identities are hashed by pseudonymizer unless nil
+func NewIngester(dbAcess *mongo.DB, bucket Bucketing, pseudonymizer *Pseudonymizer) Ingester {
+	return newIngester(mongoStore{dbAcess: dbAcess}, dbAcess.ParkingCollection, bucket, pseudonymizer)
+}
+
+func newIngester(store Store, parks Parks, bucket Bucketing, pseudonymizer *Pseudonymizer) *ingesterImpl {
+	log, _ := zap.NewProduction()
+
+	return &ingesterImpl{
+		store:         store,
+		parks:         parks,
+		bucket:        bucket,
+		pseudonymizer: pseudonymizer,
+		logger:        log,
+	}
+}
+
+func (s *ingesterImpl) Ingest(ctx context.Context, parking model.Parking, line *model.Line) (*model.Transaction, error) {
+	err := checkIngested(line)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	parking, err = s.resolve(ctx, parking)
+	if err != nil {
+		return nil, err
+	}
+	line.Duration = int64(line.CheckOut.Sub(line.CheckIn).Minutes())
+
+	transaction := newTransaction(line, parking, s.bucket, primitive.NilObjectID)
+	plates := s.pseudonymizer.Plates(transaction.Matricula)
+	s.pseudonymizer.Apply(transaction)
+
+	// events are delivered at least once, an event sent again finds the
+	// transaction of its ticket and plate stored already, while the open
+	// stay of a file is closed like an import would
+	found := &model.Transaction{}
+	if transaction.Sequence != "" {
+		found, err = s.store.GetTransactionByTicket(ctx, transaction.Sequence, parking.ID, plates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case found.ID.IsZero():
+		transaction, err = saveTransaction(ctx, s.store, s.logger, transaction)
+	case found.Status == OPEN:
+		transaction, err = closeStay(ctx, s.store, s.logger, s.bucket, found, transaction)
+	default:
+		s.logger.Sugar().Infow("event already ingested", "transaction", found.ID.Hex())
+		return found, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the transaction is stored already, a failed check is only logged
+	_, err = flagOverlaps(ctx, s.store, transaction, plates)
+	if err != nil {
+		s.logger.Info(err.Error())
+	}
+
+	return transaction, nil
+}
+
+// resolve returns the park of the registry of a pushed park, found like
+// the park of a file. Parks missing from the registry or disagreeing with
+// it are refused
+func (s *ingesterImpl) resolve(ctx context.Context, parking model.Parking) (model.Parking, error) {
+	if parking.ID == 0 && parking.Slug == "" {
+		return parking, &ValidationError{Err: fmt.Errorf("park id or slug is required")}
+	}
+
+	park, err := findParking(ctx, s.parks, parking)
+	if _, unknown := err.(*ErrUnknownPark); unknown {
+		return parking, &ValidationError{Err: err}
+	}
+	if err != nil {
+		return parking, err
+	}
+
+	if parking.ID != 0 && parking.ID != park.ID {
+		return parking, &ValidationError{Err: fmt.Errorf("park [%s] has id [%d], not [%d]", park.Slug, park.ID, parking.ID)}
+	}
+
+	return park.Parking(), nil
+}
+
+// checkIngested checks a pushed line has both dates, in order, and known
+// codes
+func checkIngested(line *model.Line) error {
+	if line.CheckIn.IsZero() || line.CheckOut.IsZero() {
+		return fmt.Errorf("checkin and checkout are required")
+	}
+
+	if line.CheckOut.Before(line.CheckIn) {
+		return fmt.Errorf("checkout [%s] is before checkin [%s]", line.CheckOut.Format(importDateLayout), line.CheckIn.Format(importDateLayout))
+	}
+
+	return checkCodes(line)
+}
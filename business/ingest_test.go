@@ -0,0 +1,155 @@
+package business
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIngester_Ingest(t *testing.T) {
+	checkin := time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC)
+	line := func(table string, checkout time.Time) *model.Line {
+		return &model.Line{
+			Ticket:        "1001",
+			Matricula:     "abc-1234",
+			CheckIn:       checkin,
+			CheckOut:      checkout,
+			PaidValue:     12.5,
+			PaymentMethod: "CRÉDITO",
+			Table:         table,
+		}
+	}
+	monza := model.Parking{ID: 6, Slug: "monza"}
+	parks := memoryParks{{ID: 6, Name: "Monza", Slug: "monza"}}
+
+	type TestRun struct {
+		name          string
+		parking       model.Parking
+		line          *model.Line
+		expected      *model.Transaction
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:    "success",
+			parking: monza,
+			line:    line("NORMAL", checkin.Add(40*time.Minute)),
+			expected: &model.Transaction{
+				CheckinDate:      checkin,
+				CheckoutDate:     checkin.Add(40 * time.Minute),
+				Sequence:         "1001",
+				FareAmount:       12.5,
+				PaidAmount:       12.5,
+				Matricula:        "ABC1234",
+				MatriculaRaw:     "abc-1234",
+				PlateFormat:      "old",
+				IsValid:          true,
+				UseType:          "Avulso",
+				OfferType:        "On-demand",
+				PaymentMethod:    "Creditcard",
+				ParkingInfo:      model.Parking{ID: 6, Slug: "monza", Name: "Monza"},
+				Duration:         40,
+				TimeIntervalHour: []time.Time{time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)},
+				Buckets:          1,
+			},
+		},
+		{
+			name:          "no park",
+			line:          line("NORMAL", checkin.Add(40*time.Minute)),
+			expectedError: true,
+		},
+		{
+			name:          "unknown park",
+			parking:       model.Parking{ID: 7, Slug: "centro"},
+			line:          line("NORMAL", checkin.Add(40*time.Minute)),
+			expectedError: true,
+		},
+		{
+			name:          "unknown park id",
+			parking:       model.Parking{ID: 7},
+			line:          line("NORMAL", checkin.Add(40*time.Minute)),
+			expectedError: true,
+		},
+		{
+			name:          "park id of another park",
+			parking:       model.Parking{ID: 7, Slug: "monza"},
+			line:          line("NORMAL", checkin.Add(40*time.Minute)),
+			expectedError: true,
+		},
+		{
+			name:          "no checkout",
+			parking:       monza,
+			line:          line("NORMAL", time.Time{}),
+			expectedError: true,
+		},
+		{
+			name:          "checkout before checkin",
+			parking:       monza,
+			line:          line("NORMAL", checkin.Add(-time.Minute)),
+			expectedError: true,
+		},
+		{
+			name:          "unknown table",
+			parking:       monza,
+			line:          line("VIP", checkin.Add(40*time.Minute)),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memoryStore{}
+			result, err := newIngester(store, parks, HoursTouched{}, nil).Ingest(context.Background(), tc.parking, tc.line)
+
+			if tc.expectedError {
+				_, invalid := err.(*ValidationError)
+				require.True(t, invalid, "%v", err)
+				require.Equal(t, 0, len(store.transactions))
+				return
+			}
+
+			require.Nil(t, err)
+			tc.expected.ID = result.ID
+			require.Equal(t, tc.expected, result)
+			require.Equal(t, []*model.Transaction{tc.expected}, store.transactions)
+		})
+	}
+}
+
+func TestIngester_IngestAgain(t *testing.T) {
+	checkin := time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC)
+	line := func() *model.Line {
+		return &model.Line{
+			Ticket: "1001", Matricula: "abc-1234", CheckIn: checkin, CheckOut: checkin.Add(40 * time.Minute),
+			PaidValue: 12.5, PaymentMethod: "CRÉDITO", Table: "NORMAL",
+		}
+	}
+	monza := model.Parking{ID: 6, Name: "Monza", Slug: "monza"}
+	open := &model.Transaction{ID: primitive.NewObjectID(), ParkingInfo: monza, Sequence: "1002", Matricula: "ABC1234", CheckinDate: checkin.Add(2 * time.Hour), Status: OPEN}
+	store := &memoryStore{transactions: []*model.Transaction{open}}
+	ingester := newIngester(store, memoryParks{{ID: 6, Name: "Monza", Slug: "monza"}}, HoursTouched{}, nil)
+
+	first, err := ingester.Ingest(context.Background(), monza, line())
+	require.Nil(t, err)
+
+	// the event is retried after its acknowledgement was lost
+	again, err := ingester.Ingest(context.Background(), monza, line())
+	require.Nil(t, err)
+	require.Equal(t, first.ID, again.ID)
+	require.Equal(t, 2, len(store.transactions))
+
+	// the checkout of a stay imported open closes it
+	closing := line()
+	closing.Ticket = "1002"
+	closing.CheckIn, closing.CheckOut = checkin.Add(2*time.Hour), checkin.Add(3*time.Hour)
+	closed, err := ingester.Ingest(context.Background(), monza, closing)
+	require.Nil(t, err)
+	require.Equal(t, open.ID, closed.ID)
+	require.Equal(t, VALID, store.transactions[0].Status)
+	require.Equal(t, 2, len(store.transactions))
+}
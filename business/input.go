@@ -0,0 +1,432 @@
+package business
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/klauspost/compress/zstd"
+)
+
+// StdinPath is the path that reads a file from the standard input
+const StdinPath = "-"
+
+// inputPatterns are the files of a directory that are imported. Fixed-width
+// .txt reports are only read when named, directories hold notes too
+var inputPatterns = []string{"*.csv", "*.csv.gz", "*.csv.zst", "*.tsv", "*.jsonl", "*.zip", "*.xlsx"}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte{0x50, 0x4b, 0x03, 0x04}
+)
+
+// Input is something rows are read from: a plain file, a compressed file,
+// a member of a zip archive, a spreadsheet or the standard input
+type Input struct {
+	// Path names the input in the ledger, zip members are named after the
+	// archive and the member joined by a colon
+	Path string
+	// Stream is set for inputs that can only be read once, their checksum
+	// is only known after reading them
+	Stream bool
+	// format is how the rows are written, from the file extension
+	format string
+
+	open     func() (io.ReadCloser, error)
+	checksum func() (string, error)
+}
+
+// Open returns the uncompressed content of the input, plain files are
+// returned as *os.File so they can be seeked
+func (i Input) Open() (io.ReadCloser, error) {
+	return i.open()
+}
+
+// Checksum returns the hex SHA-256 of the input as stored, that is of the
+// compressed file or of the zip member, a stream must be read first
+func (i Input) Checksum() (string, error) {
+	return i.checksum()
+}
+
+// Inputs returns the inputs of path, which is the standard input when
+// StdinPath, a CSV file, a gzip or zstd compressed CSV file, a zip
+// archive whose CSV members are read in turn or an Excel spreadsheet
+func Inputs(path string, stdin io.Reader) ([]Input, error) {
+	if path == StdinPath {
+		return []Input{stdinInput(stdin)}, nil
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return zipInputs(path)
+	}
+
+	input := Input{
+		Path:   path,
+		format: formatOf(path),
+		open: func() (io.ReadCloser, error) {
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("error opening file [%s]: [%s]", path, err.Error())
+			}
+
+			return decompress(path, file, compression(path))
+		},
+		checksum: func() (string, error) {
+			return Checksum(path)
+		},
+	}
+
+	return []Input{input}, nil
+}
+
+// stdinInput reads stdin once, hashing it as it goes and finding out its
+// compression from its first bytes
+func stdinInput(stdin io.Reader) Input {
+	hash := sha256.New()
+
+	return Input{
+		Path:   StdinPath,
+		Stream: true,
+		format: FormatCSV,
+		open: func() (io.ReadCloser, error) {
+			buffered := bufio.NewReader(io.TeeReader(stdin, hash))
+			magic, _ := buffered.Peek(len(zstdMagic))
+
+			switch {
+			case bytes.HasPrefix(magic, gzipMagic):
+				return decompress(StdinPath, ioutil.NopCloser(buffered), ".gz")
+			case bytes.HasPrefix(magic, zstdMagic):
+				return decompress(StdinPath, ioutil.NopCloser(buffered), ".zst")
+			case bytes.HasPrefix(magic, zipMagic):
+				return nil, fmt.Errorf("zip archives and spreadsheets can not be read from the standard input")
+			}
+
+			return ioutil.NopCloser(buffered), nil
+		},
+		checksum: func() (string, error) {
+			return hex.EncodeToString(hash.Sum(nil)), nil
+		},
+	}
+}
+
+// zipInputs returns an input per CSV member of the archive at path, in
+// name order
+func zipInputs(path string) ([]Input, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive [%s]: [%s]", path, err.Error())
+	}
+	defer archive.Close()
+
+	names := []string{}
+	for _, member := range archive.File {
+		if !member.FileInfo().IsDir() && strings.EqualFold(filepath.Ext(member.Name), ".csv") {
+			names = append(names, member.Name)
+		}
+	}
+	sort.Strings(names)
+
+	inputs := []Input{}
+	for _, name := range names {
+		name := name
+		open := func() (io.ReadCloser, error) {
+			return openZipMember(path, name)
+		}
+
+		inputs = append(inputs, Input{
+			Path:   path + ":" + name,
+			format: FormatCSV,
+			open:   open,
+			checksum: func() (string, error) {
+				member, err := open()
+				if err != nil {
+					return "", err
+				}
+				defer member.Close()
+
+				return checksum(path+":"+name, member)
+			},
+		})
+	}
+
+	return inputs, nil
+}
+
+func openZipMember(path string, name string) (io.ReadCloser, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive [%s]: [%s]", path, err.Error())
+	}
+
+	for _, member := range archive.File {
+		if member.Name != name {
+			continue
+		}
+
+		content, err := member.Open()
+		if err != nil {
+			archive.Close()
+			return nil, fmt.Errorf("error opening [%s] of archive [%s]: [%s]", name, path, err.Error())
+		}
+
+		return &layeredReader{Reader: content, closers: []io.Closer{content, archive}}, nil
+	}
+
+	archive.Close()
+	return nil, fmt.Errorf("archive [%s] has no member [%s]", path, name)
+}
+
+// compression returns the extension of the compression of path, empty
+// when it is not compressed
+func compression(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" || ext == ".zst" {
+		return ext
+	}
+
+	return ""
+}
+
+// decompress wraps r in the decompressor of ext, closing it closes r too
+func decompress(path string, r io.ReadCloser, ext string) (io.ReadCloser, error) {
+	switch ext {
+	case ".gz":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("error decompressing [%s]: [%s]", path, err.Error())
+		}
+
+		return &layeredReader{Reader: gz, closers: []io.Closer{gz, r}}, nil
+	case ".zst":
+		zst, err := zstd.NewReader(r)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("error decompressing [%s]: [%s]", path, err.Error())
+		}
+
+		return &layeredReader{Reader: zst, closers: []io.Closer{zst.IOReadCloser(), r}}, nil
+	}
+
+	return r, nil
+}
+
+// layeredReader reads from the outermost layer of a stack of readers and
+// closes all of them
+type layeredReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (l *layeredReader) Close() error {
+	var first error
+	for _, closer := range l.closers {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+const (
+	FormatCSV   = "csv"
+	FormatTSV   = "tsv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+	FormatFixed = "fixed"
+)
+
+// formatOf returns the format of the file at path from its extension,
+// under the compression one, CSV when unknown
+func formatOf(path string) string {
+	if compression(path) != "" {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".tab":
+		return FormatTSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".xlsx":
+		return FormatXLSX
+	case ".txt":
+		return FormatFixed
+	}
+
+	return FormatCSV
+}
+
+// openRows opens the source of the rows of the input after the checkpoint.
+// The input is read in the format and dialect given, converted from its
+// encoding to UTF-8 and its byte order mark dropped. Plain UTF-8 files are
+// seeked to the checkpoint offset, anything else is read again skipping
+// the rows up to the checkpoint line
+func openRows(input Input, start model.Checkpoint, dialect config.Dialect) (RowSource, io.Closer, error) {
+	format := input.format
+	if dialect.Format != "" {
+		format = dialect.Format
+	}
+
+	if format == FormatXLSX {
+		return openSheet(input, start, dialect)
+	}
+
+	in, err := input.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buffered := bufio.NewReaderSize(in, encodingSample)
+	sample, _ := buffered.Peek(encodingSample)
+
+	encoding, err := resolveEncoding(dialect.Encoding, sample)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+
+	bom := int64(0)
+	if hasBOM(sample) {
+		bom = int64(len(utf8BOM))
+	}
+
+	if dialect.Sniff && format == FormatCSV {
+		sniffed := SniffDialect(sample[bom:min64(int64(len(sample)), sniffSample)])
+		dialect.Delimiter, dialect.Comment = sniffed.Delimiter, sniffed.Comment
+		dialect.Fields, dialect.HeaderRows = sniffed.Fields, sniffed.HeaderRows
+	}
+
+	file, seekable := in.(*os.File)
+	if seekable && encoding == EncodingUTF8 {
+		position := start.Offset
+		if position <= bom {
+			position = bom
+		} else {
+			// the header is behind the checkpoint
+			dialect.HeaderRows = 0
+		}
+
+		_, err = file.Seek(position, io.SeekStart)
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("error seeking file [%s]: [%s]", input.Path, err.Error())
+		}
+
+		source, err := newTextSource(format, file, position, dialect)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		return source, file, nil
+	}
+
+	buffered.Discard(int(bom))
+	source, err := newTextSource(format, decode(buffered, encoding), bom, dialect)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+
+	err = skipRows(source, start.Line)
+	if err != nil {
+		in.Close()
+		return nil, nil, fmt.Errorf("error skipping to line [%d] of [%s]: [%s]", start.Line, input.Path, err.Error())
+	}
+
+	return source, in, nil
+}
+
+// newTextSource returns the source of the rows of the text file in format
+// read from r, which starts base bytes into the file
+func newTextSource(format string, r io.Reader, base int64, dialect config.Dialect) (RowSource, error) {
+	var lines *lineSource
+	switch format {
+	case FormatCSV:
+		return newCSVSource(r, base, dialect)
+	case FormatTSV:
+		lines = newLineSource(r, base, parseTSV)
+	case FormatJSONL:
+		lines = newLineSource(r, base, func(line []byte) ([]string, error) {
+			return parseJSONL(line, transactionColumns)
+		})
+	case FormatFixed:
+		var err error
+		lines, err = newFixedWidthSource(r, base, dialect.Columns)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("format [%s] does not exists", format)
+	}
+
+	return newFrameSource(lines, dialect.HeaderRows, dialect.FooterRows, dialect.Fields), nil
+}
+
+// skipRows reads past rows rows of source. Rows that could not be read
+// are counted by the checkpoint line too, only fatal errors stop it
+func skipRows(source RowSource, rows int64) error {
+	for i := int64(0); i < rows; i++ {
+		_, err := source.Next()
+		if err != nil && !isRowError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openSheet opens the sheet of the dialect of a spreadsheet after the
+// rows up to the checkpoint line
+func openSheet(input Input, start model.Checkpoint, dialect config.Dialect) (RowSource, io.Closer, error) {
+	source, err := newSheetSource(input.Path, dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = skipRows(source, start.Line)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error skipping to line [%d] of [%s]: [%s]", start.Line, input.Path, err.Error())
+	}
+
+	return source, ioutil.NopCloser(nil), nil
+}
+
+// Rows opens the source of the rows of the input in dialect
+func (i Input) Rows(dialect config.Dialect) (RowSource, io.Closer, error) {
+	return openRows(i, model.Checkpoint{}, dialect)
+}
+
+func min64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// checksum returns the hex SHA-256 of what is read from r
+func checksum(path string, r io.Reader) (string, error) {
+	hash := sha256.New()
+	_, err := io.Copy(hash, r)
+	if err != nil {
+		return "", fmt.Errorf("error reading file [%s]: [%s]", path, err.Error())
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,224 @@
+package business
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+const inputContent = "a,1\nb,2\nc,3\n"
+
+func gzipped(t *testing.T, content string) string {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	writer.Write([]byte(content))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func zstded(t *testing.T, content string) string {
+	buf := &bytes.Buffer{}
+	writer, err := zstd.NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write([]byte(content))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func zipped(t *testing.T, members map[string]string) string {
+	buf := &bytes.Buffer{}
+	writer := zip.NewWriter(buf)
+	for name, content := range members {
+		member, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		member.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestInputs(t *testing.T) {
+	dir := tempDir(t, map[string]string{
+		"plain.csv":      inputContent,
+		"file.csv.gz":    gzipped(t, inputContent),
+		"file.csv.zst":   zstded(t, inputContent),
+		"archive.zip":    zipped(t, map[string]string{"b.csv": "b,2\n", "a.csv": inputContent, "notes.txt": "notes"}),
+		"corrupt.csv.gz": "not gzip",
+	})
+
+	type TestRun struct {
+		name          string
+		path          string
+		stdin         string
+		expected      map[string]string
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:     "plain",
+			path:     filepath.Join(dir, "plain.csv"),
+			expected: map[string]string{filepath.Join(dir, "plain.csv"): inputContent},
+		},
+		{
+			name:     "gzip",
+			path:     filepath.Join(dir, "file.csv.gz"),
+			expected: map[string]string{filepath.Join(dir, "file.csv.gz"): inputContent},
+		},
+		{
+			name:     "zstd",
+			path:     filepath.Join(dir, "file.csv.zst"),
+			expected: map[string]string{filepath.Join(dir, "file.csv.zst"): inputContent},
+		},
+		{
+			name: "zip members",
+			path: filepath.Join(dir, "archive.zip"),
+			expected: map[string]string{
+				filepath.Join(dir, "archive.zip") + ":a.csv": inputContent,
+				filepath.Join(dir, "archive.zip") + ":b.csv": "b,2\n",
+			},
+		},
+		{
+			name:          "corrupt",
+			path:          filepath.Join(dir, "corrupt.csv.gz"),
+			expectedError: true,
+		},
+		{
+			name:     "stdin",
+			path:     StdinPath,
+			stdin:    inputContent,
+			expected: map[string]string{StdinPath: inputContent},
+		},
+		{
+			name:     "stdin gzip",
+			path:     StdinPath,
+			stdin:    gzipped(t, inputContent),
+			expected: map[string]string{StdinPath: inputContent},
+		},
+		{
+			name:     "stdin zstd",
+			path:     StdinPath,
+			stdin:    zstded(t, inputContent),
+			expected: map[string]string{StdinPath: inputContent},
+		},
+		{
+			name:          "stdin zip",
+			path:          StdinPath,
+			stdin:         zipped(t, map[string]string{"a.csv": inputContent}),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := Inputs(tc.path, bytes.NewBufferString(tc.stdin))
+			require.Nil(t, err)
+
+			result := map[string]string{}
+			for _, input := range inputs {
+				in, err := input.Open()
+				if tc.expectedError {
+					require.NotNil(t, err)
+					return
+				}
+				require.Nil(t, err)
+
+				content, err := ioutil.ReadAll(in)
+				require.Nil(t, err)
+				require.Nil(t, in.Close())
+				result[input.Path] = string(content)
+
+				// streams are hashed as read, as stored
+				if input.Stream {
+					checksum, err := input.Checksum()
+					require.Nil(t, err)
+					sum := sha256.Sum256([]byte(tc.stdin))
+					require.Equal(t, hex.EncodeToString(sum[:]), checksum)
+				}
+			}
+
+			require.False(t, tc.expectedError)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestOpenRows(t *testing.T) {
+	dir := tempDir(t, map[string]string{
+		"plain.csv":   inputContent,
+		"file.csv.gz": gzipped(t, inputContent),
+		"bad.csv.gz":  gzipped(t, "a,1\nb,2,x\nc,3\n"),
+	})
+
+	type TestRun struct {
+		name     string
+		path     string
+		start    model.Checkpoint
+		expected []string
+	}
+
+	tt := []TestRun{
+		{
+			name:     "plain from the start",
+			path:     filepath.Join(dir, "plain.csv"),
+			expected: []string{"a", "1"},
+		},
+		{
+			name:     "plain seeks to the offset",
+			path:     filepath.Join(dir, "plain.csv"),
+			start:    model.Checkpoint{Line: 2, Offset: 8},
+			expected: []string{"c", "3"},
+		},
+		{
+			name:     "compressed skips to the line",
+			path:     filepath.Join(dir, "file.csv.gz"),
+			start:    model.Checkpoint{Line: 2, Offset: 8},
+			expected: []string{"c", "3"},
+		},
+		{
+			name:     "compressed skips past rejected rows",
+			path:     filepath.Join(dir, "bad.csv.gz"),
+			start:    model.Checkpoint{Line: 2, Offset: 10},
+			expected: []string{"c", "3"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := Inputs(tc.path, nil)
+			require.Nil(t, err)
+
+			source, in, err := openRows(inputs[0], tc.start, config.Dialect{})
+			require.Nil(t, err)
+			defer in.Close()
+
+			row, err := source.Next()
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, row.Fields)
+			require.Equal(t, tc.start.Offset+4, row.Offset)
+		})
+	}
+}
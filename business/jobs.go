@@ -0,0 +1,428 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/csv-processor/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// JobQueued is a job waiting for a worker
+	JobQueued = "queued"
+	// JobRunning is a job whose file is being imported
+	JobRunning = "running"
+	// JobDone is a job whose file was imported, or refused as already imported
+	JobDone = "done"
+	// JobFailed is a job stopped by an error
+	JobFailed = "failed"
+	// JobCanceled is a job canceled before it finished
+	JobCanceled = "canceled"
+)
+
+const (
+	// rejectsName is the name of the rejects file in the directory of a job
+	rejectsName = "rejects.csv"
+	// evictEvery is how often finished jobs are checked for eviction
+	evictEvery = time.Minute
+)
+
+var (
+	// ErrQueueFull is returned when a job is submitted to a full queue
+	ErrQueueFull = fmt.Errorf("job queue is full")
+	// ErrJobNotFound is returned for the ID of a job the queue does not have
+	ErrJobNotFound = fmt.Errorf("job not found")
+	// ErrJobFinished is returned when canceling a job that already finished
+	ErrJobFinished = fmt.Errorf("job already finished")
+)
+
+// Job is the import of an uploaded file
+type Job struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Filetype string        `json:"filetype"`
+	Parking  model.Parking `json:"parking"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	// Imports are the ledger records of the inputs of the file, one per
+	// member of zip archives
+	Imports []*model.Import `json:"imports"`
+	// Refused are the earlier imports of the content of inputs that were
+	// not imported again
+	Refused []*model.Import `json:"refused,omitempty"`
+	// Summary adds up the summaries of the imports
+	Summary    model.ImportSummary `json:"summary"`
+	CreatedAt  time.Time           `json:"created_at"`
+	StartedAt  *time.Time          `json:"started_at,omitempty"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+
+	file   ImportFile
+	dir    string
+	cancel context.CancelFunc
+}
+
+// finished returns whether the job will not change anymore
+func (j *Job) finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+// JobQueue imports uploaded files in the background, a bounded number of
+// them waiting at a time
+type JobQueue interface {
+	// Submit saves the content of the file named name and queues its import
+	Submit(name string, content io.Reader, file ImportFile) (*Job, error)
+	Get(id string) (*Job, error)
+	// Cancel stops a queued or running job
+	Cancel(id string) (*Job, error)
+	// Rejects opens the rows rejected by the import of a job
+	Rejects(id string) (io.ReadCloser, error)
+	// Run runs the jobs until ctx is canceled, which cancels the running ones
+	Run(ctx context.Context)
+}
+
+type jobQueueImpl struct {
+	importer  Importer
+	dir       string
+	workers   int
+	retention time.Duration
+	queue     chan *Job
+	logger    *zap.Logger
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewJobQueue returns a queue of at most size jobs waiting for one of
+// workers, the files uploaded are kept in dir. Finished jobs and their
+// directories are dropped retention after they finished, a retention of 0
+// keeps them
+func NewJobQueue(importer Importer, dir string, size int, workers int, retention time.Duration) JobQueue {
+	log, _ := zap.NewProduction()
+
+	return &jobQueueImpl{
+		importer:  importer,
+		dir:       dir,
+		workers:   workers,
+		retention: retention,
+		queue:     make(chan *Job, size),
+		logger:    log,
+		jobs:      make(map[string]*Job),
+	}
+}
+
+func (q *jobQueueImpl) Submit(name string, content io.Reader, file ImportFile) (*Job, error) {
+	name = filepath.Base(name)
+	if name == "." || name == string(filepath.Separator) {
+		return nil, fmt.Errorf("file name [%s] is not valid", name)
+	}
+
+	if len(q.queue) == cap(q.queue) {
+		return nil, ErrQueueFull
+	}
+
+	job := &Job{
+		ID:        primitive.NewObjectID().Hex(),
+		Name:      name,
+		Filetype:  file.Filetype,
+		Parking:   file.Parking,
+		Status:    JobQueued,
+		Imports:   []*model.Import{},
+		CreatedAt: time.Now(),
+	}
+	job.dir = filepath.Join(q.dir, job.ID)
+
+	err := os.MkdirAll(job.dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating job directory [%s]: [%s]", job.dir, err.Error())
+	}
+
+	file.Path = filepath.Join(job.dir, name)
+	err = saveUpload(file.Path, content)
+	if err != nil {
+		os.RemoveAll(job.dir)
+		return nil, err
+	}
+	job.file = file
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case q.queue <- job:
+	default:
+		os.RemoveAll(job.dir)
+		return nil, ErrQueueFull
+	}
+	q.jobs[job.ID] = job
+
+	return job.copy(), nil
+}
+
+func saveUpload(path string, content io.Reader) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file [%s]: [%s]", path, err.Error())
+	}
+
+	_, err = io.Copy(out, content)
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("error saving file [%s]: [%s]", path, err.Error())
+	}
+
+	return out.Close()
+}
+
+func (q *jobQueueImpl) Get(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	return job.copy(), nil
+}
+
+func (q *jobQueueImpl) Cancel(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	if job.finished() {
+		return job.copy(), ErrJobFinished
+	}
+
+	// running jobs finish as canceled once their import stops
+	if job.cancel != nil {
+		job.cancel()
+		return job.copy(), nil
+	}
+
+	os.Remove(job.file.Path)
+	q.finish(job, JobCanceled, "")
+
+	return job.copy(), nil
+}
+
+func (q *jobQueueImpl) Rejects(id string) (io.ReadCloser, error) {
+	job, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(q.dir, job.ID, rejectsName))
+	if os.IsNotExist(err) {
+		// the job has not read any row yet
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening rejects of job [%s]: [%s]", id, err.Error())
+	}
+
+	return file, nil
+}
+
+func (q *jobQueueImpl) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	if q.retention > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.evictExpired(ctx, evictEvery)
+		}()
+	}
+
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case job := <-q.queue:
+					q.run(ctx, job)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// evictExpired evicts the expired jobs every interval until ctx is
+// canceled, starting with the directories left by earlier runs
+func (q *jobQueueImpl) evictExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		q.evict(time.Now().Add(-q.retention))
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// evict drops the jobs finished before and their directories, along with
+// the directories of jobs the queue does not know, from earlier runs,
+// last modified before
+func (q *jobQueueImpl) evict(before time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id, job := range q.jobs {
+		if !job.finished() || !job.FinishedAt.Before(before) {
+			continue
+		}
+
+		err := os.RemoveAll(job.dir)
+		if err != nil {
+			q.logger.Sugar().Errorw("error removing job directory", "job", id, "error", err.Error())
+			continue
+		}
+		delete(q.jobs, id)
+	}
+
+	entries, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			q.logger.Sugar().Errorw("error listing job directories", "dir", q.dir, "error", err.Error())
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if _, ok := q.jobs[entry.Name()]; ok || !entry.IsDir() || !entry.ModTime().Before(before) {
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			q.logger.Sugar().Errorw("error removing job directory", "job", entry.Name(), "error", err.Error())
+		}
+	}
+}
+
+// run imports every input of the file of job
+func (q *jobQueueImpl) run(ctx context.Context, job *Job) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	q.mu.Lock()
+	if job.finished() {
+		q.mu.Unlock()
+		return
+	}
+	started := time.Now()
+	job.StartedAt = &started
+	job.Status = JobRunning
+	job.cancel = cancel
+	q.mu.Unlock()
+
+	// the upload is not needed once imported, the rejects are kept
+	defer os.Remove(job.file.Path)
+
+	err := q.importInputs(ctx, job)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case ctx.Err() != nil:
+		q.finish(job, JobCanceled, "")
+	case err != nil:
+		q.finish(job, JobFailed, err.Error())
+	default:
+		q.finish(job, JobDone, "")
+	}
+}
+
+func (q *jobQueueImpl) importInputs(ctx context.Context, job *Job) error {
+	rejects, err := os.Create(filepath.Join(job.dir, rejectsName))
+	if err != nil {
+		return fmt.Errorf("error creating rejects of job [%s]: [%s]", job.ID, err.Error())
+	}
+	defer rejects.Close()
+
+	file := job.file
+	file.Rejects = NewRejectsWriter(rejects)
+
+	inputs, err := file.Inputs(nil)
+	if err != nil {
+		return err
+	}
+
+	for _, input := range inputs {
+		record, imported, err := q.importer.Import(ctx, input)
+
+		q.mu.Lock()
+		if record != nil && imported {
+			job.Imports = append(job.Imports, record)
+			job.Summary = addSummaries(job.Summary, record.Summary)
+		} else if record != nil {
+			job.Refused = append(job.Refused, record)
+		}
+		q.mu.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("error importing [%s]: [%s]", input.Path, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// finish sets the final status of job, with the lock held
+func (q *jobQueueImpl) finish(job *Job, status string, reason string) {
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Status = status
+	job.Error = reason
+	job.cancel = nil
+
+	q.logger.Sugar().Infow("job finished", "job", job.ID, "name", job.Name, "status", status, "error", reason)
+}
+
+// copy returns a copy of the job safe to read without the lock
+func (j *Job) copy() *Job {
+	c := *j
+	c.Imports = append([]*model.Import{}, j.Imports...)
+	if j.Refused != nil {
+		c.Refused = append([]*model.Import{}, j.Refused...)
+	}
+
+	return &c
+}
+
+func addSummaries(a model.ImportSummary, b model.ImportSummary) model.ImportSummary {
+	return model.ImportSummary{
+		Rows:     a.Rows + b.Rows,
+		Inserted: a.Inserted + b.Inserted,
+		Skipped:  a.Skipped + b.Skipped,
+		Failed:   a.Failed + b.Failed,
+		Rejected: a.Rejected + b.Rejected,
+		Open:     a.Open + b.Open,
+		Closed:   a.Closed + b.Closed,
+
+		Overlapping: a.Overlapping + b.Overlapping,
+	}
+}
@@ -0,0 +1,187 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// importerFunc is an Importer running a function
+type importerFunc func(ctx context.Context, file ImportFile) (*model.Import, bool, error)
+
+func (f importerFunc) Import(ctx context.Context, file ImportFile) (*model.Import, bool, error) {
+	return f(ctx, file)
+}
+
+func waitJob(t *testing.T, queue JobQueue, id string) *Job {
+	for i := 0; i < 200; i++ {
+		job, err := queue.Get(id)
+		require.Nil(t, err)
+		if job.finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("job [%s] did not finish", id)
+	return nil
+}
+
+func TestJobQueue_Run(t *testing.T) {
+	type TestRun struct {
+		name            string
+		importer        importerFunc
+		expectedStatus  string
+		expectedSummary model.ImportSummary
+		expectedRejects string
+		expectedRefused int
+	}
+
+	tt := []TestRun{
+		{
+			name: "imported with rejects",
+			importer: func(ctx context.Context, file ImportFile) (*model.Import, bool, error) {
+				content, err := ioutil.ReadFile(file.Path)
+				if err != nil {
+					return nil, false, err
+				}
+				file.Rejects.Write(Row{Line: 2, Raw: content}, fmt.Errorf("unknown table [X]"))
+
+				return &model.Import{ID: primitive.NewObjectID(), Summary: model.ImportSummary{Rows: 2, Inserted: 1, Rejected: 1}}, true, nil
+			},
+			expectedStatus:  JobDone,
+			expectedSummary: model.ImportSummary{Rows: 2, Inserted: 1, Rejected: 1},
+			expectedRejects: "line,error,row\n2,unknown table [X],\"a,b\"\n",
+		},
+		{
+			name: "already imported",
+			importer: func(ctx context.Context, file ImportFile) (*model.Import, bool, error) {
+				return &model.Import{ID: primitive.NewObjectID()}, false, nil
+			},
+			expectedStatus:  JobDone,
+			expectedRefused: 1,
+		},
+		{
+			name: "failed",
+			importer: func(ctx context.Context, file ImportFile) (*model.Import, bool, error) {
+				return nil, false, fmt.Errorf("database is down")
+			},
+			expectedStatus: JobFailed,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			queue := NewJobQueue(tc.importer, tempDir(t, nil), 1, 1, 0)
+			go queue.Run(ctx)
+
+			job, err := queue.Submit("../monza.csv", bytes.NewBufferString("a,b"), ImportFile{Filetype: "transactions"})
+			require.Nil(t, err)
+			require.Equal(t, "monza.csv", job.Name)
+
+			job = waitJob(t, queue, job.ID)
+			require.Equal(t, tc.expectedStatus, job.Status)
+			require.Equal(t, tc.expectedSummary, job.Summary)
+			require.Equal(t, tc.expectedRefused, len(job.Refused))
+
+			rejects, err := queue.Rejects(job.ID)
+			require.Nil(t, err)
+			defer rejects.Close()
+
+			content, err := ioutil.ReadAll(rejects)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedRejects, string(content))
+		})
+	}
+}
+
+func TestJobQueue_Cancel(t *testing.T) {
+	started := make(chan bool)
+	importer := importerFunc(func(ctx context.Context, file ImportFile) (*model.Import, bool, error) {
+		started <- true
+		<-ctx.Done()
+		return &model.Import{ID: primitive.NewObjectID(), Status: model.ImportFailed}, true, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queue := NewJobQueue(importer, tempDir(t, nil), 1, 1, 0)
+	go queue.Run(ctx)
+
+	running, err := queue.Submit("monza.csv", bytes.NewBufferString("a,b"), ImportFile{})
+	require.Nil(t, err)
+	<-started
+
+	queued, err := queue.Submit("monza.csv", bytes.NewBufferString("a,b"), ImportFile{})
+	require.Nil(t, err)
+
+	// the worker is busy and the queue holds one job
+	_, err = queue.Submit("monza.csv", bytes.NewBufferString("a,b"), ImportFile{})
+	require.Equal(t, ErrQueueFull, err)
+
+	job, err := queue.Cancel(queued.ID)
+	require.Nil(t, err)
+	require.Equal(t, JobCanceled, job.Status)
+
+	_, err = queue.Cancel(running.ID)
+	require.Nil(t, err)
+	job = waitJob(t, queue, running.ID)
+	require.Equal(t, JobCanceled, job.Status)
+	require.Equal(t, 1, len(job.Imports))
+
+	_, err = queue.Cancel(running.ID)
+	require.Equal(t, ErrJobFinished, err)
+
+	_, err = queue.Get("unknown")
+	require.Equal(t, ErrJobNotFound, err)
+}
+
+func TestJobQueue_Evict(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	importer := importerFunc(func(ctx context.Context, file ImportFile) (*model.Import, bool, error) {
+		return &model.Import{ID: primitive.NewObjectID()}, true, nil
+	})
+	dir := tempDir(t, nil)
+	queue := NewJobQueue(importer, dir, 1, 1, time.Hour).(*jobQueueImpl)
+	go queue.Run(ctx)
+
+	// the directory of a job of an earlier run
+	stale := filepath.Join(dir, primitive.NewObjectID().Hex())
+	require.Nil(t, os.Mkdir(stale, 0755))
+	old := time.Now().Add(-2 * time.Hour)
+	require.Nil(t, os.Chtimes(stale, old, old))
+
+	job, err := queue.Submit("monza.csv", bytes.NewBufferString("a,b"), ImportFile{})
+	require.Nil(t, err)
+	job = waitJob(t, queue, job.ID)
+
+	// the job finished after the cutoff is kept
+	queue.evict(job.FinishedAt.Add(-time.Second))
+	_, err = queue.Get(job.ID)
+	require.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, job.ID))
+	require.Nil(t, err)
+	_, err = os.Stat(stale)
+	require.True(t, os.IsNotExist(err), "%v", err)
+
+	queue.evict(job.FinishedAt.Add(time.Second))
+	_, err = queue.Get(job.ID)
+	require.Equal(t, ErrJobNotFound, err)
+	_, err = os.Stat(filepath.Join(dir, job.ID))
+	require.True(t, os.IsNotExist(err), "%v", err)
+}
@@ -0,0 +1,125 @@
+package business
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// lineSource is the source of files with a row per line, parsed by parse.
+// Blank lines are skipped
+type lineSource struct {
+	reader *bufio.Reader
+	offset int64
+	line   int64
+	parse  func(line []byte) ([]string, error)
+}
+
+func newLineSource(r io.Reader, base int64, parse func(line []byte) ([]string, error)) *lineSource {
+	return &lineSource{
+		reader: bufio.NewReader(r),
+		offset: base,
+		parse:  parse,
+	}
+}
+
+func (s *lineSource) Next() (Row, error) {
+	for {
+		raw, err := s.reader.ReadBytes('\n')
+		if len(raw) == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return Row{}, err
+		}
+
+		s.offset += int64(len(raw))
+		s.line++
+
+		text := bytes.TrimRight(raw, "\r\n")
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+
+		row := Row{Line: s.line, Raw: text, Offset: s.offset}
+		row.Fields, err = s.parse(text)
+		if err != nil {
+			err = &RowError{Line: s.line, Err: err}
+		}
+
+		return row, err
+	}
+}
+
+// NewTSVSource returns the source of the rows of a tab separated file,
+// whose fields are neither quoted nor escaped
+func NewTSVSource(r io.Reader) RowSource {
+	return newLineSource(r, 0, parseTSV)
+}
+
+func parseTSV(line []byte) ([]string, error) {
+	return strings.Split(string(line), "\t"), nil
+}
+
+// NewJSONLSource returns the source of the rows of a JSON Lines file. A
+// line is either an array of the fields or an object whose fields are
+// taken in the order of columns
+func NewJSONLSource(r io.Reader, columns []string) RowSource {
+	return newLineSource(r, 0, func(line []byte) ([]string, error) {
+		return parseJSONL(line, columns)
+	})
+}
+
+func parseJSONL(line []byte, columns []string) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := value.(type) {
+	case []interface{}:
+		fields := make([]string, len(value))
+		for i, field := range value {
+			fields[i], err = jsonField(field)
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %s", i+1, err.Error())
+			}
+		}
+
+		return fields, nil
+	case map[string]interface{}:
+		fields := make([]string, len(columns))
+		for i, column := range columns {
+			fields[i], err = jsonField(value[column])
+			if err != nil {
+				return nil, fmt.Errorf("field [%s]: %s", column, err.Error())
+			}
+		}
+
+		return fields, nil
+	}
+
+	return nil, fmt.Errorf("expected an array or an object")
+}
+
+func jsonField(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return fmt.Sprintf("%t", value), nil
+	}
+
+	return "", fmt.Errorf("nested values are not fields")
+}
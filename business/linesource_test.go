@@ -0,0 +1,68 @@
+package business
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readRows(t *testing.T, source RowSource) ([]Row, []error) {
+	rows, errs := []Row{}, []error{}
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			return rows, errs
+		}
+		rows = append(rows, row)
+		errs = append(errs, err)
+	}
+}
+
+func TestLineSources(t *testing.T) {
+	type TestRun struct {
+		name           string
+		source         RowSource
+		expected       []Row
+		expectedErrors []bool
+	}
+
+	tt := []TestRun{
+		{
+			name:   "tsv",
+			source: NewTSVSource(bytes.NewBufferString("a\t1\t\n\nb\t\"2\"\t x\r\n")),
+			expected: []Row{
+				{Fields: []string{"a", "1", ""}, Line: 1, Raw: []byte("a\t1\t"), Offset: 5},
+				{Fields: []string{"b", "\"2\"", " x"}, Line: 3, Raw: []byte("b\t\"2\"\t x"), Offset: 16},
+			},
+			expectedErrors: []bool{false, false},
+		},
+		{
+			name: "jsonl",
+			source: NewJSONLSource(bytes.NewBufferString(
+				`["a", 1, true, null]`+"\n"+
+					`{"ticket": "1001", "paid": 12.5}`+"\n"+
+					`{"ticket": {"id": 1}}`+"\n"+
+					`"a"`+"\n"), []string{"ticket", "paid"}),
+			expected: []Row{
+				{Fields: []string{"a", "1", "true", ""}, Line: 1, Raw: []byte(`["a", 1, true, null]`), Offset: 21},
+				{Fields: []string{"1001", "12.5"}, Line: 2, Raw: []byte(`{"ticket": "1001", "paid": 12.5}`), Offset: 54},
+				{Line: 3, Raw: []byte(`{"ticket": {"id": 1}}`), Offset: 76},
+				{Line: 4, Raw: []byte(`"a"`), Offset: 80},
+			},
+			expectedErrors: []bool{false, false, true, true},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, errs := readRows(t, tc.source)
+
+			require.Equal(t, tc.expected, rows)
+			for i, err := range errs {
+				require.Equal(t, tc.expectedErrors[i], err != nil, "row %d: %v", i, err)
+			}
+		})
+	}
+}
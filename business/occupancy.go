@@ -0,0 +1,146 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.uber.org/zap"
+)
+
+// occupancyReader reads how many vehicles were parked and which ones
+type occupancyReader interface {
+	Occupancy(ctx context.Context, parking int64, from time.Time, to time.Time) ([]model.HourOccupancy, error)
+	ListPresent(ctx context.Context, parking int64, hour time.Time) ([]model.Transaction, error)
+}
+
+// OverOccupancy is an hour when more vehicles were parked in a park than
+// fit in it, which usually means missing checkouts or duplicated tickets
+type OverOccupancy struct {
+	Hour time.Time `json:"hour"`
+	// UseType is the use type whose spaces were exceeded, empty for the park
+	UseType  string `json:"use_type,omitempty"`
+	Vehicles int64  `json:"vehicles"`
+	Capacity int64  `json:"capacity"`
+	// Suspects are the transactions of the hour likely to be wrong
+	Suspects []Suspect `json:"suspects"`
+}
+
+// Suspect is a transaction likely to be wrong along with why
+type Suspect struct {
+	Transaction model.Transaction `json:"transaction"`
+	Reasons     []string          `json:"reasons"`
+}
+
+type OccupancyCheck interface {
+	OverOccupied(ctx context.Context, park *model.Park, from time.Time, to time.Time) ([]OverOccupancy, error)
+}
+
+type occupancyCheckImpl struct {
+	transactions occupancyReader
+	// maxStay is how long a stay can be before it is suspected of a missing
+	// checkout
+	maxStay time.Duration
+	logger  *zap.Logger
+}
+
+func NewOccupancyCheck(dbAcess *mongo.DB, maxStay time.Duration) OccupancyCheck {
+	return newOccupancyCheck(dbAcess.TransactionCollection, maxStay)
+}
+
+func newOccupancyCheck(transactions occupancyReader, maxStay time.Duration) *occupancyCheckImpl {
+	log, _ := zap.NewProduction()
+
+	return &occupancyCheckImpl{
+		transactions: transactions,
+		maxStay:      maxStay,
+		logger:       log,
+	}
+}
+
+// OverOccupied returns the hours from from to to, excluded, when the park
+// held more vehicles than its capacity, or more of a use type than the
+// capacity of the use type, with the suspect transactions of each
+func (s *occupancyCheckImpl) OverOccupied(ctx context.Context, park *model.Park, from time.Time, to time.Time) ([]OverOccupancy, error) {
+	if park.Capacity == 0 && len(park.Capacities) == 0 {
+		return nil, fmt.Errorf("park [%s] has no capacity", park.Slug)
+	}
+
+	hours, err := s.transactions.Occupancy(ctx, park.ID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error reading occupancy of park [%s]: [%s]", park.Slug, err.Error())
+	}
+
+	useTypes := []string{}
+	for useType := range park.Capacities {
+		useTypes = append(useTypes, useType)
+	}
+	sort.Strings(useTypes)
+
+	over := []OverOccupancy{}
+	for _, hour := range hours {
+		exceeded := []OverOccupancy{}
+		if park.Capacity > 0 && hour.Vehicles > park.Capacity {
+			exceeded = append(exceeded, OverOccupancy{Hour: hour.Hour, Vehicles: hour.Vehicles, Capacity: park.Capacity})
+		}
+		for _, useType := range useTypes {
+			if vehicles := hour.UseTypes[useType]; vehicles > park.Capacities[useType] {
+				exceeded = append(exceeded, OverOccupancy{Hour: hour.Hour, UseType: useType, Vehicles: vehicles, Capacity: park.Capacities[useType]})
+			}
+		}
+		if len(exceeded) == 0 {
+			continue
+		}
+
+		present, err := s.transactions.ListPresent(ctx, park.ID, hour.Hour)
+		if err != nil {
+			return nil, fmt.Errorf("error listing transactions of park [%s] at [%s]: [%s]", park.Slug, hour.Hour, err.Error())
+		}
+
+		for _, o := range exceeded {
+			o.Suspects = s.suspects(present, o.UseType)
+			over = append(over, o)
+		}
+	}
+
+	return over, nil
+}
+
+// suspects returns the transactions of useType, every one when empty,
+// sharing their ticket or plate with another one parked at the same time,
+// or staying longer than maxStay
+func (s *occupancyCheckImpl) suspects(present []model.Transaction, useType string) []Suspect {
+	tickets := map[string]int{}
+	plates := map[string]int{}
+	for _, transaction := range present {
+		tickets[transaction.Sequence]++
+		plates[transaction.Matricula]++
+	}
+
+	suspects := []Suspect{}
+	for _, transaction := range present {
+		if useType != "" && transaction.UseType != useType {
+			continue
+		}
+
+		reasons := []string{}
+		if transaction.Sequence != "" && tickets[transaction.Sequence] > 1 {
+			reasons = append(reasons, fmt.Sprintf("ticket [%s] is duplicated", transaction.Sequence))
+		}
+		if transaction.Matricula != "" && plates[transaction.Matricula] > 1 {
+			reasons = append(reasons, fmt.Sprintf("plate [%s] is parked more than once", transaction.Matricula))
+		}
+		if s.maxStay > 0 && transaction.CheckoutDate.Sub(transaction.CheckinDate) > s.maxStay {
+			reasons = append(reasons, fmt.Sprintf("stay is longer than %s, checkout may be missing", s.maxStay))
+		}
+
+		if len(reasons) > 0 {
+			suspects = append(suspects, Suspect{Transaction: transaction, Reasons: reasons})
+		}
+	}
+
+	return suspects
+}
@@ -0,0 +1,124 @@
+package business
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOccupancy counts the occupancy of transactions in memory
+type memoryOccupancy []model.Transaction
+
+func (m memoryOccupancy) Occupancy(ctx context.Context, parking int64, from time.Time, to time.Time) ([]model.HourOccupancy, error) {
+	hours := []model.HourOccupancy{}
+	for hour := from; hour.Before(to); hour = hour.Add(time.Hour) {
+		occupancy := model.HourOccupancy{Hour: hour, UseTypes: map[string]int64{}}
+		present, _ := m.ListPresent(ctx, parking, hour)
+		for _, transaction := range present {
+			occupancy.Vehicles++
+			occupancy.UseTypes[transaction.UseType]++
+		}
+		if occupancy.Vehicles > 0 {
+			hours = append(hours, occupancy)
+		}
+	}
+
+	return hours, nil
+}
+
+func (m memoryOccupancy) ListPresent(ctx context.Context, parking int64, hour time.Time) ([]model.Transaction, error) {
+	present := []model.Transaction{}
+	for _, transaction := range m {
+		for _, h := range transaction.TimeIntervalHour {
+			if transaction.ParkingInfo.ID == parking && h.Equal(hour) {
+				present = append(present, transaction)
+			}
+		}
+	}
+
+	return present, nil
+}
+
+func TestOccupancyCheck_OverOccupied(t *testing.T) {
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	stay := func(ticket string, plate string, useType string, from int, to int) model.Transaction {
+		transaction := model.Transaction{
+			Sequence:     ticket,
+			Matricula:    plate,
+			UseType:      useType,
+			ParkingInfo:  model.Parking{ID: 6},
+			CheckinDate:  day.Add(time.Duration(from) * time.Hour),
+			CheckoutDate: day.Add(time.Duration(to)*time.Hour - time.Minute),
+		}
+		for h := from; h < to; h++ {
+			transaction.TimeIntervalHour = append(transaction.TimeIntervalHour, day.Add(time.Duration(h)*time.Hour))
+		}
+		return transaction
+	}
+
+	transactions := memoryOccupancy{
+		stay("1", "ABC1234", "Avulso", 0, 30),
+		stay("2", "DEF4567", "Avulso", 10, 11),
+		stay("2", "GHI7890", "Avulso", 10, 12),
+		stay("3", "ABC1234", "Mensalista", 11, 12),
+		stay("4", "JKL1234", "Mensalista", 11, 12),
+	}
+
+	type TestRun struct {
+		name          string
+		park          *model.Park
+		expected      []OverOccupancy
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name: "park capacity",
+			park: &model.Park{ID: 6, Slug: "monza", Capacity: 3},
+			expected: []OverOccupancy{
+				{Hour: day.Add(11 * time.Hour), Vehicles: 4, Capacity: 3, Suspects: []Suspect{
+					{Transaction: transactions[0], Reasons: []string{"plate [ABC1234] is parked more than once", "stay is longer than 24h0m0s, checkout may be missing"}},
+					{Transaction: transactions[3], Reasons: []string{"plate [ABC1234] is parked more than once"}},
+				}},
+			},
+		},
+		{
+			name: "use type capacity",
+			park: &model.Park{ID: 6, Slug: "monza", Capacities: map[string]int64{"Avulso": 2}},
+			expected: []OverOccupancy{
+				{Hour: day.Add(10 * time.Hour), UseType: "Avulso", Vehicles: 3, Capacity: 2, Suspects: []Suspect{
+					{Transaction: transactions[0], Reasons: []string{"stay is longer than 24h0m0s, checkout may be missing"}},
+					{Transaction: transactions[1], Reasons: []string{"ticket [2] is duplicated"}},
+					{Transaction: transactions[2], Reasons: []string{"ticket [2] is duplicated"}},
+				}},
+			},
+		},
+		{
+			name:     "within capacity",
+			park:     &model.Park{ID: 6, Slug: "monza", Capacity: 10},
+			expected: []OverOccupancy{},
+		},
+		{
+			name:          "no capacity",
+			park:          &model.Park{ID: 6, Slug: "monza"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := newOccupancyCheck(transactions, 24*time.Hour).OverOccupied(context.Background(), tc.park, day, day.AddDate(0, 0, 1))
+
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// flagOverlaps flags transaction and the stays of its plate in its park it
+// overlaps as deviations, linking each to the other, and returns how many
+// it overlaps. The plate is matched as any of plates, so stays hashed with
+// older keys are checked too. Stays without a plate or both dates are not
+// checked
+func flagOverlaps(ctx context.Context, store Store, transaction *model.Transaction, plates []string) (int, error) {
+	if transaction.Matricula == "" || transaction.CheckinDate.IsZero() || transaction.CheckoutDate.IsZero() {
+		return 0, nil
+	}
+
+	overlapping, err := store.OverlappingTransactions(ctx, transaction, plates)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, other := range overlapping {
+		err = store.MarkOverlap(ctx, transaction.ID, other.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		if transaction.PriorStatus == nil {
+			prior := transaction.Status
+			transaction.PriorStatus = &prior
+		}
+		transaction.Status = DEVIATION
+		transaction.OverlapsWith = append(transaction.OverlapsWith, other.ID)
+	}
+
+	return len(overlapping), nil
+}
+
+// overlapReader reads the flagged transactions of a park
+type overlapReader interface {
+	Iterate(ctx context.Context, filter model.TransactionFilter, fn func(transaction *model.Transaction) error) error
+	GetByID(ctx context.Context, id string) (*model.Transaction, error)
+}
+
+// OverlapPair is two stays of the same plate in a park overlapping each
+// other, the one checking in first before
+type OverlapPair struct {
+	First  model.Transaction `json:"first"`
+	Second model.Transaction `json:"second"`
+}
+
+type OverlapReport interface {
+	Overlaps(ctx context.Context, parking int64, from time.Time, to time.Time) ([]OverlapPair, error)
+}
+
+type overlapReportImpl struct {
+	transactions overlapReader
+	logger       *zap.Logger
+}
+
+func NewOverlapReport(dbAcess *mongo.DB) OverlapReport {
+	return newOverlapReport(dbAcess.TransactionCollection)
+}
+
+func newOverlapReport(transactions overlapReader) *overlapReportImpl {
+	log, _ := zap.NewProduction()
+
+	return &overlapReportImpl{
+		transactions: transactions,
+		logger:       log,
+	}
+}
+
+// Overlaps returns the pairs of overlapping stays of a park with either
+// one checking out from from to to, excluded, ordered by checkout
+func (s *overlapReportImpl) Overlaps(ctx context.Context, parking int64, from time.Time, to time.Time) ([]OverlapPair, error) {
+	status := DEVIATION
+	filter := model.TransactionFilter{ParkingID: &parking, From: &from, To: &to, Status: &status}
+
+	found := map[primitive.ObjectID]model.Transaction{}
+	flagged := []model.Transaction{}
+	err := s.transactions.Iterate(ctx, filter, func(transaction *model.Transaction) error {
+		if len(transaction.OverlapsWith) > 0 {
+			found[transaction.ID] = *transaction
+			flagged = append(flagged, *transaction)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing deviations of park [%d]: [%s]", parking, err.Error())
+	}
+
+	pairs := []OverlapPair{}
+	seen := map[[2]primitive.ObjectID]bool{}
+	for _, transaction := range flagged {
+		for _, id := range transaction.OverlapsWith {
+			key := [2]primitive.ObjectID{transaction.ID, id}
+			if id.Hex() < transaction.ID.Hex() {
+				key = [2]primitive.ObjectID{id, transaction.ID}
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			// the other stay may check out outside of the period
+			other, ok := found[id]
+			if !ok {
+				stored, err := s.transactions.GetByID(ctx, id.Hex())
+				if err != nil {
+					return nil, fmt.Errorf("error getting transaction [%s]: [%s]", id.Hex(), err.Error())
+				}
+				if stored.ID.IsZero() {
+					continue
+				}
+				other = *stored
+			}
+
+			pair := OverlapPair{First: transaction, Second: other}
+			if other.CheckinDate.Before(transaction.CheckinDate) {
+				pair = OverlapPair{First: other, Second: transaction}
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs, nil
+}
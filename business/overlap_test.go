@@ -0,0 +1,73 @@
+package business
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryTransactions reads transactions in memory, filtered by park,
+// checkout and status
+type memoryTransactions []model.Transaction
+
+func (m memoryTransactions) Iterate(ctx context.Context, filter model.TransactionFilter, fn func(transaction *model.Transaction) error) error {
+	for _, transaction := range m {
+		if transaction.ParkingInfo.ID != *filter.ParkingID || transaction.Status != *filter.Status ||
+			transaction.CheckoutDate.Before(*filter.From) || !transaction.CheckoutDate.Before(*filter.To) {
+			continue
+		}
+
+		transaction := transaction
+		err := fn(&transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m memoryTransactions) GetByID(ctx context.Context, id string) (*model.Transaction, error) {
+	for _, transaction := range m {
+		if transaction.ID.Hex() == id {
+			return &transaction, nil
+		}
+	}
+
+	return &model.Transaction{}, nil
+}
+
+func TestOverlapReport_Overlaps(t *testing.T) {
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	stay := func(i int, from int, to int, overlaps ...primitive.ObjectID) model.Transaction {
+		return model.Transaction{
+			ID:           ids[i],
+			ParkingInfo:  model.Parking{ID: 6},
+			Matricula:    "ABC1234",
+			Status:       DEVIATION,
+			CheckinDate:  day.Add(time.Duration(from) * time.Hour),
+			CheckoutDate: day.Add(time.Duration(to) * time.Hour),
+			OverlapsWith: overlaps,
+		}
+	}
+
+	transactions := memoryTransactions{
+		stay(0, 10, 12, ids[1]),
+		stay(1, 11, 13, ids[0], ids[2]),
+		// checks out the next day, outside of the period
+		stay(2, 12, 30, ids[1]),
+		{ID: ids[3], ParkingInfo: model.Parking{ID: 6}, Status: DEVIATION, CheckoutDate: day.Add(time.Hour)},
+	}
+
+	pairs, err := newOverlapReport(transactions).Overlaps(context.Background(), 6, day, day.AddDate(0, 0, 1))
+	require.Nil(t, err)
+	require.Equal(t, []OverlapPair{
+		{First: transactions[0], Second: transactions[1]},
+		{First: transactions[1], Second: transactions[2]},
+	}, pairs)
+}
@@ -0,0 +1,60 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/csv-processor/model"
+)
+
+// Parks looks parks up in the registry, which returns empty parks for the
+// unknown ones
+type Parks interface {
+	GetBySlug(ctx context.Context, slug string) (*model.Park, error)
+	GetByID(ctx context.Context, id int64) (*model.Park, error)
+}
+
+// ErrUnknownPark is returned for parks missing from the registry
+type ErrUnknownPark struct {
+	Park string
+}
+
+func (e *ErrUnknownPark) Error() string {
+	return fmt.Sprintf("unknown park [%s], add it with park-add", e.Park)
+}
+
+// FindPark returns the park of the registry with slug
+func FindPark(ctx context.Context, parks Parks, slug string) (*model.Park, error) {
+	park, err := parks.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("error finding park [%s]: [%s]", slug, err.Error())
+	}
+
+	if park.ID == 0 {
+		return nil, &ErrUnknownPark{Park: slug}
+	}
+
+	return park, nil
+}
+
+// findParking returns the park of the registry found by slug, or by ID
+// without one. The ID found along with a slug is left to check
+func findParking(ctx context.Context, parks Parks, found model.Parking) (*model.Park, error) {
+	var park *model.Park
+	var err error
+
+	if found.Slug != "" {
+		park, err = FindPark(ctx, parks, found.Slug)
+	} else {
+		park, err = parks.GetByID(ctx, found.ID)
+		if err == nil && park.ID == 0 {
+			err = &ErrUnknownPark{Park: strconv.FormatInt(found.ID, 10)}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return park, nil
+}
@@ -0,0 +1,43 @@
+package business
+
+import (
+	"context"
+	"testing"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryParks is a registry of parks in memory
+type memoryParks []model.Park
+
+func (m memoryParks) GetBySlug(ctx context.Context, slug string) (*model.Park, error) {
+	for _, park := range m {
+		if park.Slug == slug {
+			return &park, nil
+		}
+	}
+
+	return &model.Park{}, nil
+}
+
+func (m memoryParks) GetByID(ctx context.Context, id int64) (*model.Park, error) {
+	for _, park := range m {
+		if park.ID == id {
+			return &park, nil
+		}
+	}
+
+	return &model.Park{}, nil
+}
+
+func TestFindPark(t *testing.T) {
+	parks := memoryParks{{ID: 6, Name: "Monza", Slug: "monza"}}
+
+	park, err := FindPark(context.Background(), parks, "monza")
+	require.Nil(t, err)
+	require.Equal(t, model.Parking{ID: 6, Name: "Monza", Slug: "monza"}, park.Parking())
+
+	_, err = FindPark(context.Background(), parks, "centro")
+	require.Equal(t, &ErrUnknownPark{Park: "centro"}, err)
+}
@@ -0,0 +1,140 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	// PlateOld is the Brazilian plate of three letters and four digits
+	PlateOld = "old"
+	// PlateMercosul is the Mercosul plate, whose fifth character is a letter
+	PlateMercosul = "mercosul"
+	// PlateUnknown is a plate of neither format
+	PlateUnknown = "unknown"
+)
+
+var (
+	oldPlate      = regexp.MustCompile(`^[A-Z]{3}[0-9]{4}$`)
+	mercosulPlate = regexp.MustCompile(`^[A-Z]{3}[0-9][A-Z][0-9]{2}$`)
+)
+
+// NormalizePlate upper-cases a plate and strips its separators, such as
+// the dash of abc-1234
+func NormalizePlate(raw string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, raw)
+}
+
+// PlateFormat returns the format of a normalized plate, empty for no plate
+func PlateFormat(plate string) string {
+	switch {
+	case plate == "":
+		return ""
+	case oldPlate.MatchString(plate):
+		return PlateOld
+	case mercosulPlate.MatchString(plate):
+		return PlateMercosul
+	default:
+		return PlateUnknown
+	}
+}
+
+// MaskPlate hides a plate but its first three and last two characters,
+// ABC1234 becoming ABC**34. Shorter plates are hidden whole
+func MaskPlate(plate string) string {
+	if len(plate) <= 5 {
+		return strings.Repeat("*", len(plate))
+	}
+
+	return plate[:3] + strings.Repeat("*", len(plate)-5) + plate[len(plate)-2:]
+}
+
+// setPlate sets the normalized plate of transaction from its raw value
+func setPlate(transaction *model.Transaction, raw string) {
+	transaction.MatriculaRaw = raw
+	transaction.Matricula = NormalizePlate(raw)
+	transaction.PlateFormat = PlateFormat(transaction.Matricula)
+}
+
+// plateStore reads every transaction and rewrites their plates
+type plateStore interface {
+	Iterate(ctx context.Context, filter model.TransactionFilter, fn func(transaction *model.Transaction) error) error
+	SetPlate(ctx context.Context, transaction *model.Transaction) error
+}
+
+type PlateMigration interface {
+	Normalize(ctx context.Context, dryRun bool) (total int64, changed int64, err error)
+}
+
+type plateMigrationImpl struct {
+	store  plateStore
+	logger *zap.Logger
+}
+
+func NewPlateMigration(dbAcess *mongo.DB) PlateMigration {
+	return newPlateMigration(dbAcess.TransactionCollection)
+}
+
+func newPlateMigration(store plateStore) *plateMigrationImpl {
+	log, _ := zap.NewProduction()
+
+	return &plateMigrationImpl{
+		store:  store,
+		logger: log,
+	}
+}
+
+// Normalize normalizes the plates of the transactions stored before plates
+// were, or by an older normalizer, keeping their raw value, and returns how
+// many transactions were read and changed. On a dry run it only counts them
+func (s *plateMigrationImpl) Normalize(ctx context.Context, dryRun bool) (int64, int64, error) {
+	total, changed := int64(0), int64(0)
+
+	err := s.store.Iterate(ctx, model.TransactionFilter{}, func(transaction *model.Transaction) error {
+		total++
+
+		// pseudonymized plates were normalized before being hashed
+		if transaction.KeyID != "" {
+			return nil
+		}
+
+		// documents stored before normalizing hold the raw plate only
+		raw := transaction.MatriculaRaw
+		if raw == "" {
+			raw = transaction.Matricula
+		}
+
+		normalized := *transaction
+		setPlate(&normalized, raw)
+		if normalized.Matricula == transaction.Matricula && normalized.MatriculaRaw == transaction.MatriculaRaw &&
+			normalized.PlateFormat == transaction.PlateFormat {
+			return nil
+		}
+
+		changed++
+		if dryRun {
+			return nil
+		}
+
+		err := s.store.SetPlate(ctx, &normalized)
+		if err != nil {
+			return fmt.Errorf("error normalizing plate of transaction [%s]: [%s]", transaction.ID.Hex(), err.Error())
+		}
+
+		return nil
+	})
+
+	return total, changed, err
+}
@@ -0,0 +1,99 @@
+package business
+
+import (
+	"context"
+	"testing"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizePlate(t *testing.T) {
+	type TestRun struct {
+		raw            string
+		expected       string
+		expectedFormat string
+	}
+
+	tt := []TestRun{
+		{raw: "ABC1234", expected: "ABC1234", expectedFormat: PlateOld},
+		{raw: "abc-1234", expected: "ABC1234", expectedFormat: PlateOld},
+		{raw: " abc 1234 ", expected: "ABC1234", expectedFormat: PlateOld},
+		{raw: "ABC1D23", expected: "ABC1D23", expectedFormat: PlateMercosul},
+		{raw: "abc.1d23", expected: "ABC1D23", expectedFormat: PlateMercosul},
+		{raw: "AB1234", expected: "AB1234", expectedFormat: PlateUnknown},
+		{raw: "-", expected: "", expectedFormat: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.raw, func(t *testing.T) {
+			plate := NormalizePlate(tc.raw)
+			require.Equal(t, tc.expected, plate)
+			require.Equal(t, tc.expectedFormat, PlateFormat(plate))
+		})
+	}
+}
+
+func TestMaskPlate(t *testing.T) {
+	require.Equal(t, "ABC**34", MaskPlate("ABC1234"))
+	require.Equal(t, "ABC**23", MaskPlate("ABC1D23"))
+	require.Equal(t, "*****", MaskPlate("AB123"))
+	require.Equal(t, "", MaskPlate(""))
+}
+
+// memoryPlates keeps the transactions whose plates are normalized in memory
+type memoryPlates struct {
+	transactions []model.Transaction
+}
+
+func (m *memoryPlates) Iterate(ctx context.Context, filter model.TransactionFilter, fn func(transaction *model.Transaction) error) error {
+	for _, transaction := range m.transactions {
+		transaction := transaction
+		err := fn(&transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryPlates) SetPlate(ctx context.Context, transaction *model.Transaction) error {
+	for i := range m.transactions {
+		if m.transactions[i].ID == transaction.ID {
+			m.transactions[i] = *transaction
+		}
+	}
+
+	return nil
+}
+
+func TestPlateMigration_Normalize(t *testing.T) {
+	store := &memoryPlates{transactions: []model.Transaction{
+		{ID: primitive.NewObjectID(), Matricula: "abc-1234"},
+		{ID: primitive.NewObjectID(), Matricula: "ABC1D23", MatriculaRaw: "ABC1D23", PlateFormat: PlateMercosul},
+		{ID: primitive.NewObjectID(), Matricula: "ABC1D23", MatriculaRaw: "abc 1d23"},
+	}}
+
+	total, changed, err := newPlateMigration(store).Normalize(context.Background(), true)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, int64(2), changed)
+	require.Equal(t, "abc-1234", store.transactions[0].Matricula)
+
+	total, changed, err = newPlateMigration(store).Normalize(context.Background(), false)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, int64(2), changed)
+
+	require.Equal(t, "ABC1234", store.transactions[0].Matricula)
+	require.Equal(t, "abc-1234", store.transactions[0].MatriculaRaw)
+	require.Equal(t, PlateOld, store.transactions[0].PlateFormat)
+	require.Equal(t, "abc 1d23", store.transactions[2].MatriculaRaw)
+	require.Equal(t, PlateMercosul, store.transactions[2].PlateFormat)
+
+	_, changed, err = newPlateMigration(store).Normalize(context.Background(), false)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), changed)
+}
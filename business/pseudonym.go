@@ -0,0 +1,178 @@
+package business
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.uber.org/zap"
+)
+
+// Pseudonymizer replaces the plates and identity documents of the
+// transactions with their HMAC-SHA256, so they still join on plate. A nil
+// Pseudonymizer keeps them in clear
+type Pseudonymizer struct {
+	keyID string
+	keys  map[string][]byte
+}
+
+// NewPseudonymizer returns the pseudonymizer of the privacy settings, nil
+// when they have no key
+func NewPseudonymizer(privacy config.Privacy) *Pseudonymizer {
+	if privacy.KeyID == "" {
+		return nil
+	}
+
+	keys := map[string][]byte{}
+	for id, secret := range privacy.Keys {
+		keys[id] = []byte(secret)
+	}
+
+	return &Pseudonymizer{keyID: privacy.KeyID, keys: keys}
+}
+
+func (p *Pseudonymizer) hash(keyID string, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, p.keys[keyID])
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Apply hashes the plate and identity of transaction with the current key,
+// recording its ID. The raw plate is dropped, its format is kept
+func (p *Pseudonymizer) Apply(transaction *model.Transaction) {
+	if p == nil || transaction.KeyID != "" {
+		return
+	}
+
+	transaction.Matricula = p.hash(p.keyID, transaction.Matricula)
+	transaction.Identity = p.hash(p.keyID, transaction.Identity)
+	transaction.MatriculaRaw = ""
+	transaction.KeyID = p.keyID
+}
+
+// Plates returns the values a plate may be stored as: in clear and hashed
+// with each key, the current one first
+func (p *Pseudonymizer) Plates(plate string) []string {
+	plate = NormalizePlate(plate)
+	if p == nil {
+		return []string{plate}
+	}
+
+	ids := []string{}
+	for id := range p.keys {
+		if id != p.keyID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	plates := []string{p.hash(p.keyID, plate)}
+	for _, id := range ids {
+		plates = append(plates, p.hash(id, plate))
+	}
+
+	// documents stored before the keys were set are in clear
+	return append(plates, plate)
+}
+
+// MaskTransaction masks the plate and identity of a transaction exported or
+// returned by the API stored in clear, hashed ones are kept to join on
+func MaskTransaction(transaction *model.Transaction) {
+	if transaction.KeyID == "" {
+		transaction.Matricula = MaskPlate(transaction.Matricula)
+		transaction.Identity = MaskPlate(transaction.Identity)
+	}
+	transaction.MatriculaRaw = MaskPlate(transaction.MatriculaRaw)
+}
+
+// plateEraser erases the personal data of the transactions of a plate
+type plateEraser interface {
+	ErasePlates(ctx context.Context, plates []string) (int64, error)
+}
+
+// ForgetSummary counts what was erased of a plate
+type ForgetSummary struct {
+	Transactions int64 `json:"transactions"`
+	// Archived are the transactions erased in the archive collection
+	Archived int64 `json:"archived"`
+	// Rejects are the rejected rows dropped from the rejects of the jobs
+	Rejects int64 `json:"rejects"`
+}
+
+type Erasure interface {
+	// Forget erases the plate, identity and links to other stays of every
+	// transaction of a plate, archived to the collection or not, and drops
+	// the rows holding it from the rejects of the finished jobs. The stays
+	// are kept, so is the revenue. JSONL archives are files out of its reach
+	Forget(ctx context.Context, plate string) (*ForgetSummary, error)
+}
+
+type erasureImpl struct {
+	transactions  plateEraser
+	archive       plateEraser
+	pseudonymizer *Pseudonymizer
+	rejectsDir    string
+	logger        *zap.Logger
+}
+
+// NewErasure returns the erasure of the privacy settings, the rejects of
+// the jobs are looked for under rejectsDir, the directory of serve, unless
+// empty
+func NewErasure(dbAcess *mongo.DB, privacy config.Privacy, rejectsDir string) Erasure {
+	return newErasure(dbAcess.TransactionCollection, dbAcess.ArchiveCollection, NewPseudonymizer(privacy), rejectsDir)
+}
+
+func newErasure(transactions plateEraser, archive plateEraser, pseudonymizer *Pseudonymizer, rejectsDir string) *erasureImpl {
+	log, _ := zap.NewProduction()
+
+	return &erasureImpl{
+		transactions:  transactions,
+		archive:       archive,
+		pseudonymizer: pseudonymizer,
+		rejectsDir:    rejectsDir,
+		logger:        log,
+	}
+}
+
+func (s *erasureImpl) Forget(ctx context.Context, plate string) (*ForgetSummary, error) {
+	plates := s.pseudonymizer.Plates(plate)
+	normalized := plates[len(plates)-1]
+	if normalized == "" {
+		return nil, fmt.Errorf("plate [%s] is empty once normalized", plate)
+	}
+
+	erased, err := s.transactions.ErasePlates(ctx, plates)
+	if err != nil {
+		return nil, fmt.Errorf("error erasing transactions of plate [%s]: [%s]", MaskPlate(normalized), err.Error())
+	}
+	summary := &ForgetSummary{Transactions: erased}
+
+	summary.Archived, err = s.archive.ErasePlates(ctx, plates)
+	if err != nil {
+		return nil, fmt.Errorf("error erasing archived transactions of plate [%s]: [%s]", MaskPlate(normalized), err.Error())
+	}
+
+	if s.rejectsDir != "" {
+		var skipped []string
+		summary.Rejects, skipped, err = forgetRejects(s.rejectsDir, normalized)
+		if err != nil {
+			return nil, fmt.Errorf("error erasing rejects of plate [%s]: [%s]", MaskPlate(normalized), err.Error())
+		}
+		if len(skipped) > 0 {
+			s.logger.Sugar().Warnw("Rejects of queued or running jobs not erased, forget again once they finish", "jobs", skipped)
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,160 @@
+package business
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPseudonymizer(t *testing.T) {
+	require.Nil(t, NewPseudonymizer(config.Privacy{Keys: map[string]string{"2020": "secret"}}))
+
+	old := NewPseudonymizer(config.Privacy{KeyID: "2020", Keys: map[string]string{"2020": "secret"}})
+	current := NewPseudonymizer(config.Privacy{KeyID: "2021", Keys: map[string]string{"2020": "secret", "2021": "rotated"}})
+
+	transaction := &model.Transaction{Matricula: "ABC1234", MatriculaRaw: "abc-1234", PlateFormat: PlateOld, Identity: "12345678901"}
+	old.Apply(transaction)
+	require.Equal(t, 64, len(transaction.Matricula))
+	require.Equal(t, 64, len(transaction.Identity))
+	require.Equal(t, "", transaction.MatriculaRaw)
+	require.Equal(t, PlateOld, transaction.PlateFormat)
+	require.Equal(t, "2020", transaction.KeyID)
+
+	// a pseudonymized transaction is not hashed again
+	hashed := *transaction
+	current.Apply(transaction)
+	require.Equal(t, hashed, *transaction)
+
+	plates := current.Plates("abc-1234")
+	require.Equal(t, 3, len(plates))
+	require.NotEqual(t, transaction.Matricula, plates[0])
+	require.Equal(t, transaction.Matricula, plates[1])
+	require.Equal(t, "ABC1234", plates[2])
+	require.Equal(t, old.Plates("ABC1234")[0], plates[1])
+
+	var clear *Pseudonymizer
+	require.Equal(t, []string{"ABC1234"}, clear.Plates("abc-1234"))
+	transaction = &model.Transaction{Matricula: "ABC1234"}
+	clear.Apply(transaction)
+	require.Equal(t, &model.Transaction{Matricula: "ABC1234"}, transaction)
+}
+
+func TestIngester_IngestPseudonymized(t *testing.T) {
+	pseudonymizer := NewPseudonymizer(config.Privacy{KeyID: "2020", Keys: map[string]string{"2020": "secret"}})
+	checkin := time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC)
+	line := &model.Line{
+		Ticket: "1001", Matricula: "abc-1234", Identity: "123.456.789-01", CheckIn: checkin, CheckOut: checkin.Add(time.Hour),
+		PaidValue: 12.5, PaymentMethod: "CRÉDITO", Table: "NORMAL",
+	}
+
+	store := &memoryStore{}
+	result, err := newIngester(store, memoryParks{{ID: 6, Name: "Monza", Slug: "monza"}}, HoursTouched{}, pseudonymizer).Ingest(context.Background(), model.Parking{ID: 6, Slug: "monza"}, line)
+	require.Nil(t, err)
+	require.Equal(t, pseudonymizer.Plates("ABC1234")[0], result.Matricula)
+	require.Equal(t, "", result.MatriculaRaw)
+	require.NotEqual(t, "123.456.789-01", result.Identity)
+	require.Equal(t, "2020", result.KeyID)
+	require.Equal(t, result.Matricula, store.transactions[0].Matricula)
+}
+
+func TestVP_ProcessRotatedKeys(t *testing.T) {
+	row := func(ticket string, plate string, checkin string, checkout string) []string {
+		return []string{"Monza", ticket, "", plate, "NORMAL", "", checkin, checkout, "", "", "5.00", "DINHEIRO", "NORMAL"}
+	}
+	parking := model.Parking{ID: 6, Slug: "monza"}
+	store := &memoryStore{}
+
+	first := newVP(store, NewMemorySource([][]string{
+		row("4001", "ABC1234", "01/10/2020 22:00:00", ""),
+		row("4002", "DEF4567", "01/10/2020 10:00:00", "01/10/2020 12:00:00"),
+	}), "transactions", parking, HoursTouched{}, primitive.NewObjectID())
+	first.pseudonymizer = NewPseudonymizer(config.Privacy{KeyID: "2020", Keys: map[string]string{"2020": "secret"}})
+	_, err := first.Process(context.Background())
+	require.Nil(t, err)
+
+	// the stays hashed with the old key are still closed and overlapped
+	second := newVP(store, NewMemorySource([][]string{
+		row("4001", "ABC1234", "01/10/2020 22:00:00", "02/10/2020 00:30:00"),
+		row("4003", "DEF4567", "01/10/2020 11:00:00", "01/10/2020 11:30:00"),
+	}), "transactions", parking, HoursTouched{}, primitive.NewObjectID())
+	second.pseudonymizer = NewPseudonymizer(config.Privacy{KeyID: "2021", Keys: map[string]string{"2020": "secret", "2021": "rotated"}})
+	summary, err := second.Process(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, model.ImportSummary{Rows: 2, Inserted: 1, Closed: 1, Overlapping: 1}, *summary)
+	require.Equal(t, 3, len(store.transactions))
+
+	closed := store.transactions[0]
+	require.Equal(t, VALID, closed.Status)
+	require.Equal(t, "2021", closed.KeyID)
+	require.Equal(t, second.pseudonymizer.Plates("ABC1234")[0], closed.Matricula)
+
+	require.Equal(t, DEVIATION, store.transactions[1].Status)
+	require.Equal(t, []primitive.ObjectID{store.transactions[2].ID}, store.transactions[1].OverlapsWith)
+}
+
+func TestMaskTransaction(t *testing.T) {
+	transaction := &model.Transaction{Matricula: "ABC1234", MatriculaRaw: "abc-1234", Identity: "12345678901"}
+	MaskTransaction(transaction)
+	require.Equal(t, &model.Transaction{Matricula: "ABC**34", MatriculaRaw: "abc***34", Identity: "123******01"}, transaction)
+
+	transaction = &model.Transaction{Matricula: "5f0e9a1c", Identity: "77aa0b1c", KeyID: "2020"}
+	MaskTransaction(transaction)
+	require.Equal(t, &model.Transaction{Matricula: "5f0e9a1c", Identity: "77aa0b1c", KeyID: "2020"}, transaction)
+}
+
+// memoryEraser records the plates erased
+type memoryEraser struct {
+	plates []string
+}
+
+func (m *memoryEraser) ErasePlates(ctx context.Context, plates []string) (int64, error) {
+	m.plates = plates
+	return 2, nil
+}
+
+func TestErasure_Forget(t *testing.T) {
+	pseudonymizer := NewPseudonymizer(config.Privacy{KeyID: "2020", Keys: map[string]string{"2020": "secret"}})
+	rejects := "line,error,row\n" +
+		"2,unknown table [X],\"Monza,2001,,abc-1234,X\"\n" +
+		"3,invalid checkin [32/10/2020],\"Monza,2002,,DEF4567,NORMAL\"\n" +
+		"4,unknown table [X],Monza;2003;;ABC 1234;X\n"
+	dir := tempDir(t, nil)
+	for _, job := range []string{"first", "second", "running"} {
+		require.Nil(t, os.Mkdir(filepath.Join(dir, job), 0755))
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, job, rejectsName), []byte(rejects), 0644))
+	}
+	// the upload is kept until the job finishes
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "running", "transactions.csv"), []byte("a;b\n"), 0644))
+
+	eraser, archive := &memoryEraser{}, &memoryEraser{}
+	summary, err := newErasure(eraser, archive, pseudonymizer, dir).Forget(context.Background(), "abc-1234")
+	require.Nil(t, err)
+	require.Equal(t, &ForgetSummary{Transactions: 2, Archived: 2, Rejects: 4}, summary)
+	require.Equal(t, pseudonymizer.Plates("ABC1234"), eraser.plates)
+	require.Equal(t, pseudonymizer.Plates("ABC1234"), archive.plates)
+	for _, job := range []string{"first", "second"} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, job, rejectsName))
+		require.Nil(t, err)
+		require.Equal(t, "line,error,row\n3,invalid checkin [32/10/2020],\"Monza,2002,,DEF4567,NORMAL\"\n", string(content))
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "running", rejectsName))
+	require.Nil(t, err)
+	require.Equal(t, rejects, string(content))
+
+	eraser = &memoryEraser{}
+	summary, err = newErasure(eraser, archive, nil, "").Forget(context.Background(), "abc-1234")
+	require.Nil(t, err)
+	require.Equal(t, &ForgetSummary{Transactions: 2, Archived: 2}, summary)
+	require.Equal(t, []string{"ABC1234"}, eraser.plates)
+
+	_, err = newErasure(eraser, archive, nil, "").Forget(context.Background(), "--")
+	require.NotNil(t, err)
+}
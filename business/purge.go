@@ -0,0 +1,201 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// ArchiveNone purges the expired transactions without keeping them
+	ArchiveNone = "none"
+	// ArchiveJSONL writes the expired transactions to a JSONL file first
+	ArchiveJSONL = "jsonl"
+	// ArchiveCollection copies the expired transactions to the archive
+	// collection first
+	ArchiveCollection = "collection"
+
+	// purgeBatch is how many transactions are archived and purged at once
+	purgeBatch = 1000
+)
+
+// PurgeSummary counts the expired transactions of a park
+type PurgeSummary struct {
+	// Expired counts the transactions checked out before the retention
+	Expired int64 `json:"expired"`
+	// Deleted counts the transactions deleted before the retention of
+	// the deleted ones
+	Deleted int64 `json:"deleted"`
+	// Purged counts the transactions removed, none on a dry run
+	Purged int64 `json:"purged"`
+}
+
+// purgeStore reads and removes the expired transactions of a park
+type purgeStore interface {
+	CountExpired(ctx context.Context, parking int64, checkoutBefore time.Time, deletedBefore time.Time) (int64, int64, error)
+	IterateExpired(ctx context.Context, parking int64, checkoutBefore time.Time, deletedBefore time.Time, fn func(transaction *model.Transaction) error) error
+	Purge(ctx context.Context, ids []primitive.ObjectID) (int64, error)
+}
+
+// Archiver keeps the transactions about to be purged
+type Archiver interface {
+	Archive(ctx context.Context, transactions []model.Transaction) error
+}
+
+type jsonlArchive struct {
+	writer  io.Writer
+	encoder *json.Encoder
+}
+
+// NewJSONLArchive returns the archiver writing a JSON transaction per line
+// to w, which is flushed after each batch when it can be
+func NewJSONLArchive(w io.Writer) Archiver {
+	return &jsonlArchive{writer: w, encoder: json.NewEncoder(w)}
+}
+
+func (a *jsonlArchive) Archive(ctx context.Context, transactions []model.Transaction) error {
+	for _, transaction := range transactions {
+		err := a.encoder.Encode(transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the transactions are purged once archived, they must be written out
+	if flusher, ok := a.writer.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+// NewCollectionArchive returns the archiver copying the transactions to the
+// archive collection
+func NewCollectionArchive(dbAcess *mongo.DB) Archiver {
+	return dbAcess.ArchiveCollection
+}
+
+type Purge interface {
+	// Purge removes the transactions of park past its retention policy at
+	// now, archiving them first unless the archiver is nil. A dry run only
+	// counts them
+	Purge(ctx context.Context, park model.Park, now time.Time, dryRun bool) (*PurgeSummary, error)
+}
+
+type purgeImpl struct {
+	store    purgeStore
+	archiver Archiver
+	logger   *zap.Logger
+}
+
+func NewPurge(dbAcess *mongo.DB, archiver Archiver) Purge {
+	return newPurge(dbAcess.TransactionCollection, archiver)
+}
+
+func newPurge(store purgeStore, archiver Archiver) *purgeImpl {
+	log, _ := zap.NewProduction()
+
+	return &purgeImpl{
+		store:    store,
+		archiver: archiver,
+		logger:   log,
+	}
+}
+
+// retentionCutoffs returns when the transactions of park still kept were
+// checked out and deleted from, zero for a park keeping them forever
+func retentionCutoffs(park model.Park, now time.Time) (time.Time, time.Time) {
+	var checkoutBefore, deletedBefore time.Time
+	if park.RetentionDays > 0 {
+		checkoutBefore = now.AddDate(0, 0, -int(park.RetentionDays))
+	}
+	if park.DeletedRetentionDays > 0 {
+		deletedBefore = now.AddDate(0, 0, -int(park.DeletedRetentionDays))
+	}
+
+	return checkoutBefore, deletedBefore
+}
+
+// RebuildFrom returns from, or the first day none of whose transactions
+// purge can have removed when later. The rollups of the earlier days can
+// not be rebuilt from the transactions left
+func RebuildFrom(park model.Park, from time.Time, now time.Time) time.Time {
+	checkoutBefore, _ := retentionCutoffs(park, now)
+	if checkoutBefore.IsZero() {
+		return from
+	}
+
+	first := time.Date(checkoutBefore.Year(), checkoutBefore.Month(), checkoutBefore.Day()+1, 0, 0, 0, 0, time.UTC)
+	if from.Before(first) {
+		return first
+	}
+
+	return from
+}
+
+func (s *purgeImpl) Purge(ctx context.Context, park model.Park, now time.Time, dryRun bool) (*PurgeSummary, error) {
+	summary := &PurgeSummary{}
+
+	checkoutBefore, deletedBefore := retentionCutoffs(park, now)
+	if checkoutBefore.IsZero() && deletedBefore.IsZero() {
+		return summary, nil
+	}
+
+	var err error
+	summary.Expired, summary.Deleted, err = s.store.CountExpired(ctx, park.ID, checkoutBefore, deletedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("error counting expired transactions of park [%s]: [%s]", park.Slug, err.Error())
+	}
+	if dryRun || summary.Expired+summary.Deleted == 0 {
+		return summary, nil
+	}
+
+	batch := []model.Transaction{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if s.archiver != nil {
+			err := s.archiver.Archive(ctx, batch)
+			if err != nil {
+				return fmt.Errorf("error archiving transactions of park [%s]: [%s]", park.Slug, err.Error())
+			}
+		}
+
+		ids := []primitive.ObjectID{}
+		for _, transaction := range batch {
+			ids = append(ids, transaction.ID)
+		}
+		purged, err := s.store.Purge(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("error purging transactions of park [%s]: [%s]", park.Slug, err.Error())
+		}
+		summary.Purged += purged
+		batch = batch[:0]
+
+		s.logger.Sugar().Infow("purging...", "park", park.Slug, "purged", summary.Purged)
+
+		return nil
+	}
+
+	err = s.store.IterateExpired(ctx, park.ID, checkoutBefore, deletedBefore, func(transaction *model.Transaction) error {
+		batch = append(batch, *transaction)
+		if len(batch) < purgeBatch {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	return summary, flush()
+}
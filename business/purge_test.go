@@ -0,0 +1,155 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryPurgeStore holds the transactions purged from in memory
+type memoryPurgeStore struct {
+	transactions []model.Transaction
+}
+
+func (m *memoryPurgeStore) expired(parking int64, checkoutBefore time.Time, deletedBefore time.Time) ([]model.Transaction, []model.Transaction) {
+	expired, deleted := []model.Transaction{}, []model.Transaction{}
+	for _, transaction := range m.transactions {
+		if transaction.ParkingInfo.ID != parking {
+			continue
+		}
+		if transaction.DeletedAt == nil && !checkoutBefore.IsZero() && !transaction.CheckoutDate.IsZero() &&
+			transaction.CheckoutDate.Before(checkoutBefore) {
+			expired = append(expired, transaction)
+		}
+		if transaction.DeletedAt != nil && !deletedBefore.IsZero() && transaction.DeletedAt.Before(deletedBefore) {
+			deleted = append(deleted, transaction)
+		}
+	}
+
+	return expired, deleted
+}
+
+func (m *memoryPurgeStore) CountExpired(ctx context.Context, parking int64, checkoutBefore time.Time, deletedBefore time.Time) (int64, int64, error) {
+	expired, deleted := m.expired(parking, checkoutBefore, deletedBefore)
+	return int64(len(expired)), int64(len(deleted)), nil
+}
+
+func (m *memoryPurgeStore) IterateExpired(ctx context.Context, parking int64, checkoutBefore time.Time, deletedBefore time.Time, fn func(transaction *model.Transaction) error) error {
+	expired, deleted := m.expired(parking, checkoutBefore, deletedBefore)
+	for _, transaction := range append(expired, deleted...) {
+		transaction := transaction
+		err := fn(&transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryPurgeStore) Purge(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	purge := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		purge[id] = true
+	}
+
+	kept := []model.Transaction{}
+	for _, transaction := range m.transactions {
+		if !purge[transaction.ID] {
+			kept = append(kept, transaction)
+		}
+	}
+	purged := int64(len(m.transactions) - len(kept))
+	m.transactions = kept
+
+	return purged, nil
+}
+
+func TestPurge_Purge(t *testing.T) {
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time {
+		return now.AddDate(0, 0, -days)
+	}
+	deletedAgo := func(days int) *time.Time {
+		deleted := daysAgo(days)
+		return &deleted
+	}
+	transaction := func(parking int64, checkout time.Time, deleted *time.Time) model.Transaction {
+		return model.Transaction{ID: primitive.NewObjectID(), ParkingInfo: model.Parking{ID: parking}, CheckoutDate: checkout, DeletedAt: deleted}
+	}
+
+	type TestRun struct {
+		name            string
+		park            model.Park
+		dryRun          bool
+		expected        *PurgeSummary
+		expectedArchive int
+		expectedKept    int
+	}
+
+	tt := []TestRun{
+		{
+			name:         "kept forever",
+			park:         model.Park{ID: 6, Slug: "monza"},
+			expected:     &PurgeSummary{},
+			expectedKept: 6,
+		},
+		{
+			name:         "dry run",
+			park:         model.Park{ID: 6, Slug: "monza", RetentionDays: 365, DeletedRetentionDays: 30},
+			dryRun:       true,
+			expected:     &PurgeSummary{Expired: 1, Deleted: 1},
+			expectedKept: 6,
+		},
+		{
+			name:            "transactions",
+			park:            model.Park{ID: 6, Slug: "monza", RetentionDays: 365},
+			expected:        &PurgeSummary{Expired: 1, Purged: 1},
+			expectedArchive: 1,
+			expectedKept:    5,
+		},
+		{
+			name:            "transactions and deleted ones",
+			park:            model.Park{ID: 6, Slug: "monza", RetentionDays: 365, DeletedRetentionDays: 30},
+			expected:        &PurgeSummary{Expired: 1, Deleted: 1, Purged: 2},
+			expectedArchive: 2,
+			expectedKept:    4,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memoryPurgeStore{transactions: []model.Transaction{
+				transaction(6, daysAgo(400), nil),
+				transaction(6, daysAgo(10), nil),
+				// an open stay
+				transaction(6, time.Time{}, nil),
+				transaction(6, daysAgo(400), deletedAgo(60)),
+				transaction(6, daysAgo(400), deletedAgo(5)),
+				transaction(7, daysAgo(400), nil),
+			}}
+			archive := &bytes.Buffer{}
+
+			summary, err := newPurge(store, NewJSONLArchive(archive)).Purge(context.Background(), tc.park, now, tc.dryRun)
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, summary)
+			require.Equal(t, tc.expectedKept, len(store.transactions))
+
+			lines := 0
+			decoder := json.NewDecoder(archive)
+			for decoder.More() {
+				archived := model.Transaction{}
+				require.Nil(t, decoder.Decode(&archived))
+				require.Equal(t, int64(6), archived.ParkingInfo.ID)
+				lines++
+			}
+			require.Equal(t, tc.expectedArchive, lines)
+		})
+	}
+}
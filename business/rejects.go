@@ -0,0 +1,154 @@
+package business
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RejectsWriter writes the rows an import rejected as CSV, with the line
+// each one starts at and why it was rejected
+type RejectsWriter struct {
+	writer  *csv.Writer
+	started bool
+}
+
+func NewRejectsWriter(w io.Writer) *RejectsWriter {
+	return &RejectsWriter{writer: csv.NewWriter(w)}
+}
+
+// Write writes a rejected row, the header before the first one. Rows whose
+// source does not keep them as written are joined by commas
+func (r *RejectsWriter) Write(row Row, reason error) error {
+	if !r.started {
+		r.started = true
+		err := r.writer.Write([]string{"line", "error", "row"})
+		if err != nil {
+			return err
+		}
+	}
+
+	raw := string(row.Raw)
+	if raw == "" {
+		raw = strings.Join(row.Fields, ",")
+	}
+
+	err := r.writer.Write([]string{strconv.FormatInt(row.Line, 10), reason.Error(), raw})
+	if err != nil {
+		return err
+	}
+
+	r.writer.Flush()
+
+	return r.writer.Error()
+}
+
+// forgetRejects drops the rejected rows holding plate, normalized, from the
+// rejects files of the jobs under dir and returns how many were dropped,
+// along with the jobs skipped as queued or running. Rows are matched on
+// their letters and digits, like plates are normalized, so a row only
+// looking like it holds the plate is dropped too
+func forgetRejects(dir string, plate string) (int64, []string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", rejectsName))
+	if err != nil {
+		return 0, nil, fmt.Errorf("error listing rejects in [%s]: [%s]", dir, err.Error())
+	}
+
+	var dropped int64
+	skipped := []string{}
+	for _, path := range paths {
+		active, err := jobActive(filepath.Dir(path))
+		if err != nil {
+			return dropped, skipped, err
+		}
+		// the job may still write to the file, rows written after it is
+		// replaced would be lost
+		if active {
+			skipped = append(skipped, filepath.Base(filepath.Dir(path)))
+			continue
+		}
+
+		count, err := dropRejects(path, plate)
+		if err != nil {
+			return dropped, skipped, err
+		}
+		dropped += count
+	}
+
+	return dropped, skipped, nil
+}
+
+// jobActive returns whether the job of dir is queued or running, serve may
+// run in another process so it is told by the upload, only removed once
+// the rejects are closed
+func jobActive(dir string) (bool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, fmt.Errorf("error listing job directory [%s]: [%s]", dir, err.Error())
+	}
+
+	for _, entry := range entries {
+		// temporary files of dropRejects start as the rejects
+		if !strings.HasPrefix(entry.Name(), rejectsName) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// dropRejects rewrites the rejects file at path without the rows holding
+// plate, the file is replaced whole so it is never left half written
+func dropRejects(path string, plate string) (int64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("error reading rejects [%s]: [%s]", path, err.Error())
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("error reading rejects [%s]: [%s]", path, err.Error())
+	}
+
+	kept := [][]string{}
+	for i, record := range records {
+		// the error may quote the plate too
+		if i > 0 && strings.Contains(NormalizePlate(strings.Join(record[1:], " ")), plate) {
+			continue
+		}
+		kept = append(kept, record)
+	}
+
+	dropped := int64(len(records) - len(kept))
+	if dropped == 0 {
+		return 0, nil
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(path), rejectsName)
+	if err != nil {
+		return 0, fmt.Errorf("error rewriting rejects [%s]: [%s]", path, err.Error())
+	}
+	defer os.Remove(temp.Name())
+
+	writer := csv.NewWriter(temp)
+	writer.WriteAll(kept)
+	err = writer.Error()
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error rewriting rejects [%s]: [%s]", path, err.Error())
+	}
+
+	return dropped, nil
+}
@@ -0,0 +1,138 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// rollbackStore reads and undoes what an import persisted
+type rollbackStore interface {
+	GetImport(ctx context.Context, id string) (*model.Import, error)
+	UpdateImport(ctx context.Context, record *model.Import) (*model.Import, error)
+	CountByImport(ctx context.Context, importID primitive.ObjectID) (int64, error)
+	CheckoutRangeByImport(ctx context.Context, importID primitive.ObjectID) (time.Time, time.Time, error)
+	DeleteByImport(ctx context.Context, importID primitive.ObjectID) (int64, error)
+	ReopenByImport(ctx context.Context, importID primitive.ObjectID) (int64, error)
+	RebuildRevenue(ctx context.Context, parking int64, from time.Time, to time.Time) (int64, error)
+}
+
+type Rollback interface {
+	Rollback(ctx context.Context, importID string, dryRun bool) (record *model.Import, total int64, err error)
+}
+
+type rollbackImpl struct {
+	store  rollbackStore
+	parks  Parks
+	logger *zap.Logger
+}
+
+func NewRollback(dbAcess *mongo.DB) Rollback {
+	return newRollback(mongoStore{dbAcess: dbAcess}, dbAcess.ParkingCollection)
+}
+
+func newRollback(store rollbackStore, parks Parks) *rollbackImpl {
+	log, _ := zap.NewProduction()
+
+	return &rollbackImpl{
+		store:  store,
+		parks:  parks,
+		logger: log,
+	}
+}
+
+// Rollback deletes (logically) every transaction created by an import,
+// reopens the stays it closed, rebuilds the rollups of the days they were
+// checked out and marks the import as rolled back. On a dry run it only
+// counts the transactions
+func (s *rollbackImpl) Rollback(ctx context.Context, importID string, dryRun bool) (*model.Import, int64, error) {
+	record, err := s.store.GetImport(ctx, importID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if record.ID.IsZero() {
+		return nil, 0, fmt.Errorf("import [%s] does not exists", importID)
+	}
+
+	if record.Status == model.ImportRolledBack {
+		return record, 0, fmt.Errorf("import [%s] was already rolled back", importID)
+	}
+
+	if record.Status == model.ImportRunning {
+		return record, 0, fmt.Errorf("import [%s] is still running", importID)
+	}
+
+	total, err := s.store.CountByImport(ctx, record.ID)
+	if err != nil {
+		return record, 0, err
+	}
+
+	if dryRun {
+		return record, total, nil
+	}
+
+	// open stays have no checkout and no revenue to rebuild
+	from, to, err := s.store.CheckoutRangeByImport(ctx, record.ID)
+	if err != nil {
+		return record, 0, err
+	}
+
+	total, err = s.store.DeleteByImport(ctx, record.ID)
+	if err != nil {
+		return record, 0, err
+	}
+
+	reopened, err := s.store.ReopenByImport(ctx, record.ID)
+	if err != nil {
+		return record, total, err
+	}
+
+	if !from.IsZero() {
+		err = s.rebuild(ctx, record.ParkingInfo.ID, from, to)
+		if err != nil {
+			return record, total, err
+		}
+	}
+
+	now := time.Now()
+	record.Status = model.ImportRolledBack
+	record.RolledBack = total
+	record.Reopened = reopened
+	record.RolledBackAt = &now
+
+	record, err = s.store.UpdateImport(ctx, record)
+	if err != nil {
+		return nil, total, err
+	}
+
+	s.logger.Sugar().Infow("rolled back", "import", importID, "transactions", total, "reopened", reopened)
+
+	return record, total, nil
+}
+
+// rebuild rebuilds the rollups of the days from from to to of a park, but
+// the days purge may have removed transactions of, which keep theirs
+func (s *rollbackImpl) rebuild(ctx context.Context, parking int64, from time.Time, to time.Time) error {
+	park, err := s.parks.GetByID(ctx, parking)
+	if err != nil {
+		return fmt.Errorf("error getting park [%d]: [%s]", parking, err.Error())
+	}
+
+	kept := RebuildFrom(*park, from, time.Now())
+	if kept.After(from) {
+		s.logger.Sugar().Warnw("rollups of purged days not rebuilt", "park", parking, "from", from, "until", kept)
+	}
+	if kept.After(to) {
+		return nil
+	}
+
+	_, err = s.store.RebuildRevenue(ctx, parking, kept, to)
+
+	return err
+}
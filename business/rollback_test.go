@@ -0,0 +1,355 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryRollback undoes imports in a memoryStore and records the ranges of
+// the rebuilt rollups
+type memoryRollback struct {
+	*memoryStore
+	imports  []*model.Import
+	rebuilds [][2]time.Time
+}
+
+func (m *memoryRollback) GetImport(ctx context.Context, id string) (*model.Import, error) {
+	for _, record := range m.imports {
+		if record.ID.Hex() == id {
+			return record, nil
+		}
+	}
+
+	return &model.Import{}, nil
+}
+
+func (m *memoryRollback) UpdateImport(ctx context.Context, record *model.Import) (*model.Import, error) {
+	for i, stored := range m.imports {
+		if stored.ID == record.ID {
+			m.imports[i] = record
+			return record, nil
+		}
+	}
+
+	return nil, fmt.Errorf("import [%s] not found", record.ID.Hex())
+}
+
+func (m *memoryRollback) CountByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	var count int64
+	for _, transaction := range m.transactions {
+		if transaction.ImportID == importID && transaction.DeletedAt == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *memoryRollback) CheckoutRangeByImport(ctx context.Context, importID primitive.ObjectID) (time.Time, time.Time, error) {
+	var from, to time.Time
+	for _, transaction := range m.transactions {
+		if transaction.ImportID != importID && transaction.ClosedImportID != importID {
+			continue
+		}
+		if transaction.DeletedAt != nil || transaction.CheckoutDate.IsZero() {
+			continue
+		}
+		if from.IsZero() || transaction.CheckoutDate.Before(from) {
+			from = transaction.CheckoutDate
+		}
+		if transaction.CheckoutDate.After(to) {
+			to = transaction.CheckoutDate
+		}
+	}
+
+	return from, to, nil
+}
+
+func (m *memoryRollback) DeleteByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	deleted := []primitive.ObjectID{}
+	now := time.Now()
+	for _, transaction := range m.transactions {
+		if transaction.ImportID == importID && transaction.DeletedAt == nil {
+			transaction.DeletedAt = &now
+			deleted = append(deleted, transaction.ID)
+		}
+	}
+	m.unlinkOverlaps(deleted)
+
+	return int64(len(deleted)), nil
+}
+
+// unlinkOverlaps drops the links to the stays of ids, setting back the
+// prior status of the stays overlapping no stay anymore
+func (m *memoryRollback) unlinkOverlaps(ids []primitive.ObjectID) {
+	for _, transaction := range m.transactions {
+		if transaction.PriorStatus == nil {
+			continue
+		}
+
+		kept := []primitive.ObjectID{}
+		for _, id := range transaction.OverlapsWith {
+			if !hasID(ids, id) {
+				kept = append(kept, id)
+			}
+		}
+		transaction.OverlapsWith = kept
+
+		if len(kept) == 0 {
+			transaction.Status = *transaction.PriorStatus
+			transaction.PriorStatus = nil
+			transaction.OverlapsWith = nil
+		}
+	}
+}
+
+func hasID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *memoryRollback) ReopenByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	reopened := []primitive.ObjectID{}
+	for _, transaction := range m.transactions {
+		if transaction.ClosedImportID == importID && transaction.DeletedAt == nil {
+			transaction.Status = OPEN
+			transaction.CheckoutDate = time.Time{}
+			transaction.PaidAmount = 0
+			transaction.ClosedImportID = primitive.NilObjectID
+			transaction.OverlapsWith = nil
+			transaction.PriorStatus = nil
+			reopened = append(reopened, transaction.ID)
+		}
+	}
+	m.unlinkOverlaps(reopened)
+
+	return int64(len(reopened)), nil
+}
+
+func (m *memoryRollback) RebuildRevenue(ctx context.Context, parking int64, from time.Time, to time.Time) (int64, error) {
+	m.rebuilds = append(m.rebuilds, [2]time.Time{from, to})
+
+	return 0, nil
+}
+
+func TestRollback_Rollback(t *testing.T) {
+	checkin := time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)
+	monza := model.Parking{ID: 6, Slug: "monza"}
+
+	type TestRun struct {
+		name             string
+		status           string
+		transactions     func(importID primitive.ObjectID) []*model.Transaction
+		dryRun           bool
+		expectedTotal    int64
+		expectedReopened int64
+		expectedRebuilds [][2]time.Time
+		expectedError    bool
+	}
+
+	tt := []TestRun{
+		{
+			name:   "created and open stays",
+			status: model.ImportDone,
+			transactions: func(importID primitive.ObjectID) []*model.Transaction {
+				return []*model.Transaction{
+					{ImportID: importID, ParkingInfo: monza, CheckinDate: checkin, CheckoutDate: checkin.Add(time.Hour), Status: VALID},
+					{ImportID: importID, ParkingInfo: monza, CheckinDate: checkin, Status: OPEN},
+				}
+			},
+			expectedTotal:    2,
+			expectedRebuilds: [][2]time.Time{{checkin.Add(time.Hour), checkin.Add(time.Hour)}},
+		},
+		{
+			name:   "only open stays",
+			status: model.ImportDone,
+			transactions: func(importID primitive.ObjectID) []*model.Transaction {
+				return []*model.Transaction{
+					{ImportID: importID, ParkingInfo: monza, CheckinDate: checkin, Status: OPEN},
+				}
+			},
+			expectedTotal: 1,
+		},
+		{
+			name:   "closed stays",
+			status: model.ImportDone,
+			transactions: func(importID primitive.ObjectID) []*model.Transaction {
+				return []*model.Transaction{
+					{ImportID: importID, ParkingInfo: monza, CheckinDate: checkin, CheckoutDate: checkin.Add(time.Hour), Status: VALID},
+					{ImportID: primitive.NewObjectID(), ClosedImportID: importID, ParkingInfo: monza, CheckinDate: checkin.Add(-24 * time.Hour), CheckoutDate: checkin.Add(2 * time.Hour), PaidAmount: 12.5, Status: VALID},
+				}
+			},
+			expectedTotal:    1,
+			expectedReopened: 1,
+			expectedRebuilds: [][2]time.Time{{checkin.Add(time.Hour), checkin.Add(2 * time.Hour)}},
+		},
+		{
+			name:   "dry run",
+			status: model.ImportDone,
+			transactions: func(importID primitive.ObjectID) []*model.Transaction {
+				return []*model.Transaction{
+					{ImportID: importID, ParkingInfo: monza, CheckinDate: checkin, CheckoutDate: checkin.Add(time.Hour), Status: VALID},
+				}
+			},
+			dryRun:        true,
+			expectedTotal: 1,
+		},
+		{
+			name:          "already rolled back",
+			status:        model.ImportRolledBack,
+			transactions:  func(importID primitive.ObjectID) []*model.Transaction { return nil },
+			expectedError: true,
+		},
+		{
+			name:          "running",
+			status:        model.ImportRunning,
+			transactions:  func(importID primitive.ObjectID) []*model.Transaction { return nil },
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			record := &model.Import{ID: primitive.NewObjectID(), ParkingInfo: monza, Status: tc.status}
+			store := &memoryRollback{
+				memoryStore: &memoryStore{transactions: tc.transactions(record.ID)},
+				imports:     []*model.Import{record},
+			}
+
+			result, total, err := newRollback(store, memoryParks{}).Rollback(context.Background(), record.ID.Hex(), tc.dryRun)
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedTotal, total)
+			require.Equal(t, tc.expectedRebuilds, store.rebuilds)
+
+			if tc.dryRun {
+				require.Equal(t, model.ImportDone, result.Status)
+				for _, transaction := range store.transactions {
+					require.Nil(t, transaction.DeletedAt)
+				}
+				return
+			}
+
+			require.Equal(t, model.ImportRolledBack, result.Status)
+			require.Equal(t, tc.expectedTotal, result.RolledBack)
+			require.Equal(t, tc.expectedReopened, result.Reopened)
+			for _, transaction := range store.transactions {
+				if transaction.ImportID == record.ID {
+					require.NotNil(t, transaction.DeletedAt)
+					continue
+				}
+				// the stays the import closed are open again
+				require.Nil(t, transaction.DeletedAt)
+				require.Equal(t, OPEN, transaction.Status)
+				require.True(t, transaction.CheckoutDate.IsZero())
+				require.True(t, transaction.ClosedImportID.IsZero())
+			}
+		})
+	}
+
+	t.Run("unknown import", func(t *testing.T) {
+		_, _, err := newRollback(&memoryRollback{memoryStore: &memoryStore{}}, memoryParks{}).Rollback(context.Background(), primitive.NewObjectID().Hex(), false)
+		require.NotNil(t, err)
+	})
+}
+
+func TestRollback_RollbackOverlaps(t *testing.T) {
+	checkin := time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)
+	monza := model.Parking{ID: 6, Slug: "monza"}
+	record := &model.Import{ID: primitive.NewObjectID(), ParkingInfo: monza, Status: model.ImportDone}
+	earlier := primitive.NewObjectID()
+
+	stay := func(importID primitive.ObjectID, status int) *model.Transaction {
+		return &model.Transaction{ID: primitive.NewObjectID(), ImportID: importID, ParkingInfo: monza, Matricula: "ABC1234",
+			CheckinDate: checkin, CheckoutDate: checkin.Add(time.Hour), Status: status}
+	}
+	rolledBack := stay(record.ID, VALID)
+	alone := stay(earlier, VALID)
+	invalid := stay(earlier, INVALID)
+	twice := stay(earlier, VALID)
+	other := stay(earlier, VALID)
+
+	store := &memoryRollback{
+		memoryStore: &memoryStore{transactions: []*model.Transaction{rolledBack, alone, invalid, twice, other}},
+		imports:     []*model.Import{record},
+	}
+	for _, flagged := range []*model.Transaction{alone, invalid, twice} {
+		require.Nil(t, store.MarkOverlap(context.Background(), rolledBack.ID, flagged.ID))
+	}
+	require.Nil(t, store.MarkOverlap(context.Background(), twice.ID, other.ID))
+
+	_, total, err := newRollback(store, memoryParks{}).Rollback(context.Background(), record.ID.Hex(), false)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), total)
+
+	// the stays overlapping only the rolled back one get their status back
+	require.Equal(t, VALID, alone.Status)
+	require.Nil(t, alone.OverlapsWith)
+	require.Nil(t, alone.PriorStatus)
+	require.Equal(t, INVALID, invalid.Status)
+	require.Nil(t, invalid.OverlapsWith)
+
+	require.Equal(t, DEVIATION, twice.Status)
+	require.Equal(t, []primitive.ObjectID{other.ID}, twice.OverlapsWith)
+	require.Equal(t, DEVIATION, other.Status)
+}
+
+func TestRollback_RollbackPurged(t *testing.T) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monza := model.Parking{ID: 6, Slug: "monza"}
+	parks := memoryParks{{ID: 6, Name: "Monza", Slug: "monza", RetentionDays: 30}}
+
+	type TestRun struct {
+		name             string
+		checkouts        []time.Time
+		expectedRebuilds [][2]time.Time
+	}
+
+	tt := []TestRun{
+		{
+			name:             "kept days",
+			checkouts:        []time.Time{today.AddDate(0, 0, -10), today.AddDate(0, 0, -5)},
+			expectedRebuilds: [][2]time.Time{{today.AddDate(0, 0, -10), today.AddDate(0, 0, -5)}},
+		},
+		{
+			name:             "purged and kept days",
+			checkouts:        []time.Time{today.AddDate(0, 0, -40), today.AddDate(0, 0, -5)},
+			expectedRebuilds: [][2]time.Time{{today.AddDate(0, 0, -29), today.AddDate(0, 0, -5)}},
+		},
+		{
+			name:      "purged days",
+			checkouts: []time.Time{today.AddDate(0, 0, -40), today.AddDate(0, 0, -35)},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			record := &model.Import{ID: primitive.NewObjectID(), ParkingInfo: monza, Status: model.ImportDone}
+			store := &memoryRollback{memoryStore: &memoryStore{}, imports: []*model.Import{record}}
+			for _, checkout := range tc.checkouts {
+				store.transactions = append(store.transactions, &model.Transaction{ImportID: record.ID, ParkingInfo: monza,
+					CheckinDate: checkout.Add(-time.Hour), CheckoutDate: checkout})
+			}
+
+			_, _, err := newRollback(store, parks).Rollback(context.Background(), record.ID.Hex(), false)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedRebuilds, store.rebuilds)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package business
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Row is a row of a file split into its fields
+type Row struct {
+	Fields []string
+	// Line is the line of the file the row starts at, from 1
+	Line int64
+	// Raw is the row as written in the file, converted to UTF-8, when the
+	// source keeps it
+	Raw []byte
+	// Offset is where the next row starts, in bytes for text files, and
+	// is what a resumed import seeks to
+	Offset int64
+}
+
+// RowSource returns the rows of a file one at a time, and io.EOF after the
+// last one. A row may be returned along with the error it has
+type RowSource interface {
+	Next() (Row, error)
+}
+
+// RowError is the error of a single row, the rows after it can still be read
+type RowError struct {
+	Line int64
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// isRowError returns whether err is the error of a single row rather than
+// of the file
+func isRowError(err error) bool {
+	var rowErr *RowError
+	var parseErr *csv.ParseError
+
+	return errors.As(err, &rowErr) || errors.As(err, &parseErr)
+}
+
+// memorySource returns rows kept in memory
+type memorySource struct {
+	records [][]string
+	next    int
+}
+
+// NewMemorySource returns the source of records, numbered from line 1
+func NewMemorySource(records [][]string) RowSource {
+	return &memorySource{records: records}
+}
+
+func (s *memorySource) Next() (Row, error) {
+	if s.next >= len(s.records) {
+		return Row{}, io.EOF
+	}
+
+	s.next++
+
+	return Row{
+		Fields: s.records[s.next-1],
+		Line:   int64(s.next),
+		Offset: int64(s.next),
+	}, nil
+}
+
+// aheadRow is a row read ahead along with its error
+type aheadRow struct {
+	row Row
+	err error
+}
+
+// frameSource drops the header and footer rows of a source and checks the
+// number of fields of the others, the footer is held back by reading that
+// many rows ahead
+type frameSource struct {
+	source RowSource
+	header int
+	footer int
+	// fields is how many fields every row has, the first row sets it when
+	// 0 and rows may have any number of fields when negative
+	fields int
+
+	ahead []aheadRow
+}
+
+func newFrameSource(source RowSource, header int, footer int, fields int) *frameSource {
+	return &frameSource{
+		source: source,
+		header: header,
+		footer: footer,
+		fields: fields,
+	}
+}
+
+func (s *frameSource) Next() (Row, error) {
+	for ; s.header > 0; s.header-- {
+		_, err := s.source.Next()
+		if err != nil {
+			return Row{}, err
+		}
+	}
+
+	for len(s.ahead) <= s.footer {
+		row, err := s.source.Next()
+		if err == io.EOF {
+			// what is left ahead is the footer
+			return Row{}, io.EOF
+		}
+		s.ahead = append(s.ahead, aheadRow{row: row, err: err})
+	}
+
+	next := s.ahead[0]
+	s.ahead = s.ahead[1:]
+
+	if next.err == nil && s.fields >= 0 {
+		if s.fields == 0 {
+			s.fields = len(next.row.Fields)
+		} else if len(next.row.Fields) != s.fields {
+			next.err = &csv.ParseError{StartLine: int(next.row.Line), Line: int(next.row.Line), Err: csv.ErrFieldCount}
+		}
+	}
+
+	return next.row, next.err
+}
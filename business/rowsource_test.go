@@ -0,0 +1,82 @@
+package business
+
+import (
+	"context"
+	"testing"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFrameSource(t *testing.T) {
+	records := [][]string{{"unit", "ticket"}, {"a", "1"}, {"b", "2", "x"}, {"c", "3"}, {"total"}}
+
+	type TestRun struct {
+		name           string
+		header         int
+		footer         int
+		fields         int
+		expected       []int64
+		expectedErrors []bool
+	}
+
+	tt := []TestRun{
+		{
+			name:           "everything",
+			fields:         -1,
+			expected:       []int64{1, 2, 3, 4, 5},
+			expectedErrors: []bool{false, false, false, false, false},
+		},
+		{
+			name:           "header and footer",
+			header:         1,
+			footer:         1,
+			expected:       []int64{2, 3, 4},
+			expectedErrors: []bool{false, true, false},
+		},
+		{
+			name:           "fields given",
+			header:         1,
+			fields:         3,
+			expected:       []int64{2, 3, 4, 5},
+			expectedErrors: []bool{true, false, true, true},
+		},
+		{
+			name:     "all header",
+			header:   3,
+			footer:   2,
+			expected: []int64{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, errs := readRows(t, newFrameSource(NewMemorySource(records), tc.header, tc.footer, tc.fields))
+
+			lines := []int64{}
+			for _, row := range rows {
+				lines = append(lines, row.Line)
+			}
+			require.Equal(t, tc.expected, lines)
+
+			for i, err := range errs {
+				require.Equal(t, tc.expectedErrors[i], err != nil, "row %d: %v", i, err)
+			}
+		})
+	}
+}
+
+func TestVP_ValidateFromMemory(t *testing.T) {
+	source := NewMemorySource([][]string{
+		{"Monza", "1001", "", "ABC1234", "NORMAL", "", "01/10/2020 10:20:00", "01/10/2020 10:50:00", "", "", "12.50", "DINHEIRO", "NORMAL"},
+		{"Monza", "1002", "", "ABC1234", "NORMAL", "", "", "", "", "", "12.50", "DINHEIRO", "NORMAL"},
+		{"Monza", "1003"},
+	})
+
+	processor := NewVP(nil, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NilObjectID)
+	report, err := processor.Validate(context.Background())
+
+	require.Nil(t, err)
+	require.Equal(t, &ValidationReport{Rows: 3, Valid: 1, Skipped: 1, Invalid: 1, Errors: []string{"record 3: expected 13 fields, got 2"}}, report)
+}
@@ -0,0 +1,95 @@
+package business
+
+import (
+	"context"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store persists what the processors produce
+type Store interface {
+	CreateTransaction(ctx context.Context, transaction *model.Transaction) (*model.Transaction, error)
+	UpdateTransaction(ctx context.Context, transaction *model.Transaction) (*model.Transaction, error)
+	// GetTransactionByTicket and OverlappingTransactions match the plate
+	// stored as any of plates, hashed with each key or in clear
+	GetTransactionByTicket(ctx context.Context, ticket string, parking int64, plates []string) (*model.Transaction, error)
+	HasTransactionStatus(ctx context.Context, parking int64, status int) (bool, error)
+	OverlappingTransactions(ctx context.Context, transaction *model.Transaction, plates []string) ([]model.Transaction, error)
+	MarkOverlap(ctx context.Context, first primitive.ObjectID, second primitive.ObjectID) error
+	TransactionExists(ctx context.Context, importID primitive.ObjectID, line int64) (bool, error)
+	IncrementRevenue(ctx context.Context, transaction *model.Transaction) error
+	SaveCheckpoint(ctx context.Context, importID primitive.ObjectID, checkpoint model.Checkpoint) error
+}
+
+// mongoStore is the Store backed by the database
+type mongoStore struct {
+	dbAcess *mongo.DB
+}
+
+func (s mongoStore) CreateTransaction(ctx context.Context, transaction *model.Transaction) (*model.Transaction, error) {
+	return s.dbAcess.TransactionCollection.Create(ctx, transaction)
+}
+
+func (s mongoStore) UpdateTransaction(ctx context.Context, transaction *model.Transaction) (*model.Transaction, error) {
+	return s.dbAcess.TransactionCollection.Update(ctx, transaction)
+}
+
+func (s mongoStore) GetTransactionByTicket(ctx context.Context, ticket string, parking int64, plates []string) (*model.Transaction, error) {
+	return s.dbAcess.TransactionCollection.GetByTicketAndMatricula(ctx, ticket, parking, plates, OPEN)
+}
+
+func (s mongoStore) HasTransactionStatus(ctx context.Context, parking int64, status int) (bool, error) {
+	return s.dbAcess.TransactionCollection.ExistsByStatus(ctx, parking, status)
+}
+
+func (s mongoStore) OverlappingTransactions(ctx context.Context, transaction *model.Transaction, plates []string) ([]model.Transaction, error) {
+	return s.dbAcess.TransactionCollection.Overlapping(ctx, transaction.ParkingInfo.ID, plates,
+		transaction.CheckinDate, transaction.CheckoutDate, transaction.ID)
+}
+
+func (s mongoStore) MarkOverlap(ctx context.Context, first primitive.ObjectID, second primitive.ObjectID) error {
+	return s.dbAcess.TransactionCollection.MarkOverlap(ctx, first, second, DEVIATION)
+}
+
+func (s mongoStore) TransactionExists(ctx context.Context, importID primitive.ObjectID, line int64) (bool, error) {
+	return s.dbAcess.TransactionCollection.ExistsByImportLine(ctx, importID, line)
+}
+
+func (s mongoStore) IncrementRevenue(ctx context.Context, transaction *model.Transaction) error {
+	return s.dbAcess.DailyRevenueCollection.Increment(ctx, transaction)
+}
+
+func (s mongoStore) SaveCheckpoint(ctx context.Context, importID primitive.ObjectID, checkpoint model.Checkpoint) error {
+	return s.dbAcess.ImportCollection.SaveCheckpoint(ctx, importID, checkpoint)
+}
+
+func (s mongoStore) GetImport(ctx context.Context, id string) (*model.Import, error) {
+	return s.dbAcess.ImportCollection.GetByID(ctx, id)
+}
+
+func (s mongoStore) UpdateImport(ctx context.Context, record *model.Import) (*model.Import, error) {
+	return s.dbAcess.ImportCollection.Update(ctx, record)
+}
+
+func (s mongoStore) CountByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	return s.dbAcess.TransactionCollection.CountByImport(ctx, importID)
+}
+
+func (s mongoStore) CheckoutRangeByImport(ctx context.Context, importID primitive.ObjectID) (time.Time, time.Time, error) {
+	return s.dbAcess.TransactionCollection.CheckoutRangeByImport(ctx, importID)
+}
+
+func (s mongoStore) DeleteByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	return s.dbAcess.TransactionCollection.DeleteByImport(ctx, importID)
+}
+
+func (s mongoStore) ReopenByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	return s.dbAcess.TransactionCollection.ReopenByImport(ctx, importID, OPEN)
+}
+
+func (s mongoStore) RebuildRevenue(ctx context.Context, parking int64, from time.Time, to time.Time) (int64, error) {
+	return s.dbAcess.DailyRevenueCollection.Rebuild(ctx, parking, from, to)
+}
@@ -0,0 +1,170 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/csv-processor/mongo"
+	"go.uber.org/zap"
+)
+
+// visitReader reads the transactions of a plate
+type visitReader interface {
+	Iterate(ctx context.Context, filter model.TransactionFilter, fn func(transaction *model.Transaction) error) error
+}
+
+// ParkVisits is the count of the visits of a plate to a park
+type ParkVisits struct {
+	ParkID int64  `json:"park_id"`
+	Slug   string `json:"slug"`
+	Visits int64  `json:"visits"`
+}
+
+// PlateProfile sums up the visits of a plate across the parks. Invalid
+// transactions are not visits
+type PlateProfile struct {
+	// Plate is masked when the privacy settings say so
+	Plate      string       `json:"plate"`
+	Visits     int64        `json:"visits"`
+	Parks      []ParkVisits `json:"parks"`
+	TotalSpent float64      `json:"total_spent"`
+	// AverageStayMinutes is the average of the stays with both dates
+	AverageStayMinutes     float64 `json:"average_stay_minutes"`
+	PreferredPaymentMethod string  `json:"preferred_payment_method"`
+	// FirstSeen and LastSeen are the first and last checkin, or checkout
+	// of the lost tickets
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// TypicalArrivalHour is the hour of the day, in the time zone of the
+	// park, the plate checks in the most, missing without checkins
+	TypicalArrivalHour *int `json:"typical_arrival_hour,omitempty"`
+}
+
+type VisitHistory interface {
+	Profile(ctx context.Context, plate string) (*PlateProfile, error)
+}
+
+type visitHistoryImpl struct {
+	transactions  visitReader
+	maskPlates    bool
+	pseudonymizer *Pseudonymizer
+	logger        *zap.Logger
+}
+
+// NewVisitHistory returns the visit history of the privacy settings, which
+// tell whether the plates of the profiles are masked and how they are stored
+func NewVisitHistory(dbAcess *mongo.DB, privacy config.Privacy) VisitHistory {
+	return newVisitHistory(dbAcess.TransactionCollection, privacy.MaskPlates, NewPseudonymizer(privacy))
+}
+
+func newVisitHistory(transactions visitReader, maskPlates bool, pseudonymizer *Pseudonymizer) *visitHistoryImpl {
+	log, _ := zap.NewProduction()
+
+	return &visitHistoryImpl{
+		transactions:  transactions,
+		maskPlates:    maskPlates,
+		pseudonymizer: pseudonymizer,
+		logger:        log,
+	}
+}
+
+// Profile returns the profile of the visits of plate, which is normalized
+// first and looked up hashed with every key. A plate never seen has no
+// visits
+func (s *visitHistoryImpl) Profile(ctx context.Context, plate string) (*PlateProfile, error) {
+	plate = NormalizePlate(plate)
+	profile := &PlateProfile{Plate: plate, Parks: []ParkVisits{}}
+	if s.maskPlates {
+		profile.Plate = MaskPlate(plate)
+	}
+	if plate == "" {
+		return profile, nil
+	}
+
+	parks := map[int64]*ParkVisits{}
+	methods := map[string]int64{}
+	hours := map[int]int64{}
+	var stays int64
+	var stayed time.Duration
+
+	err := s.transactions.Iterate(ctx, model.TransactionFilter{Matriculas: s.pseudonymizer.Plates(plate)}, func(transaction *model.Transaction) error {
+		if transaction.Status == INVALID {
+			return nil
+		}
+
+		profile.Visits++
+		profile.TotalSpent += transaction.PaidAmount
+
+		park, ok := parks[transaction.ParkingInfo.ID]
+		if !ok {
+			park = &ParkVisits{ParkID: transaction.ParkingInfo.ID, Slug: transaction.ParkingInfo.Slug}
+			parks[park.ParkID] = park
+		}
+		park.Visits++
+
+		if transaction.PaymentMethod != "" {
+			methods[transaction.PaymentMethod]++
+		}
+
+		if !transaction.CheckinDate.IsZero() && !transaction.CheckoutDate.IsZero() {
+			stays++
+			stayed += transaction.CheckoutDate.Sub(transaction.CheckinDate)
+		}
+
+		seen := transaction.CheckinDate
+		if seen.IsZero() {
+			seen = transaction.CheckoutDate
+		}
+		if !seen.IsZero() && (profile.FirstSeen.IsZero() || seen.Before(profile.FirstSeen)) {
+			profile.FirstSeen = seen
+		}
+		if seen.After(profile.LastSeen) {
+			profile.LastSeen = seen
+		}
+
+		// the dates are the wall clock of the park already
+		if !transaction.CheckinDate.IsZero() {
+			hours[transaction.CheckinDate.Hour()]++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing transactions of plate [%s]: [%s]", profile.Plate, err.Error())
+	}
+
+	for _, park := range parks {
+		profile.Parks = append(profile.Parks, *park)
+	}
+	sort.Slice(profile.Parks, func(i, j int) bool {
+		return profile.Parks[i].ParkID < profile.Parks[j].ParkID
+	})
+
+	if stays > 0 {
+		profile.AverageStayMinutes = stayed.Minutes() / float64(stays)
+	}
+
+	// ties go to the first payment method by name and the earliest hour
+	var most int64
+	for method, count := range methods {
+		if count > most || (count == most && method < profile.PreferredPaymentMethod) {
+			most = count
+			profile.PreferredPaymentMethod = method
+		}
+	}
+
+	most = 0
+	for hour, count := range hours {
+		if count > most || (count == most && hour < *profile.TypicalArrivalHour) {
+			most = count
+			hour := hour
+			profile.TypicalArrivalHour = &hour
+		}
+	}
+
+	return profile, nil
+}
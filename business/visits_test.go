@@ -0,0 +1,119 @@
+package business
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryVisits reads transactions in memory, filtered by plate
+type memoryVisits []model.Transaction
+
+func (m memoryVisits) Iterate(ctx context.Context, filter model.TransactionFilter, fn func(transaction *model.Transaction) error) error {
+	for _, transaction := range m {
+		matches := false
+		for _, plate := range filter.Matriculas {
+			matches = matches || transaction.Matricula == plate
+		}
+		if !matches {
+			continue
+		}
+
+		transaction := transaction
+		err := fn(&transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestVisitHistory_Profile(t *testing.T) {
+	monza := model.Parking{ID: 6, Slug: "monza"}
+	centro := model.Parking{ID: 7, Slug: "centro"}
+
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	visit := func(parking model.Parking, plate string, checkin int, checkout int, paid float64, method string, status int) model.Transaction {
+		transaction := model.Transaction{ParkingInfo: parking, Matricula: plate, PaidAmount: paid, PaymentMethod: method, Status: status}
+		if checkin >= 0 {
+			transaction.CheckinDate = day.Add(time.Duration(checkin) * time.Hour)
+		}
+		if checkout >= 0 {
+			transaction.CheckoutDate = day.Add(time.Duration(checkout) * time.Hour)
+		}
+		return transaction
+	}
+
+	transactions := memoryVisits{
+		// the dates are the wall clock of the park, whatever its time zone
+		visit(monza, "ABC1234", 14, 16, 10, "CASH", VALID),
+		visit(monza, "ABC1234", 38, 39, 5, "CARD", VALID),
+		visit(centro, "ABC1234", 59, 63, 20, "CASH", DEVIATION),
+		// lost ticket
+		visit(centro, "ABC1234", -1, 80, 30, "CARD", LOST),
+		visit(centro, "ABC1234", 90, 91, 100, "CARD", INVALID),
+		visit(monza, "DEF4567", 10, 11, 8, "CASH", VALID),
+	}
+
+	hour := 14
+	type TestRun struct {
+		name       string
+		plate      string
+		maskPlates bool
+		expected   *PlateProfile
+	}
+
+	tt := []TestRun{
+		{
+			name:  "profile",
+			plate: "abc-1234",
+			expected: &PlateProfile{
+				Plate:  "ABC1234",
+				Visits: 4,
+				Parks: []ParkVisits{
+					{ParkID: 6, Slug: "monza", Visits: 2},
+					{ParkID: 7, Slug: "centro", Visits: 2},
+				},
+				TotalSpent:             65,
+				AverageStayMinutes:     140,
+				PreferredPaymentMethod: "CARD",
+				FirstSeen:              day.Add(14 * time.Hour),
+				LastSeen:               day.Add(80 * time.Hour),
+				TypicalArrivalHour:     &hour,
+			},
+		},
+		{
+			name:       "masked",
+			plate:      "DEF4567",
+			maskPlates: true,
+			expected: &PlateProfile{
+				Plate:                  "DEF**67",
+				Visits:                 1,
+				Parks:                  []ParkVisits{{ParkID: 6, Slug: "monza", Visits: 1}},
+				TotalSpent:             8,
+				AverageStayMinutes:     60,
+				PreferredPaymentMethod: "CASH",
+				FirstSeen:              day.Add(10 * time.Hour),
+				LastSeen:               day.Add(10 * time.Hour),
+				TypicalArrivalHour:     func() *int { hour := 10; return &hour }(),
+			},
+		},
+		{
+			name:     "never seen",
+			plate:    "GHI7890",
+			expected: &PlateProfile{Plate: "GHI7890", Parks: []ParkVisits{}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			profile, err := newVisitHistory(transactions, tc.maskPlates, nil).Profile(context.Background(), tc.plate)
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, profile)
+		})
+	}
+}
@@ -2,7 +2,6 @@ package business
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"io"
 	"strconv"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/csv-processor/model"
 	"github.com/csv-processor/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
@@ -18,186 +18,639 @@ const (
 	VALID = iota
 	DEVIATION
 	INVALID
+	// OPEN is a stay without a checkout yet, closed by the row of a later
+	// import with the checkout of its ticket and plate
+	OPEN
+	// LOST is a stay without a checkin, whose ticket was lost
+	LOST
 )
 
+// transactionColumns names the fields of the rows of transactions files,
+// in order, which is how the fields of JSON objects are found
+var transactionColumns = []string{
+	"unit", "ticket", "identity", "plate", "use_type", "agreement", "checkin",
+	"checkout", "duration", "fare", "paid", "payment_method", "table",
+}
+
 type VP interface {
-	Process(ctx context.Context) error
+	Process(ctx context.Context) (*model.ImportSummary, error)
+	Validate(ctx context.Context) (*ValidationReport, error)
+}
+
+// ValidationReport summarizes the rows of a validated file
+type ValidationReport struct {
+	Rows    int64
+	Valid   int64
+	Skipped int64
+	Invalid int64
+	// Errors holds the first errors found, prefixed by the record number
+	Errors []string
+}
+
+const maxValidationErrors = 100
+
+func (r *ValidationReport) invalid(err error) {
+	r.Invalid++
+	if len(r.Errors) < maxValidationErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("record %d: %s", r.Rows, err.Error()))
+	}
 }
 
+// defaultCheckpointEvery is how many persisted rows apart checkpoints are saved
+const defaultCheckpointEvery = 1000
+
 type vpImpl struct {
-	dbAcess  *mongo.DB
-	reader   *csv.Reader
+	store    Store
+	source   RowSource
 	filetype string
 	parking  model.Parking
-	cn       chan *model.Line
+	bucket   Bucketing
+	importID primitive.ObjectID
 	logger   *zap.Logger
+	// rejects receives the rows that could not be read or converted
+	rejects *RejectsWriter
+
+	// start is where a resumed import continues from, the reader is
+	// already positioned after it
+	start           model.Checkpoint
+	resumed         bool
+	checkpointEvery int64
+
+	// pseudonymizer hashes the plates and identities before they are
+	// persisted, nil keeps them in clear
+	pseudonymizer *Pseudonymizer
+
+	// hasOpen tells whether the park may have open stays to close, which
+	// is looked up once
+	hasOpen     bool
+	openChecked bool
+}
+
+// NewVP returns the processor of a file of parking, stamping every
+// transaction with importID
+func NewVP(dbAcess *mongo.DB, source RowSource, filetype string, parking model.Parking, bucket Bucketing, importID primitive.ObjectID) VP {
+	return newVP(mongoStore{dbAcess: dbAcess}, source, filetype, parking, bucket, importID)
 }
 
-func NewVP(dbAcess *mongo.DB, reader *csv.Reader, filetype string, parking model.Parking) VP {
+func newVP(store Store, source RowSource, filetype string, parking model.Parking, bucket Bucketing, importID primitive.ObjectID) *vpImpl {
 	log, _ := zap.NewProduction()
 
 	service := &vpImpl{
-		dbAcess:  dbAcess,
-		reader:   reader,
+		store:    store,
+		source:   source,
 		filetype: filetype,
 		parking:  parking,
-		cn:       make(chan *model.Line),
+		bucket:   bucket,
+		importID: importID,
 		logger:   log,
-	}
 
-	go service.processLine()
+		checkpointEvery: defaultCheckpointEvery,
+	}
 
 	return service
 }
 
-func (s *vpImpl) Process(ctx context.Context) error {
+// Process reads and persists every row, returning once all of them were handled
+func (s *vpImpl) Process(ctx context.Context) (*model.ImportSummary, error) {
 	switch s.filetype {
 	case "transactions":
 		return s.transactionsProcess(ctx)
 	default:
-		return fmt.Errorf("filetype [%s] does not exists for parking", s.filetype)
+		return nil, fmt.Errorf("filetype [%s] does not exists for parking", s.filetype)
 	}
 }
 
-func (s *vpImpl) transactionsProcess(ctx context.Context) error {
+// pendingLine is a line waiting to be persisted along with where the
+// reader was when it was read
+type pendingLine struct {
+	line     *model.Line
+	position model.Checkpoint
+}
+
+func (s *vpImpl) transactionsProcess(ctx context.Context) (*model.ImportSummary, error) {
+	position := s.start
+
+	cn := make(chan pendingLine)
+	done := make(chan model.Checkpoint)
+	go func() {
+		done <- s.processLine(ctx, cn)
+	}()
+
+	// stop waits for the pending lines and returns the last one handled
+	stop := func() model.Checkpoint {
+		close(cn)
+		return <-done
+	}
 
 	for {
-		line, err := s.reader.Read()
+		row, err := s.source.Next()
+
+		if err != nil && isRowError(err) {
+			position.Line++
+			position.Summary.Rows++
+			position.Offset = row.Offset
+			position.Summary.Rejected++
+			s.reject(row, err)
+			continue
+		}
 
 		if err != nil {
-			if err == io.EOF {
-				s.logger.Info("Done")
-				return nil
-			} else {
-				return err
+			persisted := stop()
+			// lines read after a cancel were not persisted
+			if err == io.EOF && ctx.Err() != nil {
+				err = ctx.Err()
 			}
+			if err != io.EOF {
+				s.checkpoint(persisted)
+				return &persisted.Summary, err
+			}
+
+			setPersisted(&position.Summary, persisted.Summary)
+			s.checkpoint(position)
+
+			s.logger.Info("Done")
+			return &position.Summary, nil
 		}
 
-		if line[6] == "" || line[7] == "" {
+		position.Line++
+		position.Summary.Rows++
+		position.Offset = row.Offset
+
+		data, skip, err := checkLine(row.Fields)
+		if skip {
+			position.Summary.Skipped++
+			continue
+		}
+		if err != nil {
+			position.Summary.Rejected++
+			s.reject(row, err)
 			continue
 		}
+		data.Number = position.Line
+
+		select {
+		case cn <- pendingLine{line: data, position: position}:
+		case <-ctx.Done():
+			persisted := stop()
+			s.checkpoint(persisted)
+			return &persisted.Summary, ctx.Err()
+		}
+	}
+}
 
-		cin, parsed := parseDate(line[6])
-		if !parsed {
-			fmt.Println(line[6])
-			panic(line[6])
+// checkpoint saves how far the import went, even when the import was
+// canceled, imports without an ID are not checkpointed
+func (s *vpImpl) checkpoint(position model.Checkpoint) {
+	if s.importID.IsZero() {
+		return
+	}
+
+	err := s.store.SaveCheckpoint(context.Background(), s.importID, position)
+	if err != nil {
+		s.logger.Info(err.Error())
+	}
+}
+
+// Validate reads every row like Process would, without persisting, and
+// reports the rows that would be skipped or would fail
+func (s *vpImpl) Validate(ctx context.Context) (*ValidationReport, error) {
+	if s.filetype != "transactions" {
+		return nil, fmt.Errorf("filetype [%s] does not exists for parking", s.filetype)
+	}
+
+	report := &ValidationReport{}
+	for {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
 		}
 
-		cout, parsed := parseDate(line[7])
-		if !parsed {
-			fmt.Println(line[7])
-			panic(line[7])
+		row, err := s.source.Next()
+		if err == io.EOF {
+			return report, nil
+		}
+		// the source can't go on past errors other than those of a row
+		if err != nil && !isRowError(err) {
+			return report, err
 		}
 
-		paid, _ := strconv.ParseFloat(line[10], 64)
+		report.Rows++
+		if err != nil {
+			report.invalid(err)
+			continue
+		}
 
-		data := &model.Line{
-			Unit:          line[0],
-			Ticket:        line[1],
-			Identity:      line[2],
-			Matricula:     line[3],
-			UseType:       line[4],
-			CheckIn:       cin,
-			CheckOut:      cout,
-			Duration:      int64(cout.Sub(cin).Minutes()),
-			PaidValue:     paid,
-			PaymentMethod: line[11],
-			Table:         line[12],
+		_, skip, err := checkLine(row.Fields)
+		if skip {
+			report.Skipped++
+			continue
 		}
+		if err != nil {
+			report.invalid(err)
+			continue
+		}
+
+		report.Valid++
+	}
+}
+
+// checkLine converts a transactions row into a line, checking its table and
+// payment method are known. Rows without any date are skipped
+func checkLine(fields []string) (*model.Line, bool, error) {
+	if len(fields) > 7 && fields[6] == "" && fields[7] == "" {
+		return nil, true, nil
+	}
+
+	data, err := parseLine(fields)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, false, checkCodes(data)
+}
+
+// checkCodes checks the table and payment method of line are known, the
+// payment method of a stay without checkout may be missing
+func checkCodes(line *model.Line) error {
+	if _, ok := lookupUseType(line.Table); !ok {
+		return fmt.Errorf("unknown table [%s]", line.Table)
+	}
+
+	if line.CheckOut.IsZero() && line.PaymentMethod == "" {
+		return nil
+	}
+
+	if _, ok := lookupPaymentMethod(line.PaymentMethod); !ok {
+		return fmt.Errorf("unknown payment method [%s]", line.PaymentMethod)
+	}
+
+	return nil
+}
 
-		s.cn <- data
+// reject writes a rejected row to the rejects, when kept
+func (s *vpImpl) reject(row Row, reason error) {
+	s.logger.Sugar().Infow("rejected", "line", row.Line, "error", reason.Error())
+
+	if s.rejects == nil {
+		return
+	}
+
+	err := s.rejects.Write(row, reason)
+	if err != nil {
+		s.logger.Info(err.Error())
 	}
 }
 
-func (s *vpImpl) processLine() {
+// processLine persists the lines sent to cn until it is closed or ctx is
+// canceled, returning the position of the last line handled
+func (s *vpImpl) processLine(ctx context.Context, cn <-chan pendingLine) model.Checkpoint {
 	s.logger.Info("Starting process line")
-	for {
-		line := <-s.cn
+
+	last := s.start
+	summary := last.Summary
+
+	// a resumed import may have persisted up to a checkpoint worth of lines
+	// after its checkpoint, those are looked up and skipped when found
+	catchUp := int64(0)
+	if s.resumed {
+		catchUp = s.checkpointEvery
+	}
+	persisted := int64(0)
+
+	for pending := range cn {
+		line := pending.line
+
+		if ctx.Err() != nil {
+			continue
+		}
+
+		if catchUp > 0 {
+			catchUp--
+			exists, err := s.store.TransactionExists(ctx, s.importID, line.Number)
+			if err != nil {
+				s.logger.Info(err.Error())
+			}
+			if exists {
+				summary.Inserted++
+				last = pending.position
+				setPersisted(&last.Summary, summary)
+				continue
+			}
+		}
 
 		s.logger.Info("processing...")
 
-		ci := line.CheckIn
-		co := line.CheckOut
+		transaction := newTransaction(line, s.parking, s.bucket, s.importID)
+		plates := s.pseudonymizer.Plates(transaction.Matricula)
+		s.pseudonymizer.Apply(transaction)
+		err := s.persist(ctx, transaction, plates, &summary)
+		if err != nil {
+			s.logger.Info(err.Error())
+			summary.Failed++
+		}
 
-		transaction := &model.Transaction{
-			CheckinDate:   ci,
-			CheckoutDate:  co,
-			Sequence:      line.Ticket,
-			FareAmount:    line.PaidValue,
-			PaidAmount:    line.PaidValue,
-			Matricula:     line.Matricula,
-			IsValid:       true,
-			UseType:       getUseType(line.Table),
-			OfferType:     "On-demand",
-			PaymentMethod: getPaymentMethod(line.PaymentMethod),
-			ParkingInfo:   s.parking,
+		last = pending.position
+		setPersisted(&last.Summary, summary)
+
+		persisted++
+		if persisted%s.checkpointEvery == 0 {
+			s.checkpoint(last)
 		}
+	}
 
-		transaction.TimeIntervalHour = []time.Time{}
-		transaction.Duration = 0
+	return last
+}
 
-		co = time.Date(co.Year(), co.Month(), co.Day(), co.Hour(), 0, 0, 0, co.Location())
+// setPersisted sets the counts of what was persisted of from on to, the
+// other counts are kept by the reader
+func setPersisted(to *model.ImportSummary, from model.ImportSummary) {
+	to.Inserted, to.Failed = from.Inserted, from.Failed
+	to.Open, to.Closed = from.Open, from.Closed
+	to.Overlapping = from.Overlapping
+}
 
-		if ci.Minute() > 0 || ci.Second() > 0 {
-			ci = time.Date(ci.Year(), ci.Month(), ci.Day(), ci.Hour()+1, 0, 0, 0, ci.Location())
+// persist stores the transaction of a line, counting it on summary. A stay
+// without checkout is stored open unless its ticket and plate already are,
+// a stay with a checkout closes the open stay of its ticket and plate, when
+// there is one, and is created otherwise. Stays with both dates are checked
+// against the other stays of their plate. The stored stays match on any of
+// plates, the values the plate may be stored as
+func (s *vpImpl) persist(ctx context.Context, transaction *model.Transaction, plates []string, summary *model.ImportSummary) error {
+	if transaction.Status != OPEN && !s.mayHaveOpen(ctx) {
+		saved, err := saveTransaction(ctx, s.store, s.logger, transaction)
+		if err == nil {
+			summary.Inserted++
+			s.checkOverlaps(ctx, saved, plates, summary)
 		}
+		return err
+	}
 
-		for {
-			if ci.After(co) {
-				break
-			}
+	found, err := s.store.GetTransactionByTicket(ctx, transaction.Sequence, transaction.ParkingInfo.ID, plates)
+	if err != nil {
+		return err
+	}
 
-			transaction.Duration = transaction.Duration + 1
-			transaction.TimeIntervalHour = append(transaction.TimeIntervalHour, ci)
-			ci = ci.Add(1 * time.Hour)
+	if transaction.Status == OPEN {
+		summary.Open++
+		if !found.ID.IsZero() {
+			return nil
 		}
 
-		s.logger.Sugar().Infow("pre insert", "transaction", transaction)
-		transaction, err := s.dbAcess.TransactionCollection.Create(context.Background(), transaction)
-		if err != nil {
-			s.logger.Info(err.Error())
+		_, err = saveTransaction(ctx, s.store, s.logger, transaction)
+		if err == nil {
+			summary.Inserted++
+			s.hasOpen = true
+		}
+		return err
+	}
+
+	if found.ID.IsZero() || found.Status != OPEN {
+		saved, err := saveTransaction(ctx, s.store, s.logger, transaction)
+		if err == nil {
+			summary.Inserted++
+			s.checkOverlaps(ctx, saved, plates, summary)
 		}
-		s.logger.Sugar().Infow("postinsert", "transaction", transaction)
+		return err
+	}
+
+	closed, err := closeStay(ctx, s.store, s.logger, s.bucket, found, transaction)
+	if err == nil {
+		summary.Closed++
+		s.checkOverlaps(ctx, closed, plates, summary)
+	}
+	return err
+}
+
+// checkOverlaps flags the stored transaction and the stays it overlaps,
+// counting it on summary when it overlaps any. A failed check is only
+// logged, the transaction is stored already
+func (s *vpImpl) checkOverlaps(ctx context.Context, transaction *model.Transaction, plates []string, summary *model.ImportSummary) {
+	overlaps, err := flagOverlaps(ctx, s.store, transaction, plates)
+	if err != nil {
+		s.logger.Info(err.Error())
+		return
 	}
+
+	if overlaps > 0 {
+		summary.Overlapping++
+	}
+}
+
+// mayHaveOpen returns whether the park may have open stays, looking it up
+// the first time
+func (s *vpImpl) mayHaveOpen(ctx context.Context) bool {
+	if s.openChecked {
+		return s.hasOpen
+	}
+
+	hasOpen, err := s.store.HasTransactionStatus(ctx, s.parking.ID, OPEN)
+	if err != nil {
+		s.logger.Info(err.Error())
+		return true
+	}
+
+	s.hasOpen, s.openChecked = hasOpen, true
+	return hasOpen
+}
+
+// closeStay closes the open stay with the checkout of transaction, keeping
+// the import that opened it and recording the one closing it, and adds it
+// to the daily revenue of its park
+func closeStay(ctx context.Context, store Store, logger *zap.Logger, bucket Bucketing, open *model.Transaction, transaction *model.Transaction) (*model.Transaction, error) {
+	closed := *transaction
+	closed.ID = open.ID
+	closed.Status = VALID
+	closed.ImportID, closed.ImportLine = open.ImportID, open.ImportLine
+	closed.ClosedImportID, closed.ClosedImportLine = transaction.ImportID, transaction.ImportLine
+	closed.Version, closed.Schema, closed.CreatedAt = open.Version, open.Schema, open.CreatedAt
+
+	// a lost ticket may hold the checkout of a stay whose checkin is known
+	if closed.CheckinDate.IsZero() {
+		closed.CheckinDate = open.CheckinDate
+	}
+	closed.Duration = int64(closed.CheckoutDate.Sub(closed.CheckinDate).Minutes())
+	closed.TimeIntervalHour, closed.Buckets = bucket.Buckets(closed.CheckinDate, closed.CheckoutDate)
+
+	updated, err := store.UpdateTransaction(ctx, &closed)
+	if err != nil {
+		return nil, err
+	}
+	logger.Sugar().Infow("closed", "transaction", updated.ID.Hex())
+
+	err = store.IncrementRevenue(ctx, updated)
+	if err != nil {
+		logger.Info(err.Error())
+	}
+
+	return updated, nil
+}
+
+// newTransaction converts a checked line of parking into its transaction
+func newTransaction(line *model.Line, parking model.Parking, bucket Bucketing, importID primitive.ObjectID) *model.Transaction {
+	ci := line.CheckIn
+	co := line.CheckOut
+
+	transaction := &model.Transaction{
+		CheckinDate:  ci,
+		CheckoutDate: co,
+		Sequence:     line.Ticket,
+		FareAmount:   line.PaidValue,
+		PaidAmount:   line.PaidValue,
+		IsValid:      true,
+		UseType:      getUseType(line.Table),
+		OfferType:    "On-demand",
+		ParkingInfo:  parking,
+		ImportID:     importID,
+		ImportLine:   line.Number,
+		Identity:     line.Identity,
+		Unit:         line.Unit,
+	}
+	if line.PaymentMethod != "" {
+		transaction.PaymentMethod = getPaymentMethod(line.PaymentMethod)
+	}
+	setPlate(transaction, line.Matricula)
+
+	// the hours of a stay are known once both its dates are
+	switch {
+	case co.IsZero():
+		transaction.Status = OPEN
+	case ci.IsZero():
+		transaction.Status = LOST
+	default:
+		transaction.Duration = line.Duration
+		transaction.TimeIntervalHour, transaction.Buckets = bucket.Buckets(ci, co)
+	}
+
+	return transaction
+}
+
+// saveTransaction persists a transaction and adds it to the daily revenue
+// of its park, once it has a checkout. A revenue that failed to update is
+// only logged
+func saveTransaction(ctx context.Context, store Store, logger *zap.Logger, transaction *model.Transaction) (*model.Transaction, error) {
+	logger.Sugar().Infow("pre insert", "transaction", transaction)
+	transaction, err := store.CreateTransaction(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	logger.Sugar().Infow("postinsert", "transaction", transaction)
+
+	if transaction.Status == OPEN {
+		return transaction, nil
+	}
+
+	err = store.IncrementRevenue(ctx, transaction)
+	if err != nil {
+		logger.Info(err.Error())
+	}
+
+	return transaction, nil
 }
 
 func getUseType(value string) string {
+	useType, ok := lookupUseType(value)
+	if !ok {
+		panic(value)
+	}
+
+	return useType
+}
+
+func lookupUseType(value string) (string, bool) {
 	if value == "MENSALISTA" {
-		return "Mensalista"
+		return "Mensalista", true
 	}
 
 	if strings.Contains(value, "NORMAL") ||
 		strings.Contains(value, "Rotativo") ||
 		strings.Contains(value, "SELO 1 HORA") {
-		return "Avulso"
+		return "Avulso", true
 	}
 
-	panic(value)
+	return "", false
+}
+
+// paymentMethods maps the operator payment codes to our payment methods
+var paymentMethods = map[string]string{
+	"CREDITO":       "Creditcard",
+	"DEBITO":        "Debitcard",
+	"DINHEIRO":      "Dinheiro",
+	"CA":            "Cancelado",
+	"CONECT CAR":    "ConectCar",
+	"SEMPARAR":      "SemParar",
+	"VELOE":         "Veloe",
+	"TRANSFERENCIA": "Transferência",
+	"N/I":           "N/I",
 }
 
 func getPaymentMethod(value string) string {
-	switch value {
-	case "CREDITO":
-		return "Creditcard"
-	case "DEBITO":
-		return "Debitcard"
-	case "DINHEIRO":
-		return "Dinheiro"
-	case "CA":
-		return "Cancelado"
-	case "CONECT CAR":
-		return "ConectCar"
-	case "SEMPARAR":
-		return "SemParar"
-	case "VELOE":
-		return "Veloe"
-	case "TRANSFERENCIA":
-		return "Transferência"
-	case "N/I":
-		return "N/I"
-	default:
+	method, ok := lookupPaymentMethod(value)
+	if !ok {
 		panic(value)
 	}
+
+	return method
+}
+
+// accents folds the accented letters of the operator codes, which some
+// systems export accented and others not
+var accents = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A",
+	"É", "E", "Ê", "E",
+	"Í", "I",
+	"Ó", "O", "Ô", "O", "Õ", "O",
+	"Ú", "U",
+	"Ç", "C",
+)
+
+func lookupPaymentMethod(value string) (string, bool) {
+	method, ok := paymentMethods[accents.Replace(value)]
+	return method, ok
+}
+
+// parseLine converts a transactions row into a line
+func parseLine(line []string) (*model.Line, error) {
+	if len(line) < 13 {
+		return nil, fmt.Errorf("expected 13 fields, got %d", len(line))
+	}
+
+	// a missing date is left zero
+	cin, cout := time.Time{}, time.Time{}
+	parsed := true
+
+	if line[6] != "" {
+		cin, parsed = parseDate(line[6])
+		if !parsed {
+			return nil, fmt.Errorf("invalid checkin [%s]", line[6])
+		}
+	}
+
+	if line[7] != "" {
+		cout, parsed = parseDate(line[7])
+		if !parsed {
+			return nil, fmt.Errorf("invalid checkout [%s]", line[7])
+		}
+	}
+
+	duration := int64(0)
+	if !cin.IsZero() && !cout.IsZero() {
+		duration = int64(cout.Sub(cin).Minutes())
+	}
+
+	paid, _ := strconv.ParseFloat(line[10], 64)
+
+	return &model.Line{
+		Unit:          line[0],
+		Ticket:        line[1],
+		Identity:      line[2],
+		Matricula:     line[3],
+		UseType:       line[4],
+		CheckIn:       cin,
+		CheckOut:      cout,
+		Duration:      duration,
+		PaidValue:     paid,
+		PaymentMethod: line[11],
+		Table:         line[12],
+	}, nil
 }
 
 func parseDate(dt string) (date time.Time, parsed bool) {
@@ -0,0 +1,492 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseLine(t *testing.T) {
+	type TestRun struct {
+		name          string
+		line          []string
+		expected      *model.Line
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name: "success",
+			line: []string{"Monza", "1001", "", "ABC1234", "NORMAL", "", "01/10/2020 10:20:00", "01/10/2020 10:50:00", "", "", "12.50", "DINHEIRO", "NORMAL"},
+			expected: &model.Line{
+				Unit:          "Monza",
+				Ticket:        "1001",
+				Matricula:     "ABC1234",
+				UseType:       "NORMAL",
+				CheckIn:       time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC),
+				CheckOut:      time.Date(2020, 10, 1, 10, 50, 0, 0, time.UTC),
+				Duration:      30,
+				PaidValue:     12.5,
+				PaymentMethod: "DINHEIRO",
+				Table:         "NORMAL",
+			},
+		},
+		{
+			name:          "missing fields",
+			line:          []string{"Monza", "1001"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid checkin",
+			line:          []string{"Monza", "1001", "", "ABC1234", "NORMAL", "", "2020-10-01", "01/10/2020 10:50:00", "", "", "12.50", "DINHEIRO", "NORMAL"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseLine(tc.line)
+
+			if tc.expectedError {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestVP_Validate(t *testing.T) {
+	file, err := os.Open("testdata/transactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	source, err := NewCSVSource(file, config.Dialect{})
+	require.Nil(t, err)
+
+	processor := NewVP(nil, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NilObjectID)
+	report, err := processor.Validate(context.Background())
+
+	require.Nil(t, err)
+	require.Equal(t, int64(5), report.Rows)
+	require.Equal(t, int64(3), report.Valid)
+	require.Equal(t, int64(0), report.Skipped)
+	require.Equal(t, int64(2), report.Invalid)
+	require.Equal(t, []string{
+		"record 4: invalid checkin [2020-10-01 11:00]",
+		"record 5: unknown payment method [PIX]",
+	}, report.Errors)
+}
+
+func TestVP_ValidateTruncated(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/transactions.csv")
+	require.Nil(t, err)
+	compressed := gzipped(t, string(content))
+	dir := tempDir(t, map[string]string{"truncated.csv.gz": compressed[:len(compressed)/2]})
+
+	inputs, err := Inputs(filepath.Join(dir, "truncated.csv.gz"), nil)
+	require.Nil(t, err)
+	source, in, err := openRows(inputs[0], model.Checkpoint{}, config.Dialect{})
+	require.Nil(t, err)
+	defer in.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	processor := NewVP(nil, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NilObjectID)
+	_, err = processor.Validate(ctx)
+
+	require.NotNil(t, err)
+	require.NotEqual(t, context.DeadlineExceeded, err)
+}
+
+func TestVP_ProcessRejects(t *testing.T) {
+	file, err := os.Open("testdata/transactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	source, err := NewCSVSource(file, config.Dialect{})
+	require.Nil(t, err)
+
+	store := &memoryStore{}
+	rejects := &bytes.Buffer{}
+	processor := newVP(store, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NewObjectID())
+	processor.rejects = NewRejectsWriter(rejects)
+
+	summary, err := processor.Process(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, model.ImportSummary{Rows: 5, Inserted: 3, Rejected: 2, Open: 1}, *summary)
+	require.Equal(t, "line,error,row\n"+
+		"4,invalid checkin [2020-10-01 11:00],\"Monza,1004,,GHI8901,NORMAL,,2020-10-01 11:00,01/10/2020 12:00:00,,,5.00,CREDITO,NORMAL\"\n"+
+		"5,unknown payment method [PIX],\"Monza,1005,,JKL2345,NORMAL,,01/10/2020 11:00:00,01/10/2020 12:00:00,,,5.00,PIX,NORMAL\"\n",
+		rejects.String())
+}
+
+func TestVP_ProcessOpenStays(t *testing.T) {
+	row := func(ticket string, plate string, checkin string, checkout string, method string) []string {
+		return []string{"Monza", ticket, "", plate, "NORMAL", "", checkin, checkout, "", "", "5.00", method, "NORMAL"}
+	}
+	parking := model.Parking{ID: 6, Slug: "monza"}
+	store := &memoryStore{}
+
+	first := NewMemorySource([][]string{
+		row("2001", "ABC1234", "01/10/2020 22:00:00", "", ""),
+		row("2002", "DEF4567", "01/10/2020 10:00:00", "01/10/2020 11:00:00", "DINHEIRO"),
+		row("2003", "GHI8901", "01/10/2020 23:00:00", "", ""),
+		row("2004", "JKL2345", "01/10/2020 23:30:00", "", ""),
+		row("2005", "MNO6789", "", "01/10/2020 23:40:00", "DINHEIRO"),
+	})
+	firstID := primitive.NewObjectID()
+	summary, err := newVP(store, first, "transactions", parking, HoursTouched{}, firstID).Process(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, model.ImportSummary{Rows: 5, Inserted: 5, Open: 3}, *summary)
+	require.Equal(t, OPEN, store.transactions[0].Status)
+	require.Equal(t, []time.Time(nil), store.transactions[0].TimeIntervalHour)
+	require.Equal(t, LOST, store.transactions[4].Status)
+
+	second := NewMemorySource([][]string{
+		row("2001", "ABC1234", "01/10/2020 22:00:00", "02/10/2020 00:30:00", "CREDITO"),
+		row("2003", "GHI8901", "", "02/10/2020 01:00:00", "DINHEIRO"),
+		row("2004", "JKL2345", "01/10/2020 23:30:00", "", ""),
+		row("2006", "PQR1234", "02/10/2020 08:00:00", "02/10/2020 09:00:00", "DINHEIRO"),
+	})
+	secondID := primitive.NewObjectID()
+	summary, err = newVP(store, second, "transactions", parking, HoursTouched{}, secondID).Process(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, model.ImportSummary{Rows: 4, Inserted: 1, Open: 1, Closed: 2}, *summary)
+	require.Equal(t, 6, len(store.transactions))
+
+	closed := store.transactions[0]
+	require.Equal(t, VALID, closed.Status)
+	require.Equal(t, "Creditcard", closed.PaymentMethod)
+	require.Equal(t, int64(150), closed.Duration)
+	require.Equal(t, firstID, closed.ImportID)
+	require.Equal(t, int64(1), closed.ImportLine)
+	require.Equal(t, secondID, closed.ClosedImportID)
+	require.Equal(t, int64(1), closed.ClosedImportLine)
+	require.Equal(t, 3, len(closed.TimeIntervalHour))
+
+	lost := store.transactions[2]
+	require.Equal(t, VALID, lost.Status)
+	require.Equal(t, time.Date(2020, 10, 1, 23, 0, 0, 0, time.UTC), lost.CheckinDate)
+	require.Equal(t, int64(120), lost.Duration)
+
+	require.Equal(t, OPEN, store.transactions[3].Status)
+}
+
+func TestVP_ProcessRecycledTicket(t *testing.T) {
+	parking := model.Parking{ID: 6, Slug: "monza"}
+	checkin := time.Date(2020, 10, 1, 22, 0, 0, 0, time.UTC)
+	earlier := &model.Transaction{ID: primitive.NewObjectID(), ParkingInfo: parking, Sequence: "3001", Matricula: "ABC1234",
+		CheckinDate: checkin.AddDate(0, -1, 0), CheckoutDate: checkin.AddDate(0, -1, 0).Add(time.Hour), Status: VALID}
+	open := &model.Transaction{ID: primitive.NewObjectID(), ParkingInfo: parking, Sequence: "3001", Matricula: "ABC1234",
+		CheckinDate: checkin, Status: OPEN}
+	store := &memoryStore{transactions: []*model.Transaction{earlier, open}}
+
+	source := NewMemorySource([][]string{
+		{"Monza", "3001", "", "ABC1234", "NORMAL", "", "01/10/2020 22:00:00", "02/10/2020 00:30:00", "", "", "5.00", "DINHEIRO", "NORMAL"},
+	})
+	summary, err := newVP(store, source, "transactions", parking, HoursTouched{}, primitive.NewObjectID()).Process(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, model.ImportSummary{Rows: 1, Closed: 1}, *summary)
+	require.Equal(t, 2, len(store.transactions))
+
+	// the open stay is closed, not the earlier stay of the ticket
+	require.Equal(t, VALID, store.transactions[1].Status)
+	require.Equal(t, time.Date(2020, 10, 2, 0, 30, 0, 0, time.UTC), store.transactions[1].CheckoutDate)
+	require.Equal(t, checkin.AddDate(0, -1, 0).Add(time.Hour), store.transactions[0].CheckoutDate)
+}
+
+func TestVP_ProcessOverlaps(t *testing.T) {
+	row := func(ticket string, plate string, checkin string, checkout string) []string {
+		return []string{"Monza", ticket, "", plate, "NORMAL", "", checkin, checkout, "", "", "5.00", "DINHEIRO", "NORMAL"}
+	}
+	store := &memoryStore{}
+
+	source := NewMemorySource([][]string{
+		row("3001", "ABC1234", "01/10/2020 10:00:00", "01/10/2020 12:00:00"),
+		row("3002", "ABC1234", "01/10/2020 11:00:00", "01/10/2020 11:30:00"),
+		row("3003", "ABC1234", "01/10/2020 12:00:00", "01/10/2020 13:00:00"),
+		row("3004", "DEF4567", "01/10/2020 10:00:00", "01/10/2020 12:00:00"),
+		row("3005", "", "01/10/2020 10:00:00", "01/10/2020 12:00:00"),
+		row("3006", "", "01/10/2020 10:00:00", "01/10/2020 12:00:00"),
+	})
+	summary, err := newVP(store, source, "transactions", model.Parking{ID: 6}, HoursTouched{}, primitive.NewObjectID()).Process(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, model.ImportSummary{Rows: 6, Inserted: 6, Overlapping: 1}, *summary)
+
+	first, second := store.transactions[0], store.transactions[1]
+	require.Equal(t, DEVIATION, first.Status)
+	require.Equal(t, []primitive.ObjectID{second.ID}, first.OverlapsWith)
+	require.Equal(t, DEVIATION, second.Status)
+	require.Equal(t, []primitive.ObjectID{first.ID}, second.OverlapsWith)
+
+	// stays touching at checkout, of other plates or without one do not overlap
+	for _, transaction := range store.transactions[2:] {
+		require.Equal(t, VALID, transaction.Status, transaction.Sequence)
+	}
+}
+
+// memoryStore keeps what a processor persists in memory and can simulate
+// the process dying after a number of inserts
+type memoryStore struct {
+	mu           sync.Mutex
+	transactions []*model.Transaction
+	checkpoints  []model.Checkpoint
+
+	crashAfter int
+	crash      context.CancelFunc
+	dead       bool
+}
+
+var errCrashed = fmt.Errorf("crashed")
+
+// hasPlate returns whether plate is one of plates
+func hasPlate(plates []string, plate string) bool {
+	for _, p := range plates {
+		if p == plate {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *memoryStore) CreateTransaction(ctx context.Context, transaction *model.Transaction) (*model.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dead {
+		return nil, errCrashed
+	}
+
+	if transaction.ID.IsZero() {
+		transaction.ID = primitive.NewObjectID()
+	}
+	// the store keeps its own copy, like a database
+	stored := *transaction
+	m.transactions = append(m.transactions, &stored)
+	if m.crashAfter > 0 && len(m.transactions) == m.crashAfter {
+		m.dead = true
+		m.crash()
+	}
+
+	return transaction, nil
+}
+
+func (m *memoryStore) UpdateTransaction(ctx context.Context, transaction *model.Transaction) (*model.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, stored := range m.transactions {
+		if stored.ID == transaction.ID {
+			updated := *transaction
+			m.transactions[i] = &updated
+			return transaction, nil
+		}
+	}
+
+	return nil, fmt.Errorf("transaction [%s] not found", transaction.ID.Hex())
+}
+
+func (m *memoryStore) GetTransactionByTicket(ctx context.Context, ticket string, parking int64, plates []string) (*model.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the open stay first, then the one checking in last
+	var found *model.Transaction
+	for _, transaction := range m.transactions {
+		if transaction.Sequence != ticket || transaction.ParkingInfo.ID != parking || !hasPlate(plates, transaction.Matricula) {
+			continue
+		}
+		if found == nil || (transaction.Status == OPEN) != (found.Status == OPEN) && transaction.Status == OPEN ||
+			(transaction.Status == OPEN) == (found.Status == OPEN) && transaction.CheckinDate.After(found.CheckinDate) {
+			found = transaction
+		}
+	}
+	if found == nil {
+		return &model.Transaction{}, nil
+	}
+
+	return found, nil
+}
+
+func (m *memoryStore) HasTransactionStatus(ctx context.Context, parking int64, status int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, transaction := range m.transactions {
+		if transaction.ParkingInfo.ID == parking && transaction.Status == status {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *memoryStore) OverlappingTransactions(ctx context.Context, transaction *model.Transaction, plates []string) ([]model.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	overlapping := []model.Transaction{}
+	for _, other := range m.transactions {
+		if other.ID != transaction.ID && other.ParkingInfo.ID == transaction.ParkingInfo.ID && hasPlate(plates, other.Matricula) &&
+			!other.CheckinDate.IsZero() && other.CheckinDate.Before(transaction.CheckoutDate) && other.CheckoutDate.After(transaction.CheckinDate) {
+			overlapping = append(overlapping, *other)
+		}
+	}
+
+	return overlapping, nil
+}
+
+func (m *memoryStore) MarkOverlap(ctx context.Context, first primitive.ObjectID, second primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, transaction := range m.transactions {
+		var other primitive.ObjectID
+		switch transaction.ID {
+		case first:
+			other = second
+		case second:
+			other = first
+		default:
+			continue
+		}
+
+		if transaction.PriorStatus == nil {
+			prior := transaction.Status
+			transaction.PriorStatus = &prior
+		}
+		transaction.Status = DEVIATION
+		transaction.OverlapsWith = append(transaction.OverlapsWith, other)
+	}
+
+	return nil
+}
+
+func (m *memoryStore) TransactionExists(ctx context.Context, importID primitive.ObjectID, line int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, transaction := range m.transactions {
+		if (transaction.ImportID == importID && transaction.ImportLine == line) ||
+			(transaction.ClosedImportID == importID && transaction.ClosedImportLine == line) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *memoryStore) IncrementRevenue(ctx context.Context, transaction *model.Transaction) error {
+	return nil
+}
+
+func (m *memoryStore) SaveCheckpoint(ctx context.Context, importID primitive.ObjectID, checkpoint model.Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dead {
+		return errCrashed
+	}
+
+	m.checkpoints = append(m.checkpoints, checkpoint)
+
+	return nil
+}
+
+// crashFile returns a file of rows, every fifth without dates, along with
+// the record numbers expected to be persisted
+func crashFile(rows int) ([]byte, []int64) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+
+	var expected []int64
+	for i := 1; i <= rows; i++ {
+		checkin, checkout := "01/10/2020 10:00:00", "01/10/2020 11:00:00"
+		if i%5 == 0 {
+			checkin, checkout = "", ""
+		} else {
+			expected = append(expected, int64(i))
+		}
+
+		// quoted line breaks make records span lines
+		unit := "Monza"
+		if i%3 == 0 {
+			unit = "Monza\nNorte"
+		}
+
+		writer.Write([]string{unit, strconv.Itoa(i), "", fmt.Sprintf("ABC%04d", i), "NORMAL", "", checkin, checkout, "", "", "5.00", "DINHEIRO", "NORMAL"})
+	}
+	writer.Flush()
+
+	return buf.Bytes(), expected
+}
+
+func TestVP_ResumeAfterCrash(t *testing.T) {
+	file, expected := crashFile(60)
+	importID := primitive.NewObjectID()
+
+	for crashAfter := 1; crashAfter < len(expected); crashAfter++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		store := &memoryStore{crashAfter: crashAfter, crash: cancel}
+
+		// first run dies after crashAfter inserts
+		source, err := newCSVSource(bytes.NewReader(file), 0, config.Dialect{})
+		require.Nil(t, err)
+		processor := newVP(store, source, "transactions", model.Parking{}, HoursTouched{}, importID)
+		processor.checkpointEvery = 7
+
+		_, err = processor.Process(ctx)
+		require.Equal(t, context.Canceled, err)
+
+		// second run resumes from the last checkpoint that made it
+		start := model.Checkpoint{}
+		if len(store.checkpoints) > 0 {
+			start = store.checkpoints[len(store.checkpoints)-1]
+		}
+		store.dead = false
+		store.crashAfter = 0
+
+		source, err = newCSVSource(bytes.NewReader(file[start.Offset:]), start.Offset, config.Dialect{})
+		require.Nil(t, err)
+		processor = newVP(store, source, "transactions", model.Parking{}, HoursTouched{}, importID)
+		processor.checkpointEvery = 7
+		processor.start = start
+		processor.resumed = true
+
+		summary, err := processor.Process(context.Background())
+		require.Nil(t, err)
+
+		lines := []int64{}
+		for _, transaction := range store.transactions {
+			lines = append(lines, transaction.ImportLine)
+		}
+
+		require.Equal(t, expected, lines, "crash after %d inserts", crashAfter)
+		require.Equal(t, model.ImportSummary{Rows: 60, Inserted: int64(len(expected)), Skipped: 12}, *summary, "crash after %d inserts", crashAfter)
+
+		last := store.checkpoints[len(store.checkpoints)-1]
+		require.Equal(t, int64(60), last.Line)
+		require.Equal(t, int64(len(file)), last.Offset)
+	}
+}
@@ -0,0 +1,120 @@
+package business
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/tealeg/xlsx"
+)
+
+var (
+	// excel1900Epoch is day 0 of the 1900 date system, which counts the
+	// 29th of February 1900 that never was, so serials after it are a day
+	// ahead
+	excel1900Epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	excel1904Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// excelTime converts the serial of an Excel date cell to its time, to the
+// nearest second
+func excelTime(serial float64, date1904 bool) time.Time {
+	epoch := excel1900Epoch
+	if date1904 {
+		epoch = excel1904Epoch
+	} else if serial < 60 {
+		epoch = epoch.AddDate(0, 0, 1)
+	}
+
+	seconds := math.Round(serial * 24 * 60 * 60)
+
+	return epoch.Add(time.Duration(seconds) * time.Second)
+}
+
+// sheetSource is the source of the rows of a sheet of a spreadsheet
+type sheetSource struct {
+	rows []Row
+	next int
+}
+
+// newSheetSource reads the sheet of the spreadsheet at path named or
+// numbered from 1 by the sheet of dialect, the first one when empty. Date
+// cells are turned into the dates of the transactions files
+func newSheetSource(path string, dialect config.Dialect) (RowSource, error) {
+	file, err := xlsx.OpenFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening spreadsheet [%s]: [%s]", path, err.Error())
+	}
+
+	sheet, err := pickSheet(file, dialect.Sheet)
+	if err != nil {
+		return nil, fmt.Errorf("error opening spreadsheet [%s]: [%s]", path, err.Error())
+	}
+
+	rows := []Row{}
+	for line, row := range sheet.Rows {
+		record := make([]string, sheet.MaxCol)
+		empty := true
+		if row != nil {
+			for i, cell := range row.Cells {
+				if i < len(record) && cell != nil {
+					record[i] = cellValue(cell, file.Date1904)
+					empty = empty && record[i] == ""
+				}
+			}
+		}
+
+		// blank rows, between the rows or past the last one, are skipped
+		// while keeping the line numbers of the sheet
+		if !empty {
+			rows = append(rows, Row{Fields: record, Line: int64(line + 1), Offset: int64(len(rows) + 1)})
+		}
+	}
+
+	// rows have as many fields as the sheet has columns
+	return newFrameSource(&sheetSource{rows: rows}, dialect.HeaderRows, dialect.FooterRows, -1), nil
+}
+
+func pickSheet(file *xlsx.File, name string) (*xlsx.Sheet, error) {
+	if name == "" {
+		name = "1"
+	}
+
+	if sheet, ok := file.Sheet[name]; ok {
+		return sheet, nil
+	}
+
+	index, err := strconv.Atoi(name)
+	if err == nil && index >= 1 && index <= len(file.Sheets) {
+		return file.Sheets[index-1], nil
+	}
+
+	return nil, fmt.Errorf("sheet [%s] does not exists", name)
+}
+
+func cellValue(cell *xlsx.Cell, date1904 bool) string {
+	if cell.Type() == xlsx.CellTypeNumeric && cell.IsTime() {
+		serial, err := strconv.ParseFloat(cell.Value, 64)
+		if err == nil {
+			return excelTime(serial, date1904).Format(importDateLayout)
+		}
+	}
+
+	return strings.TrimSpace(cell.Value)
+}
+
+// Next returns the next row, whose offset is its number among the rows
+// read since spreadsheets are not seeked into
+func (s *sheetSource) Next() (Row, error) {
+	if s.next >= len(s.rows) {
+		return Row{}, io.EOF
+	}
+
+	s.next++
+
+	return s.rows[s.next-1], nil
+}
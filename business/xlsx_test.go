@@ -0,0 +1,194 @@
+package business
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"github.com/tealeg/xlsx"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExcelTime(t *testing.T) {
+	type TestRun struct {
+		name     string
+		serial   float64
+		date1904 bool
+		expected time.Time
+	}
+
+	tt := []TestRun{
+		{
+			name:     "date and time",
+			serial:   44105.43055555555,
+			expected: time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC),
+		},
+		{
+			name:     "midnight",
+			serial:   44105,
+			expected: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "just before midnight",
+			serial:   44105.99998842592,
+			expected: time.Date(2020, 10, 1, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name:     "before the leap day that never was",
+			serial:   59,
+			expected: time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "after the leap day that never was",
+			serial:   61,
+			expected: time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "1904 date system",
+			serial:   42643.43055555555,
+			date1904: true,
+			expected: time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, excelTime(tc.serial, tc.date1904))
+		})
+	}
+}
+
+// writeSpreadsheet writes a spreadsheet with a summary sheet and a sheet of
+// transactions with a header and a footer
+func writeSpreadsheet(t *testing.T, path string) {
+	file := xlsx.NewFile()
+
+	summary, err := file.AddSheet("Resumo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary.AddRow().AddCell().SetString("Monza")
+
+	sheet, err := file.AddSheet("Transacoes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := sheet.AddRow()
+	for _, name := range []string{"Unidade", "Ticket", "Identidade", "Placa", "Uso", "Convenio", "Entrada", "Saida", "Permanencia", "Tarifa", "Valor", "Pagamento", "Tabela"} {
+		header.AddCell().SetString(name)
+	}
+
+	rows := []struct {
+		ticket   int
+		checkin  time.Time
+		checkout time.Time
+		paid     float64
+		method   string
+	}{
+		{4001, time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC), time.Date(2020, 10, 1, 10, 50, 0, 0, time.UTC), 12.5, "DINHEIRO"},
+		{4002, time.Date(2020, 10, 1, 23, 0, 0, 0, time.UTC), time.Date(2020, 10, 2, 1, 15, 30, 0, time.UTC), 5, "CREDITO"},
+	}
+	for _, r := range rows {
+		row := sheet.AddRow()
+		row.AddCell().SetString("Monza")
+		row.AddCell().SetInt(r.ticket)
+		row.AddCell()
+		row.AddCell().SetString("ABC1234")
+		row.AddCell().SetString("NORMAL")
+		row.AddCell()
+		row.AddCell().SetDateTime(r.checkin)
+		row.AddCell().SetDateTime(r.checkout)
+		row.AddCell()
+		row.AddCell()
+		row.AddCell().SetFloat(r.paid)
+		row.AddCell().SetString(r.method)
+		row.AddCell().SetString("NORMAL")
+	}
+
+	sheet.AddRow().AddCell().SetString("Total")
+
+	err = file.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInput_RowsSpreadsheet(t *testing.T) {
+	path := filepath.Join(tempDir(t, nil), "monza.xlsx")
+	writeSpreadsheet(t, path)
+
+	type TestRun struct {
+		name          string
+		dialect       config.Dialect
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name:    "sheet by name",
+			dialect: config.Dialect{Sheet: "Transacoes", HeaderRows: 1, FooterRows: 1},
+		},
+		{
+			name:    "sheet by number",
+			dialect: config.Dialect{Sheet: "2", HeaderRows: 1, FooterRows: 1},
+		},
+		{
+			name:          "missing sheet",
+			dialect:       config.Dialect{Sheet: "3"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := Inputs(path, nil)
+			require.Nil(t, err)
+
+			source, _, err := inputs[0].Rows(tc.dialect)
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+
+			row, err := source.Next()
+			require.Nil(t, err)
+			require.Equal(t, int64(2), row.Line)
+			require.Equal(t, []string{"Monza", "4001", "", "ABC1234", "NORMAL", "", "01/10/2020 10:20:00", "01/10/2020 10:50:00", "", "", "12.5", "DINHEIRO", "NORMAL"}, row.Fields)
+
+			row, err = source.Next()
+			require.Nil(t, err)
+			require.Equal(t, "01/10/2020 23:00:00", row.Fields[6])
+			require.Equal(t, "02/10/2020 01:15:30", row.Fields[7])
+
+			source, _, err = inputs[0].Rows(tc.dialect)
+			require.Nil(t, err)
+
+			processor := NewVP(nil, source, "transactions", model.Parking{}, HoursTouched{}, primitive.NilObjectID)
+			report, err := processor.Validate(context.Background())
+			require.Nil(t, err)
+			require.Equal(t, &ValidationReport{Rows: 2, Valid: 2}, report)
+		})
+	}
+}
+
+func TestOpenRows_ResumeSpreadsheet(t *testing.T) {
+	path := filepath.Join(tempDir(t, nil), "monza.xlsx")
+	writeSpreadsheet(t, path)
+
+	inputs, err := Inputs(path, nil)
+	require.Nil(t, err)
+
+	source, _, err := openRows(inputs[0], model.Checkpoint{Line: 1, Offset: 1}, config.Dialect{Sheet: "Transacoes", HeaderRows: 1, FooterRows: 1})
+	require.Nil(t, err)
+
+	row, err := source.Next()
+	require.Nil(t, err)
+	require.Equal(t, "4002", row.Fields[1])
+	require.Equal(t, int64(3), row.Line)
+}
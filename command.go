@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/mongo"
+)
+
+// command is a subcommand of the CLI with its own flag set
+type command struct {
+	name        string
+	description string
+	// arguments describes the positional arguments, which are required when set
+	arguments string
+	flags     *flag.FlagSet
+	required  []string
+	run       func(ctx context.Context, cmd *command) error
+
+	// cfg is the configuration layered from file, environment and flags
+	cfg  *config.Config
+	seen map[string]bool
+
+	configPath string
+	mongoConn  string
+	mongoDB    string
+}
+
+func newCommand(name string, description string) *command {
+	cmd := &command{
+		name:        name,
+		description: description,
+		flags:       flag.NewFlagSet(name, flag.ExitOnError),
+		seen:        make(map[string]bool),
+	}
+
+	cmd.flags.StringVar(&cmd.configPath, "config", "", "path to the JSON config file, defaults to $"+config.EnvConfig)
+	cmd.flags.StringVar(&cmd.mongoConn, "mongo-conn", "", "mongo connection string, defaults to $"+config.EnvMongoConn)
+	cmd.flags.StringVar(&cmd.mongoDB, "mongo-db", "", "mongo database, defaults to $"+config.EnvMongoDB)
+
+	cmd.flags.Usage = func() {
+		line := strings.TrimSpace(fmt.Sprintf("csv-processor %s [flags] %s", cmd.name, cmd.arguments))
+		fmt.Fprintf(cmd.flags.Output(), "usage: %s\n\n%s\n\nflags:\n", line, cmd.description)
+		cmd.flags.PrintDefaults()
+	}
+
+	return cmd
+}
+
+// parse parses the command arguments, checks the required flags and
+// layers the configuration
+func (c *command) parse(args []string) error {
+	err := c.flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	c.flags.Visit(func(f *flag.Flag) { c.seen[f.Name] = true })
+	for _, req := range c.required {
+		if !c.seen[req] {
+			return fmt.Errorf("missing required [-%s] argument/flag", req)
+		}
+	}
+
+	if c.arguments != "" && c.flags.NArg() == 0 {
+		return fmt.Errorf("missing required %s argument", c.arguments)
+	}
+
+	c.cfg, err = config.Load(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	if c.seen["mongo-conn"] {
+		c.cfg.Mongo.Conn = c.mongoConn
+	}
+	if c.seen["mongo-db"] {
+		c.cfg.Mongo.DB = c.mongoDB
+	}
+
+	return nil
+}
+
+// connect connects to the configured database
+func (c *command) connect() (*mongo.DB, error) {
+	db, err := mongo.Connect(c.cfg.Mongo)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting database: [%s]", err.Error())
+	}
+
+	return db, nil
+}
+
+// parseDay parses a YYYY-MM-DD flag value
+func parseDay(name string, value string) (time.Time, error) {
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing %s [%s]: [%s]", name, value, err.Error())
+	}
+
+	return day, nil
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	os.Exit(2)
+}
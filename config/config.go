@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// EnvConfig is the path of the config file
+	EnvConfig = "LOTS_API_CONFIG"
+	// EnvMongoConn is the mongo connection string
+	EnvMongoConn = "LOTS_API_MONGO_CONN"
+	// EnvMongoDB is the mongo database name
+	EnvMongoDB = "LOTS_API_MONGO_DB"
+	// EnvMongoTimeout is the mongo connection timeout in seconds
+	EnvMongoTimeout = "LOTS_API_MONGO_TIMEOUT"
+	// EnvBucketing is the default hour bucketing policy
+	EnvBucketing = "LOTS_API_BUCKETING"
+	// EnvBucketingFraction is the billing unit of the fractional bucketing
+	// in minutes
+	EnvBucketingFraction = "LOTS_API_BUCKETING_FRACTION"
+	// EnvBucketingTolerance is the free period of the fractional bucketing
+	// in minutes
+	EnvBucketingTolerance = "LOTS_API_BUCKETING_TOLERANCE"
+	// EnvMaskPlates masks the plates of the visit profiles when true
+	EnvMaskPlates = "LOTS_API_MASK_PLATES"
+	// EnvPseudonymKeyID is the key plates and identities are hashed with
+	EnvPseudonymKeyID = "LOTS_API_PSEUDONYM_KEY_ID"
+	// EnvPseudonymKey is the secret of the key of EnvPseudonymKeyID
+	EnvPseudonymKey = "LOTS_API_PSEUDONYM_KEY"
+
+	defaultMongoTimeout = 10 * time.Second
+	defaultBucketing    = "touched"
+	defaultFraction     = 15
+)
+
+// Config holds the settings shared by every command, layered from the
+// config file, then the environment and then the command flags
+type Config struct {
+	Mongo     Mongo  `json:"mongo"`
+	Bucketing string `json:"bucketing"`
+	// Fractional is how the fractional bucketing bills stays
+	Fractional Fractional `json:"fractional"`
+	// Dialect is how imported files are read, unless their park has its
+	// own in ParkDialects, keyed by park slug
+	Dialect      Dialect            `json:"dialect"`
+	ParkDialects map[string]Dialect `json:"park_dialects"`
+	Privacy      Privacy            `json:"privacy"`
+}
+
+// Privacy holds how personal data is stored and shown
+type Privacy struct {
+	// MaskPlates hides the middle of the plates of the visit profiles
+	MaskPlates bool `json:"mask_plates"`
+	// KeyID is the key of Keys the plates and identities are hashed with
+	// before being stored, they are stored in clear when empty. The keys
+	// of older IDs are kept to find what they hashed
+	KeyID string            `json:"key_id"`
+	Keys  map[string]string `json:"keys"`
+}
+
+// Fractional holds the billing of the fractional bucketing, in minutes
+type Fractional struct {
+	// Fraction is the billing unit stays are rounded up to
+	Fraction int `json:"fraction"`
+	// Tolerance is the free period, stays up to it are not billed
+	Tolerance int `json:"tolerance"`
+}
+
+// Mongo holds the database settings
+type Mongo struct {
+	Conn string `json:"conn"`
+	DB   string `json:"db"`
+	// Timeout is the connection timeout in seconds
+	Timeout int `json:"timeout"`
+}
+
+// ConnectTimeout returns the connection timeout
+func (m Mongo) ConnectTimeout() time.Duration {
+	if m.Timeout <= 0 {
+		return defaultMongoTimeout
+	}
+
+	return time.Duration(m.Timeout) * time.Second
+}
+
+// Load reads the config file at path, falling back to the path in the
+// environment, and applies the environment over it. An empty path
+// without the environment variable loads only the defaults and environment
+func Load(path string) (*Config, error) {
+	cfg := &Config{
+		Bucketing:  defaultBucketing,
+		Fractional: Fractional{Fraction: defaultFraction},
+	}
+
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+
+	if path != "" {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config [%s]: %v", path, err)
+		}
+
+		err = json.Unmarshal(content, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config [%s]: %v", path, err)
+		}
+	}
+
+	err := cfg.applyEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Privacy.KeyID != "" && cfg.Privacy.Keys[cfg.Privacy.KeyID] == "" {
+		return nil, fmt.Errorf("pseudonymization key [%s] has no secret", cfg.Privacy.KeyID)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	if value, ok := os.LookupEnv(EnvMongoConn); ok {
+		c.Mongo.Conn = value
+	}
+
+	if value, ok := os.LookupEnv(EnvMongoDB); ok {
+		c.Mongo.DB = value
+	}
+
+	if value, ok := os.LookupEnv(EnvMongoTimeout); ok {
+		timeout, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("error parsing %s [%s]: %v", EnvMongoTimeout, value, err)
+		}
+		c.Mongo.Timeout = timeout
+	}
+
+	if value, ok := os.LookupEnv(EnvBucketing); ok {
+		c.Bucketing = value
+	}
+
+	if value, ok := os.LookupEnv(EnvBucketingFraction); ok {
+		fraction, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("error parsing %s [%s]: %v", EnvBucketingFraction, value, err)
+		}
+		c.Fractional.Fraction = fraction
+	}
+
+	if value, ok := os.LookupEnv(EnvBucketingTolerance); ok {
+		tolerance, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("error parsing %s [%s]: %v", EnvBucketingTolerance, value, err)
+		}
+		c.Fractional.Tolerance = tolerance
+	}
+
+	if value, ok := os.LookupEnv(EnvMaskPlates); ok {
+		mask, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("error parsing %s [%s]: %v", EnvMaskPlates, value, err)
+		}
+		c.Privacy.MaskPlates = mask
+	}
+
+	if value, ok := os.LookupEnv(EnvPseudonymKeyID); ok {
+		c.Privacy.KeyID = value
+	}
+
+	if value, ok := os.LookupEnv(EnvPseudonymKey); ok {
+		if c.Privacy.Keys == nil {
+			c.Privacy.Keys = map[string]string{}
+		}
+		c.Privacy.Keys[c.Privacy.KeyID] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func unsetEnv(t *testing.T, names ...string) {
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+		os.Unsetenv(name)
+		if ok {
+			name := name
+			t.Cleanup(func() { os.Setenv(name, value) })
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	type TestRun struct {
+		name          string
+		file          string
+		env           map[string]string
+		expected      *Config
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name: "defaults",
+			expected: &Config{
+				Bucketing:  defaultBucketing,
+				Fractional: Fractional{Fraction: defaultFraction},
+			},
+		},
+		{
+			name: "file",
+			file: `{"mongo": {"conn": "mongodb://file", "db": "file", "timeout": 3}, "bucketing": "started"}`,
+			expected: &Config{
+				Mongo:      Mongo{Conn: "mongodb://file", DB: "file", Timeout: 3},
+				Bucketing:  "started",
+				Fractional: Fractional{Fraction: defaultFraction},
+			},
+		},
+		{
+			name: "environment over file",
+			file: `{"mongo": {"conn": "mongodb://file", "db": "file"}}`,
+			env: map[string]string{
+				EnvMongoConn:    "mongodb://env",
+				EnvMongoTimeout: "5",
+			},
+			expected: &Config{
+				Mongo:      Mongo{Conn: "mongodb://env", DB: "file", Timeout: 5},
+				Bucketing:  defaultBucketing,
+				Fractional: Fractional{Fraction: defaultFraction},
+			},
+		},
+		{
+			name: "dialects",
+			file: `{"dialect": {"delimiter": ";", "header_rows": 1}, "park_dialects": {"monza": {"sniff": true}}}`,
+			expected: &Config{
+				Bucketing:    defaultBucketing,
+				Fractional:   Fractional{Fraction: defaultFraction},
+				Dialect:      Dialect{Delimiter: ";", HeaderRows: 1},
+				ParkDialects: map[string]Dialect{"monza": {Sniff: true}},
+			},
+		},
+		{
+			name: "fixed-width columns",
+			file: `{"park_dialects": {"centro": {"format": "fixed", "columns": [{"name": "ticket", "start": 11, "length": 8, "trim": "left", "pad": "0"}]}}}`,
+			expected: &Config{
+				Bucketing:  defaultBucketing,
+				Fractional: Fractional{Fraction: defaultFraction},
+				ParkDialects: map[string]Dialect{"centro": {
+					Format:  "fixed",
+					Columns: []Column{{Name: "ticket", Start: 11, Length: 8, Trim: "left", Pad: "0"}},
+				}},
+			},
+		},
+		{
+			name: "fractional bucketing",
+			file: `{"bucketing": "fractional", "fractional": {"fraction": 30, "tolerance": 5}}`,
+			env: map[string]string{
+				EnvBucketingTolerance: "10",
+			},
+			expected: &Config{
+				Bucketing:  "fractional",
+				Fractional: Fractional{Fraction: 30, Tolerance: 10},
+			},
+		},
+		{
+			name: "invalid fraction",
+			env: map[string]string{
+				EnvBucketingFraction: "quarter",
+			},
+			expectedError: true,
+		},
+		{
+			name:          "invalid file",
+			file:          `{"mongo":`,
+			expectedError: true,
+		},
+		{
+			name: "plate masking",
+			file: `{"privacy": {"mask_plates": false}}`,
+			env: map[string]string{
+				EnvMaskPlates: "true",
+			},
+			expected: &Config{
+				Bucketing:  defaultBucketing,
+				Fractional: Fractional{Fraction: defaultFraction},
+				Privacy:    Privacy{MaskPlates: true},
+			},
+		},
+		{
+			name: "pseudonymization keys",
+			file: `{"privacy": {"key_id": "2020", "keys": {"2020": "file", "2019": "old"}}}`,
+			env: map[string]string{
+				EnvPseudonymKeyID: "2021",
+				EnvPseudonymKey:   "env",
+			},
+			expected: &Config{
+				Bucketing:  defaultBucketing,
+				Fractional: Fractional{Fraction: defaultFraction},
+				Privacy:    Privacy{KeyID: "2021", Keys: map[string]string{"2021": "env", "2020": "file", "2019": "old"}},
+			},
+		},
+		{
+			name:          "pseudonymization key without secret",
+			file:          `{"privacy": {"key_id": "2021", "keys": {"2020": "file"}}}`,
+			expectedError: true,
+		},
+		{
+			name: "invalid plate masking",
+			env: map[string]string{
+				EnvMaskPlates: "sometimes",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid timeout",
+			env: map[string]string{
+				EnvMongoTimeout: "error",
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			unsetEnv(t, EnvConfig, EnvMongoConn, EnvMongoDB, EnvMongoTimeout, EnvBucketing, EnvBucketingFraction, EnvBucketingTolerance, EnvMaskPlates, EnvPseudonymKeyID, EnvPseudonymKey)
+			for name, value := range tc.env {
+				name := name
+				os.Setenv(name, value)
+				t.Cleanup(func() { os.Unsetenv(name) })
+			}
+
+			path := ""
+			if tc.file != "" {
+				path = writeConfig(t, tc.file)
+			}
+
+			cfg, err := Load(path)
+
+			if tc.expectedError {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, tc.expected, cfg)
+			}
+		})
+	}
+}
+
+func TestMongo_ConnectTimeout(t *testing.T) {
+	require.Equal(t, defaultMongoTimeout, Mongo{}.ConnectTimeout())
+	require.Equal(t, 3*time.Second, Mongo{Timeout: 3}.ConnectTimeout())
+}
+
+func TestConfig_DialectOf(t *testing.T) {
+	cfg := Config{
+		Dialect:      Dialect{Delimiter: ";"},
+		ParkDialects: map[string]Dialect{"monza": {Delimiter: "tab"}},
+	}
+
+	require.Equal(t, Dialect{Delimiter: "tab"}, cfg.DialectOf("monza"))
+	require.Equal(t, Dialect{Delimiter: ";"}, cfg.DialectOf("interlagos"))
+}
@@ -0,0 +1,58 @@
+package config
+
+// Dialect describes how the files of an operator system are written,
+// spreadsheets only use the header, footer and sheet settings
+type Dialect struct {
+	// Format is csv, tsv, jsonl, xlsx or fixed, from the file extension
+	// when empty
+	Format string `json:"format"`
+	// Encoding is the character encoding, detected when auto or empty
+	Encoding string `json:"encoding"`
+	// Delimiter separates the fields, a comma when empty and a tab when "tab"
+	Delimiter string `json:"delimiter"`
+	// Comment starts the lines that are ignored, none when empty
+	Comment          string `json:"comment"`
+	LazyQuotes       bool   `json:"lazy_quotes"`
+	TrimLeadingSpace bool   `json:"trim_leading_space"`
+	// Fields is how many fields every row has, the first row sets it when
+	// 0 and rows may have any number of fields when -1
+	Fields int `json:"fields"`
+	// HeaderRows and FooterRows are dropped from the start and the end
+	HeaderRows int `json:"header_rows"`
+	FooterRows int `json:"footer_rows"`
+	// Sheet is the name or the number, from 1, of the sheet of spreadsheets
+	// to read, the first one when empty
+	Sheet string `json:"sheet"`
+	// Sniff guesses the delimiter, comment, header and fields from the
+	// start of the file, overriding the settings above
+	Sniff bool `json:"sniff"`
+	// Columns are where the fields of fixed-width files are
+	Columns []Column `json:"columns"`
+}
+
+// Column is a field of the lines of a fixed-width file
+type Column struct {
+	// Name is the transaction field read, one of unit, ticket, identity,
+	// plate, use_type, agreement, checkin, checkout, duration, fare, paid,
+	// payment_method or table
+	Name string `json:"name"`
+	// Start is the character the field starts at, from 1, and Length how
+	// many characters it has
+	Start  int `json:"start"`
+	Length int `json:"length"`
+	// Trim is the side the padding is trimmed from: both when empty, left,
+	// right or none
+	Trim string `json:"trim"`
+	// Pad is the padding character, a space when empty
+	Pad string `json:"pad"`
+}
+
+// DialectOf returns the dialect of the files of the park with slug, the
+// dialect of the park replacing the default one when configured
+func (c Config) DialectOf(slug string) Dialect {
+	if dialect, ok := c.ParkDialects[slug]; ok {
+		return dialect
+	}
+
+	return c.Dialect
+}
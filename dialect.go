@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/csv-processor/config"
+)
+
+// dialectFlags are the flags overriding the configured dialect of the files
+type dialectFlags struct {
+	format           *string
+	encoding         *string
+	delimiter        *string
+	comment          *string
+	lazyQuotes       *bool
+	trimLeadingSpace *bool
+	fields           *int
+	headerRows       *int
+	footerRows       *int
+	sheet            *string
+	sniff            *bool
+}
+
+func addDialectFlags(cmd *command) *dialectFlags {
+	return &dialectFlags{
+		format:           cmd.flags.String("format", "", "format of the files: csv, tsv, jsonl, xlsx or fixed, defaults to the config or their extension"),
+		encoding:         cmd.flags.String("encoding", "", "character encoding of the files: auto, utf-8, windows-1252 or iso-8859-1, defaults to the config"),
+		delimiter:        cmd.flags.String("delimiter", "", "field delimiter, tab for tabs, defaults to the config or a comma"),
+		comment:          cmd.flags.String("comment", "", "character starting the lines to ignore"),
+		lazyQuotes:       cmd.flags.Bool("lazy-quotes", false, "allow quotes in unquoted fields and unescaped quotes in quoted fields"),
+		trimLeadingSpace: cmd.flags.Bool("trim-space", false, "trim the leading spaces of the fields"),
+		fields:           cmd.flags.Int("fields", 0, "fields of every row, 0 takes the first row's and -1 allows ragged rows"),
+		headerRows:       cmd.flags.Int("header-rows", 0, "rows to skip at the start of the files"),
+		footerRows:       cmd.flags.Int("footer-rows", 0, "rows to skip at the end of the files"),
+		sheet:            cmd.flags.String("sheet", "", "name or number, from 1, of the sheet of spreadsheets to read, defaults to the first"),
+		sniff:            cmd.flags.Bool("sniff", false, "guess the delimiter, comment, header and fields from the start of the files"),
+	}
+}
+
+// apply returns dialect with the flags given on the command line over it
+func (f *dialectFlags) apply(cmd *command, dialect config.Dialect) config.Dialect {
+	if cmd.seen["format"] {
+		dialect.Format = *f.format
+	}
+	if cmd.seen["encoding"] {
+		dialect.Encoding = *f.encoding
+	}
+	if cmd.seen["delimiter"] {
+		dialect.Delimiter = *f.delimiter
+	}
+	if cmd.seen["comment"] {
+		dialect.Comment = *f.comment
+	}
+	if cmd.seen["lazy-quotes"] {
+		dialect.LazyQuotes = *f.lazyQuotes
+	}
+	if cmd.seen["trim-space"] {
+		dialect.TrimLeadingSpace = *f.trimLeadingSpace
+	}
+	if cmd.seen["fields"] {
+		dialect.Fields = *f.fields
+	}
+	if cmd.seen["header-rows"] {
+		dialect.HeaderRows = *f.headerRows
+	}
+	if cmd.seen["footer-rows"] {
+		dialect.FooterRows = *f.footerRows
+	}
+	if cmd.seen["sheet"] {
+		dialect.Sheet = *f.sheet
+	}
+	if cmd.seen["sniff"] {
+		dialect.Sniff = *f.sniff
+	}
+
+	return dialect
+}
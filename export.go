@@ -0,0 +1,94 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/model"
+)
+
+func exportCommand() *command {
+	cmd := newCommand("export", "exports the transactions matching the filters to a file")
+
+	output := cmd.flags.String("out", "-", "path to the exported file, - for stdout")
+	format := cmd.flags.String("format", business.ExportCSV, "export format: csv, jsonl or parquet")
+	compress := cmd.flags.Bool("gzip", false, "gzip the exported file")
+	parkid := cmd.flags.Int64("parkid", 0, "only transactions of the park with this id")
+	from := cmd.flags.String("from", "", "only transactions checked out from this day (YYYY-MM-DD)")
+	to := cmd.flags.String("to", "", "only transactions checked out up to this day (YYYY-MM-DD)")
+	status := cmd.flags.Int("status", business.VALID, "only transactions with this status")
+
+	cmd.required = []string{"out"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		filter := model.TransactionFilter{}
+		if cmd.seen["parkid"] {
+			filter.ParkingID = parkid
+		}
+		if cmd.seen["from"] {
+			fromDay, err := parseDay("from", *from)
+			if err != nil {
+				return err
+			}
+			filter.From = &fromDay
+		}
+		if cmd.seen["to"] {
+			toDay, err := parseDay("to", *to)
+			if err != nil {
+				return err
+			}
+			toDay = toDay.AddDate(0, 0, 1)
+			filter.To = &toDay
+		}
+		if cmd.seen["status"] {
+			filter.Status = status
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		var out io.WriteCloser = os.Stdout
+		if *output != "-" {
+			file, err := os.Create(*output)
+			if err != nil {
+				return fmt.Errorf("error creating file [%s]: [%s]", *output, err.Error())
+			}
+			out = file
+		}
+
+		writer := io.Writer(out)
+		var gz *gzip.Writer
+		if *compress {
+			gz = gzip.NewWriter(out)
+			writer = gz
+		}
+
+		total, err := business.NewExport(db, writer, *format).Export(ctx, filter)
+
+		// the gzip trailer is written and the file closed even when the
+		// export failed, the first error is the one returned
+		if gz != nil {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("error exporting to [%s]: [%s]", *output, err.Error())
+		}
+
+		log.Sugar().Infow("Transactions exported", "out", *output, "format", *format, "transactions", total)
+
+		return nil
+	}
+
+	return cmd
+}
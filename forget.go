@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	"github.com/csv-processor/business"
+)
+
+func forgetCommand() *command {
+	cmd := newCommand("forget", "erases the plate and identity of every transaction of a plate")
+	cmd.arguments = "<plate>"
+
+	dir := cmd.flags.String("dir", jobsDir, "directory of serve whose rejected rows holding the plate are dropped, none when empty")
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		summary, err := business.NewErasure(db, cmd.cfg.Privacy, *dir).Forget(ctx, cmd.flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		log.Sugar().Infow("Plate forgotten", "transactions", summary.Transactions, "archived", summary.Archived, "rejects", summary.Rejects)
+
+		return nil
+	}
+
+	return cmd
+}
@@ -3,7 +3,14 @@ module github.com/csv-processor
 go 1.15
 
 require (
-	github.com/stretchr/testify v1.6.1
+	github.com/klauspost/compress v1.13.1
+	github.com/stretchr/testify v1.7.0
+	github.com/tealeg/xlsx v1.0.5
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 	go.mongodb.org/mongo-driver v1.4.3
 	go.uber.org/zap v1.16.0
+	golang.org/x/text v0.3.3
+	google.golang.org/grpc v1.38.0
+	google.golang.org/protobuf v1.26.0
 )
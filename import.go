@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/csv-processor/business"
+)
+
+func importCommand() *command {
+	cmd := newCommand("import", "imports a file, a directory or a glob of files of parks into the database")
+
+	processFile := cmd.flags.String("csvFile", "", "path to the file, directory or glob of CSV files to parse, gzip, zstd and zip compressed files and xlsx spreadsheets included, - reads the standard input")
+	filetype := cmd.flags.String("filetype", "transactions", "file type to be processed")
+	park := cmd.flags.String("park", "", "slug of the park in the registry, when not in the manifest or file name")
+	parkslug := cmd.flags.String("parkslug", "", "deprecated, same as -park")
+	parkid := cmd.flags.Int64("parkid", 0, "deprecated, id of the park in the registry, when not in the manifest or file name")
+	cmd.flags.String("parkname", "", "deprecated and ignored, the name comes from the registry")
+	pattern := cmd.flags.String("pattern", "", "regexp matched against file names to find the park, with the named groups slug or id")
+	bucketing := addBucketingFlags(cmd)
+	operator := cmd.flags.String("operator", os.Getenv("USER"), "who is running the import, recorded in the import ledger")
+	force := cmd.flags.Bool("force", false, "import files whose content was already imported")
+	resume := cmd.flags.Bool("resume", false, "continue the unfinished import of the same content from its checkpoint")
+	dialect := addDialectFlags(cmd)
+
+	cmd.required = []string{"csvFile"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		bucket, err := bucketing.bucketing(cmd)
+		if err != nil {
+			return err
+		}
+
+		resolver := business.ParkResolver{
+			Filetype: *filetype,
+			Park:     *park,
+			ParkID:   *parkid,
+		}
+		if resolver.Park == "" {
+			resolver.Park = *parkslug
+		}
+		if *pattern != "" {
+			resolver.Pattern, err = regexp.Compile(*pattern)
+			if err != nil {
+				return fmt.Errorf("error parsing pattern [%s]: [%s]", *pattern, err.Error())
+			}
+		}
+
+		files, err := business.ListFiles(*processFile)
+		if err != nil {
+			return err
+		}
+
+		if len(files) == 0 {
+			return fmt.Errorf("no files found at [%s]", *processFile)
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+		resolver.Parks = db.ParkingCollection
+
+		log.Sugar().Infow("Starting parser", "files", len(files))
+
+		importer := business.NewImporter(db, bucket, business.ImportOptions{
+			Operator: *operator,
+			Force:    *force,
+			Resume:   *resume,
+
+			Pseudonymizer: business.NewPseudonymizer(cmd.cfg.Privacy),
+		})
+		// zip archives count a file per member
+		failed, total := 0, 0
+		for _, path := range files {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			total++
+			file, err := resolver.Resolve(ctx, path)
+			if err != nil {
+				log.Sugar().Errorw("Skipping file", "path", path, "error", err.Error())
+				failed++
+				continue
+			}
+
+			file.Dialect = dialect.apply(cmd, cmd.cfg.DialectOf(file.Parking.Slug))
+
+			inputs, err := file.Inputs(os.Stdin)
+			if err != nil {
+				log.Sugar().Errorw("Skipping file", "path", path, "error", err.Error())
+				failed++
+				continue
+			}
+
+			total += len(inputs) - 1
+			for _, input := range inputs {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				if !importFile(ctx, importer, input) {
+					failed++
+				}
+			}
+		}
+
+		log.Info("Finishing...")
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d files failed to import", failed, total)
+		}
+
+		return nil
+	}
+
+	return cmd
+}
+
+// importFile imports a file, logging the outcome, and returns whether it
+// did not fail
+func importFile(ctx context.Context, importer business.Importer, file business.ImportFile) bool {
+	record, imported, err := importer.Import(ctx, file)
+	if err != nil {
+		log.Sugar().Errorw("Error processing file", "path", file.Path, "error", err.Error())
+		return false
+	}
+
+	if !imported {
+		log.Sugar().Warnw("Refused, content already imported, use -force to import it again", "path", file.Path,
+			"import", record.ID.Hex(), "previous", record.Path, "operator", record.Operator, "finished", record.FinishedAt)
+		return true
+	}
+
+	log.Sugar().Infow("Imported", "path", file.Path, "import", record.ID.Hex(), "park", file.Parking.Slug,
+		"rows", record.Summary.Rows, "inserted", record.Summary.Inserted,
+		"skipped", record.Summary.Skipped, "failed", record.Summary.Failed, "rejected", record.Summary.Rejected,
+		"open", record.Summary.Open, "closed", record.Summary.Closed, "overlapping", record.Summary.Overlapping)
+
+	return true
+}
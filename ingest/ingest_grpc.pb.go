@@ -0,0 +1,141 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package ingest
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// IngestClient is the client API for Ingest service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IngestClient interface {
+	// IngestTransactions persists every event sent and acknowledges each one,
+	// in the order sent, with its transaction or why it was refused. An event
+	// sent again, of a ticket and plate of the park already stored, is
+	// accepted with the transaction stored
+	IngestTransactions(ctx context.Context, opts ...grpc.CallOption) (Ingest_IngestTransactionsClient, error)
+}
+
+type ingestClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIngestClient(cc grpc.ClientConnInterface) IngestClient {
+	return &ingestClient{cc}
+}
+
+func (c *ingestClient) IngestTransactions(ctx context.Context, opts ...grpc.CallOption) (Ingest_IngestTransactionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ingest_ServiceDesc.Streams[0], "/ingest.Ingest/IngestTransactions", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ingestIngestTransactionsClient{stream}
+	return x, nil
+}
+
+type Ingest_IngestTransactionsClient interface {
+	Send(*TransactionEvent) error
+	Recv() (*TransactionAck, error)
+	grpc.ClientStream
+}
+
+type ingestIngestTransactionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *ingestIngestTransactionsClient) Send(m *TransactionEvent) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *ingestIngestTransactionsClient) Recv() (*TransactionAck, error) {
+	m := new(TransactionAck)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IngestServer is the server API for Ingest service.
+// All implementations must embed UnimplementedIngestServer
+// for forward compatibility
+type IngestServer interface {
+	// IngestTransactions persists every event sent and acknowledges each one,
+	// in the order sent, with its transaction or why it was refused. An event
+	// sent again, of a ticket and plate of the park already stored, is
+	// accepted with the transaction stored
+	IngestTransactions(Ingest_IngestTransactionsServer) error
+	mustEmbedUnimplementedIngestServer()
+}
+
+// UnimplementedIngestServer must be embedded to have forward compatible implementations.
+type UnimplementedIngestServer struct {
+}
+
+func (UnimplementedIngestServer) IngestTransactions(Ingest_IngestTransactionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method IngestTransactions not implemented")
+}
+func (UnimplementedIngestServer) mustEmbedUnimplementedIngestServer() {}
+
+// UnsafeIngestServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IngestServer will
+// result in compilation errors.
+type UnsafeIngestServer interface {
+	mustEmbedUnimplementedIngestServer()
+}
+
+func RegisterIngestServer(s grpc.ServiceRegistrar, srv IngestServer) {
+	s.RegisterService(&Ingest_ServiceDesc, srv)
+}
+
+func _Ingest_IngestTransactions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IngestServer).IngestTransactions(&ingestIngestTransactionsServer{stream})
+}
+
+type Ingest_IngestTransactionsServer interface {
+	Send(*TransactionAck) error
+	Recv() (*TransactionEvent, error)
+	grpc.ServerStream
+}
+
+type ingestIngestTransactionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *ingestIngestTransactionsServer) Send(m *TransactionAck) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *ingestIngestTransactionsServer) Recv() (*TransactionEvent, error) {
+	m := new(TransactionEvent)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Ingest_ServiceDesc is the grpc.ServiceDesc for Ingest service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ingest_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ingest.Ingest",
+	HandlerType: (*IngestServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "IngestTransactions",
+			Handler:       _Ingest_IngestTransactions_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ingest.proto",
+}
@@ -0,0 +1,92 @@
+// Package ingest serves the gRPC API receiving the transactions of parks as
+// they happen, generated from ingest.proto with buf generate
+package ingest
+
+//go:generate buf generate
+
+import (
+	"io"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/model"
+	"go.uber.org/zap"
+)
+
+type server struct {
+	UnimplementedIngestServer
+
+	ingester business.Ingester
+	logger   *zap.Logger
+}
+
+// NewServer returns the Ingest service persisting the events through ingester
+func NewServer(ingester business.Ingester) IngestServer {
+	log, _ := zap.NewProduction()
+
+	return &server{
+		ingester: ingester,
+		logger:   log,
+	}
+}
+
+// IngestTransactions acknowledges the events as they are persisted, a
+// refused event does not end the stream
+func (s *server) IngestTransactions(stream Ingest_IngestTransactionsServer) error {
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		ack := &TransactionAck{EventId: event.EventId}
+
+		transaction, err := s.ingester.Ingest(stream.Context(), parkingOf(event.Park), lineOf(event))
+		if err != nil {
+			_, ack.Invalid = err.(*business.ValidationError)
+			ack.Error = err.Error()
+			s.logger.Sugar().Infow("event refused", "event", event.EventId, "error", ack.Error)
+		} else {
+			ack.Accepted = true
+			ack.TransactionId = transaction.ID.Hex()
+		}
+
+		err = stream.Send(ack)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func parkingOf(park *Park) model.Parking {
+	if park == nil {
+		return model.Parking{}
+	}
+
+	return model.Parking{ID: park.Id, Slug: park.Slug, Name: park.Name}
+}
+
+// lineOf returns the line of an event, whose missing dates are zero
+func lineOf(event *TransactionEvent) *model.Line {
+	line := &model.Line{
+		Unit:          event.Unit,
+		Ticket:        event.Ticket,
+		Identity:      event.Identity,
+		Matricula:     event.Plate,
+		UseType:       event.UseType,
+		PaidValue:     event.PaidValue,
+		PaymentMethod: event.PaymentMethod,
+		Table:         event.Table,
+	}
+
+	if event.Checkin != nil {
+		line.CheckIn = event.Checkin.AsTime()
+	}
+	if event.Checkout != nil {
+		line.CheckOut = event.Checkout.AsTime()
+	}
+
+	return line
+}
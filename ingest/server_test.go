@@ -0,0 +1,130 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeIngester refuses the lines of unknown tables and fails while the
+// store is down
+type fakeIngester struct {
+	down  bool
+	lines []*model.Line
+}
+
+func (f *fakeIngester) Ingest(ctx context.Context, parking model.Parking, line *model.Line) (*model.Transaction, error) {
+	if line.Table != "NORMAL" {
+		return nil, &business.ValidationError{Err: fmt.Errorf("unknown table [%s]", line.Table)}
+	}
+	if f.down {
+		return nil, fmt.Errorf("database is down")
+	}
+
+	f.lines = append(f.lines, line)
+
+	return &model.Transaction{ID: primitive.NewObjectID(), ParkingInfo: parking}, nil
+}
+
+// dial starts the service in process and connects to it
+func dial(t *testing.T, ingester business.Ingester) IngestClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterIngestServer(server, NewServer(ingester))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return NewIngestClient(conn)
+}
+
+func TestServer_IngestTransactions(t *testing.T) {
+	ingester := &fakeIngester{}
+	client := dial(t, ingester)
+
+	checkin := time.Date(2020, 10, 1, 10, 20, 0, 0, time.UTC)
+	event := func(id string, table string) *TransactionEvent {
+		return &TransactionEvent{
+			EventId:       id,
+			Park:          &Park{Id: 6, Slug: "monza"},
+			Ticket:        id,
+			Plate:         "ABC1234",
+			Checkin:       timestamppb.New(checkin),
+			Checkout:      timestamppb.New(checkin.Add(30 * time.Minute)),
+			PaidValue:     12.5,
+			PaymentMethod: "DINHEIRO",
+			Table:         table,
+		}
+	}
+
+	stream, err := client.IngestTransactions(context.Background())
+	require.Nil(t, err)
+
+	for _, e := range []*TransactionEvent{event("1", "NORMAL"), event("2", "VIP"), event("3", "NORMAL")} {
+		require.Nil(t, stream.Send(e))
+	}
+	require.Nil(t, stream.CloseSend())
+
+	acks := []*TransactionAck{}
+	for {
+		ack, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		acks = append(acks, ack)
+	}
+
+	require.Equal(t, 3, len(acks))
+	require.Equal(t, "1", acks[0].EventId)
+	require.True(t, acks[0].Accepted)
+	require.NotEqual(t, "", acks[0].TransactionId)
+	require.Equal(t, "2", acks[1].EventId)
+	require.False(t, acks[1].Accepted)
+	require.True(t, acks[1].Invalid)
+	require.Equal(t, "unknown table [VIP]", acks[1].Error)
+	require.True(t, acks[2].Accepted)
+
+	require.Equal(t, 2, len(ingester.lines))
+	require.Equal(t, &model.Line{
+		Ticket:        "1",
+		Matricula:     "ABC1234",
+		CheckIn:       checkin,
+		CheckOut:      checkin.Add(30 * time.Minute),
+		PaidValue:     12.5,
+		PaymentMethod: "DINHEIRO",
+		Table:         "NORMAL",
+	}, ingester.lines[0])
+}
+
+func TestServer_IngestTransactionsStoreDown(t *testing.T) {
+	client := dial(t, &fakeIngester{down: true})
+
+	stream, err := client.IngestTransactions(context.Background())
+	require.Nil(t, err)
+
+	require.Nil(t, stream.Send(&TransactionEvent{EventId: "1", Table: "NORMAL"}))
+	ack, err := stream.Recv()
+	require.Nil(t, err)
+
+	// the event may be sent again
+	require.False(t, ack.Accepted)
+	require.False(t, ack.Invalid)
+	require.Equal(t, "database is down", ack.Error)
+}
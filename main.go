@@ -2,87 +2,100 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
-	"flag"
 	"fmt"
+	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
-	"github.com/csv-processor/business"
-	"github.com/csv-processor/model"
-	"github.com/csv-processor/mongo"
-
-	"os"
-
 	"go.uber.org/zap"
 )
 
-var (
-	processFile string
-	parkname    string
-	parkslug    string
-	parkid      int64
-	filetype    string
-
-	log *zap.Logger
-)
-
-func init() {
-	flag.StringVar(&processFile, "csvFile", "/home/user/file.csv", "path to file with CSV to parse")
-	flag.StringVar(&filetype, "filetype", "transactions", "file type to be processed")
-	flag.StringVar(&parkname, "parkname", "Monza", "the name of park to get business logic")
-	flag.StringVar(&parkslug, "parkslug", "monza", "the slug of park to get business logic")
-	flag.Int64Var(&parkid, "parkid", 6, "the id of park to get business logic")
-
-	required := []string{"csvFile", "parkname", "filetype", "parkslug", "parkid"}
-	flag.Parse()
-
-	seen := make(map[string]bool)
-	flag.Visit(func(f *flag.Flag) { seen[f.Name] = true })
-	for _, req := range required {
-		if !seen[req] {
-			fmt.Fprintf(os.Stderr, "missing required [-%s] argument/flag\n", req)
-			os.Exit(2)
-		}
+var log *zap.Logger
+
+// commands returns every subcommand of the CLI, in the order shown on usage
+func commands() []*command {
+	return []*command{
+		importCommand(),
+		validateCommand(),
+		rollbackCommand(),
+		exportCommand(),
+		reportCommand(),
+		rebuildRollupsCommand(),
+		occupancyCheckCommand(),
+		overlapsCommand(),
+		visitsCommand(),
+		migrateCommand(),
+		normalizePlatesCommand(),
+		forgetCommand(),
+		purgeCommand(),
+		serveCommand(),
+		parksCommand(),
+		parkShowCommand(),
+		parkAddCommand(),
+		parkUpdateCommand(),
+		parkRemoveCommand(),
 	}
+}
 
-	log, _ = zap.NewProduction()
+func usage(cmds []*command) {
+	fmt.Fprintf(os.Stderr, "usage: csv-processor <command> [flags]\n\ncommands:\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun csv-processor <command> -h for the flags of a command\n")
 }
 
 func main() {
-	log.Info("Starting parser")
+	log, _ = zap.NewProduction()
 	defer log.Sync()
 
-	db, err := mongo.NewConnection()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error connecting database: [%s]\n", err.Error())
+	cmds := commands()
+
+	args := os.Args[1:]
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		usage(cmds)
 		os.Exit(2)
 	}
 
-	csvIn, err := os.Open(processFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error opening file [%s]: [%s]\n", processFile, err.Error())
-		os.Exit(2)
+	// flags without a command keep the original import invocation, with the
+	// deprecated -parkid, -parkslug and -parkname, working
+	if strings.HasPrefix(args[0], "-") {
+		args = append([]string{"import"}, args...)
 	}
-	defer csvIn.Close()
-	reader := csv.NewReader(csvIn)
 
-	parking := model.Parking{
-		Name: parkname,
-		Slug: parkslug,
-		ID:   parkid,
+	var cmd *command
+	for _, c := range cmds {
+		if c.name == args[0] {
+			cmd = c
+		}
+	}
+
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command [%s]\n\n", args[0])
+		usage(cmds)
+		os.Exit(2)
 	}
 
-	processor := business.NewVP(db, reader, filetype, parking)
-	err = processor.Process(context.Background())
+	err := cmd.parse(args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error processing file [%s]: [%s]\n", processFile, err.Error())
+		fmt.Fprintf(os.Stderr, "%s\n\n", err.Error())
+		cmd.flags.Usage()
 		os.Exit(2)
 	}
 
-	termChan := make(chan os.Signal)
+	// a signal cancels the command, which stops at a point it can resume from
+	ctx, cancel := context.WithCancel(context.Background())
+	termChan := make(chan os.Signal, 1)
 	signal.Notify(termChan, syscall.SIGINT, syscall.SIGTERM)
-	<-termChan // Blocks here until either SIGINT or SIGTERM is received.
+	go func() {
+		<-termChan
+		log.Info("Finishing...")
+		cancel()
+	}()
 
-	log.Info("Finishing...")
+	err = cmd.run(ctx, cmd)
+	if err != nil {
+		fail(err)
+	}
 }
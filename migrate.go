@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/csv-processor/business"
+)
+
+func migrateCommand() *command {
+	cmd := newCommand("migrate", "creates the indexes and upgrades documents stored with older schemas")
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		// connecting creates the indexes of every collection
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		total, err := db.TransactionCollection.MigrateSchema(ctx)
+		if err != nil {
+			return fmt.Errorf("error migrating transactions: [%s]", err.Error())
+		}
+
+		log.Sugar().Infow("Transactions migrated", "transactions", total)
+
+		return nil
+	}
+
+	return cmd
+}
+
+func normalizePlatesCommand() *command {
+	cmd := newCommand("normalize-plates", "normalizes the plates of the stored transactions, keeping the raw values")
+
+	dryRun := cmd.flags.Bool("dry-run", false, "only count the transactions whose plate would change")
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		total, changed, err := business.NewPlateMigration(db).Normalize(ctx, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		log.Sugar().Infow("Plates normalized", "transactions", total, "changed", changed, "dry_run", *dryRun)
+
+		return nil
+	}
+
+	return cmd
+}
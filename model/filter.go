@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// TransactionFilter narrows the transactions returned by a query,
+// nil fields are not filtered
+type TransactionFilter struct {
+	ParkingID *int64
+	// From is the first checkout date included
+	From *time.Time
+	// To is the first checkout date excluded
+	To     *time.Time
+	Status *int
+	// Matriculas are the values the plate of the vehicle is stored as,
+	// one per pseudonymization key
+	Matriculas    []string
+	PaymentMethod *string
+	UseType       *string
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ImportRunning is an import still being processed
+	ImportRunning = "running"
+	// ImportDone is an import that finished reading the whole file
+	ImportDone = "done"
+	// ImportFailed is an import stopped by an error
+	ImportFailed = "failed"
+	// ImportRolledBack is an import whose transactions were deleted
+	ImportRolledBack = "rolled_back"
+)
+
+// Import is the ledger record of a file loaded into the database, every
+// transaction it created carries its ID
+type Import struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Path        string             `bson:"path" json:"path"`
+	Checksum    string             `bson:"checksum" json:"checksum"`
+	Filetype    string             `bson:"filetype" json:"filetype"`
+	ParkingInfo Parking            `bson:"parking_info" json:"parking_info"`
+	Status      string             `bson:"status" json:"status"`
+	Summary     ImportSummary      `bson:"summary" json:"summary"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	Operator    string             `bson:"operator" json:"operator"`
+	StartedAt   time.Time          `bson:"started_at" json:"started_at"`
+	FinishedAt  *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	Checkpoint  *Checkpoint        `bson:"checkpoint,omitempty" json:"checkpoint,omitempty"`
+	// RolledBack counts the transactions deleted by the rollback
+	RolledBack int64 `bson:"rolled_back,omitempty" json:"rolled_back,omitempty"`
+	// Reopened counts the stays the import closed that the rollback reopened
+	Reopened     int64      `bson:"reopened,omitempty" json:"reopened,omitempty"`
+	RolledBackAt *time.Time `bson:"rolled_back_at,omitempty" json:"rolled_back_at,omitempty"`
+
+	Version   int        `bson:"version" json:"version"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// ImportSummary counts the rows of an imported file
+type ImportSummary struct {
+	Rows     int64 `bson:"rows" json:"rows"`
+	Inserted int64 `bson:"inserted" json:"inserted"`
+	Skipped  int64 `bson:"skipped" json:"skipped"`
+	Failed   int64 `bson:"failed" json:"failed"`
+	// Rejected counts the rows that could not be read or converted
+	Rejected int64 `bson:"rejected" json:"rejected"`
+	// Open counts the rows of stays without a checkout, stored as open
+	// transactions unless already open
+	Open int64 `bson:"open" json:"open"`
+	// Closed counts the rows that closed the open stay of an earlier import,
+	// which are not inserted
+	Closed int64 `bson:"closed" json:"closed"`
+	// Overlapping counts the rows whose stay overlaps another stay of the
+	// same plate in the park, both flagged as deviations
+	Overlapping int64 `bson:"overlapping" json:"overlapping"`
+}
+
+// Checkpoint is how far an import went, every row up to Line, which ends
+// Offset bytes into the file, was persisted
+type Checkpoint struct {
+	Line    int64         `bson:"line" json:"line"`
+	Offset  int64         `bson:"offset" json:"offset"`
+	Summary ImportSummary `bson:"summary" json:"summary"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// HourOccupancy is how many vehicles were parked during an hour
+type HourOccupancy struct {
+	Hour     time.Time `bson:"_id" json:"hour"`
+	Vehicles int64     `bson:"vehicles" json:"vehicles"`
+	// UseTypes is how many of the vehicles were of each use type
+	UseTypes map[string]int64 `bson:"use_types" json:"use_types"`
+}
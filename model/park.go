@@ -0,0 +1,84 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Park is the registry record of a park, whose identity the transactions
+// and imports of the park carry as their Parking
+type Park struct {
+	ID   int64  `bson:"_id" json:"id"`
+	Name string `bson:"name" json:"name"`
+	Slug string `bson:"slug" json:"slug"`
+	// Timezone is the IANA name of the time zone of the park, UTC when empty.
+	// The dates of its transactions are its wall clock time already
+	Timezone string `bson:"timezone" json:"timezone"`
+	// Capacity is how many vehicles fit in the park, 0 when unknown
+	Capacity int64 `bson:"capacity" json:"capacity"`
+	// Capacities is how many vehicles of a use type fit in the park, by
+	// use type, for the spaces kept for them
+	Capacities map[string]int64 `bson:"capacities,omitempty" json:"capacities,omitempty"`
+	Address    string           `bson:"address" json:"address"`
+	// Config holds the settings of the park by name
+	Config map[string]string `bson:"config,omitempty" json:"config,omitempty"`
+	// RetentionDays is how many days after their checkout the transactions
+	// of the park are kept, forever when 0
+	RetentionDays int64 `bson:"retention_days" json:"retention_days"`
+	// DeletedRetentionDays is how many days after being deleted the
+	// transactions of the park are kept, forever when 0
+	DeletedRetentionDays int64 `bson:"deleted_retention_days" json:"deleted_retention_days"`
+
+	Version   int        `bson:"version" json:"version"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// Parking returns the identity of the park
+func (p Park) Parking() Parking {
+	return Parking{ID: p.ID, Name: p.Name, Slug: p.Slug}
+}
+
+// Location returns the time zone of the park
+func (p Park) Location() (*time.Location, error) {
+	return time.LoadLocation(p.Timezone)
+}
+
+// Validate validates the model
+func (p Park) Validate() error {
+	errs := []string{}
+
+	if p.ID <= 0 {
+		errs = append(errs, fmt.Sprintf("id [%d] must be positive", p.ID))
+	}
+	if p.Slug == "" || strings.TrimSpace(p.Slug) != p.Slug || strings.Contains(p.Slug, " ") {
+		errs = append(errs, fmt.Sprintf("slug [%s] must be a word", p.Slug))
+	}
+	if p.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if _, err := p.Location(); err != nil {
+		errs = append(errs, fmt.Sprintf("timezone [%s] is unknown", p.Timezone))
+	}
+	if p.Capacity < 0 {
+		errs = append(errs, fmt.Sprintf("capacity [%d] must not be negative", p.Capacity))
+	}
+	if p.RetentionDays < 0 {
+		errs = append(errs, fmt.Sprintf("retention [%d] must not be negative", p.RetentionDays))
+	}
+	if p.DeletedRetentionDays < 0 {
+		errs = append(errs, fmt.Sprintf("retention of deleted transactions [%d] must not be negative", p.DeletedRetentionDays))
+	}
+	for useType, capacity := range p.Capacities {
+		if capacity <= 0 {
+			errs = append(errs, fmt.Sprintf("capacity [%d] of [%s] must be positive", capacity, useType))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ". "))
+	}
+
+	return nil
+}
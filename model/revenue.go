@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DailyRevenue is the revenue rollup of a park on a day for a payment method
+type DailyRevenue struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	ParkingInfo   Parking            `bson:"parking_info"`
+	Day           time.Time          `bson:"day"`
+	PaymentMethod string             `bson:"payment_method"`
+	Tickets       int64              `bson:"tickets"`
+	PaidTotal     float64            `bson:"paid_total"`
+	DiscountTotal float64            `bson:"discount_total"`
+	DurationTotal int64              `bson:"duration_total"`
+
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// AverageStay returns the average stay in minutes
+func (r DailyRevenue) AverageStay() float64 {
+	if r.Tickets == 0 {
+		return 0
+	}
+
+	return float64(r.DurationTotal) / float64(r.Tickets)
+}
+
+// RevenueDay returns the day a transaction is accounted to
+func RevenueDay(t Transaction) time.Time {
+	co := t.CheckoutDate.UTC()
+	return time.Date(co.Year(), co.Month(), co.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -9,40 +9,64 @@ import (
 )
 
 type Transaction struct {
-	ID               primitive.ObjectID `bson:"_id,omitempty"`
-	TimeIntervalHour []time.Time        `bson:"time_interval_hour"`
-	CheckinDate      time.Time          `bson:"checkin_date"`
-	CheckoutDate     time.Time          `bson:"checkout_date"`
-	PaymentDate      time.Time          `bson:"payment_date"`
-	FareAmount       float64            `bson:"fare_amount"`
-	FareName         string             `bson:"fare_name"`
-	PaidAmount       float64            `bson:"paid_amount"`
-	Discount         float64            `bson:"discount"`
-	PaymentData      string             `bson:"payment_data"`
-	PaymentMethod    string             `bson:"payment_method"`
-	UseType          string             `bson:"use_type"`
-	OfferType        string             `bson:"offer_type"`
-	Duration         float64            `bson:"duration"`
-	IsValid          bool               `bson:"is_valid"`
-	ParkingInfo      Parking            `bson:"parking_info"`
-	Status           int                `bson:"status"`
-	CashRegisterID   int64              `bson:"cash_register_id"`
-	Sequence         string             `bson:"sequence"`
-	Fiscal           string             `bson:"fiscal"`
-	Partial          string             `bson:"partial"`
-	Matricula        string             `bson:"matricula"`
-	Categoria        string             `bson:"categoria"`
-
-	Version   int        `bson:"version"`
-	Schema    int        `bson:"schema"`
-	CreatedAt time.Time  `bson:"created_at"`
-	UpdatedAt *time.Time `bson:"updated_at,omitempty"`
-	DeletedAt *time.Time `bson:"deleted_at,omitempty"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	TimeIntervalHour []time.Time        `bson:"time_interval_hour" json:"time_interval_hour"`
+	CheckinDate      time.Time          `bson:"checkin_date" json:"checkin_date"`
+	CheckoutDate     time.Time          `bson:"checkout_date" json:"checkout_date"`
+	PaymentDate      time.Time          `bson:"payment_date" json:"payment_date"`
+	FareAmount       float64            `bson:"fare_amount" json:"fare_amount"`
+	FareName         string             `bson:"fare_name" json:"fare_name"`
+	PaidAmount       float64            `bson:"paid_amount" json:"paid_amount"`
+	Discount         float64            `bson:"discount" json:"discount"`
+	PaymentData      string             `bson:"payment_data" json:"payment_data"`
+	PaymentMethod    string             `bson:"payment_method" json:"payment_method"`
+	UseType          string             `bson:"use_type" json:"use_type"`
+	OfferType        string             `bson:"offer_type" json:"offer_type"`
+	Duration         int64              `bson:"duration" json:"duration"`
+	Buckets          float64            `bson:"buckets" json:"buckets"`
+	IsValid          bool               `bson:"is_valid" json:"is_valid"`
+	ParkingInfo      Parking            `bson:"parking_info" json:"parking_info"`
+	Status           int                `bson:"status" json:"status"`
+	CashRegisterID   int64              `bson:"cash_register_id" json:"cash_register_id"`
+	Sequence         string             `bson:"sequence" json:"sequence"`
+	Fiscal           string             `bson:"fiscal" json:"fiscal"`
+	Partial          string             `bson:"partial" json:"partial"`
+	// Unit is the unit of the park the row was read with
+	Unit string `bson:"unit,omitempty" json:"unit,omitempty"`
+	// Matricula is the normalized plate, upper-cased without separators
+	Matricula string `bson:"matricula" json:"matricula"`
+	// MatriculaRaw is the plate as it was read
+	MatriculaRaw string `bson:"matricula_raw,omitempty" json:"matricula_raw,omitempty"`
+	// PlateFormat is old, mercosul or unknown, empty without a plate
+	PlateFormat string `bson:"plate_format,omitempty" json:"plate_format,omitempty"`
+	// Identity is the identity document of the customer
+	Identity string `bson:"identity,omitempty" json:"identity,omitempty"`
+	// KeyID is the key the plate and identity were hashed with, empty when
+	// they are stored in clear
+	KeyID      string             `bson:"key_id,omitempty" json:"key_id,omitempty"`
+	Categoria  string             `bson:"categoria" json:"categoria"`
+	ImportID   primitive.ObjectID `bson:"import_id,omitempty" json:"import_id,omitempty"`
+	ImportLine int64              `bson:"import_line,omitempty" json:"import_line,omitempty"`
+	// ClosedImportID and ClosedImportLine are the row that closed an open stay
+	ClosedImportID   primitive.ObjectID `bson:"closed_import_id,omitempty" json:"closed_import_id,omitempty"`
+	ClosedImportLine int64              `bson:"closed_import_line,omitempty" json:"closed_import_line,omitempty"`
+	// OverlapsWith links the stays of the same plate in the same park
+	// overlapping this one
+	OverlapsWith []primitive.ObjectID `bson:"overlaps_with,omitempty" json:"overlaps_with,omitempty"`
+	// PriorStatus is the status of the stay before it was flagged as
+	// overlapping, set back once it overlaps no stay anymore
+	PriorStatus *int `bson:"prior_status,omitempty" json:"prior_status,omitempty"`
+
+	Version   int        `bson:"version" json:"version"`
+	Schema    int        `bson:"schema" json:"schema"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 }
 
 // SchemaVersion returns the schema version
 func (t Transaction) SchemaVersion() int {
-	return 1
+	return 2
 }
 
 // Validate validates the model
@@ -57,7 +81,7 @@ func (t Transaction) Validate() error {
 }
 
 type Parking struct {
-	ID   int64  `bson:"id"`
-	Name string `bson:"name"`
-	Slug string `bson:"slug"`
+	ID   int64  `bson:"id" json:"id"`
+	Name string `bson:"name" json:"name"`
+	Slug string `bson:"slug" json:"slug"`
 }
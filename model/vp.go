@@ -3,6 +3,8 @@ package model
 import "time"
 
 type Line struct {
+	// Number is the record number of the line in its file
+	Number        int64
 	Unit          string
 	Ticket        string
 	Identity      string
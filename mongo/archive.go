@@ -0,0 +1,86 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/csv-processor/errors"
+	"github.com/csv-processor/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const archiveCollection = "transactions_archive"
+
+// ArchiveCollection represents the collection the purged transactions are
+// archived to
+type ArchiveCollection struct {
+	access *mongo.Collection
+}
+
+// NewArchiveCollection returns the archive collection access
+func NewArchiveCollection(ctx context.Context, database *mongo.Database) (*ArchiveCollection, error) {
+	archiveCol := database.Collection(archiveCollection)
+	if archiveCol == nil {
+		return nil, errors.ErrorCollectionNotFound(archiveCollection)
+	}
+
+	_, err := archiveCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "parking_info.id", Value: 1},
+				{Key: "checkout_date", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrorCreatingIndexes(err)
+	}
+
+	return &ArchiveCollection{access: archiveCol}, nil
+}
+
+// Archive stores transactions as they are, keeping their IDs. Archiving a
+// transaction again replaces it
+func (ac ArchiveCollection) Archive(ctx context.Context, transactions []model.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	writes := []mongo.WriteModel{}
+	for _, transaction := range transactions {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": transaction.ID}).
+			SetReplacement(transaction).
+			SetUpsert(true))
+	}
+
+	_, err := ac.access.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return errors.ErrorInserting(archiveCollection, err)
+	}
+
+	return nil
+}
+
+// Count counts the archived transactions of a park
+func (ac ArchiveCollection) Count(ctx context.Context, parking int64) (int64, error) {
+	count, err := ac.access.CountDocuments(ctx, bson.M{"parking_info.id": parking})
+	if err != nil {
+		return 0, errors.ErrorCounting(archiveCollection, err)
+	}
+
+	return count, nil
+}
+
+// ErasePlates erases the plate, identity and overlap links of every archived
+// transaction stored with any of plates, returning how many were erased
+func (ac ArchiveCollection) ErasePlates(ctx context.Context, plates []string) (int64, error) {
+	result, err := ac.access.UpdateMany(ctx, bson.M{"matricula": bson.M{"$in": plates}}, erasePlatesUpdate())
+	if err != nil {
+		return 0, errors.ErrorUpdating(archiveCollection, err)
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/csv-processor/config"
 	"github.com/csv-processor/errors"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -12,13 +13,25 @@ import (
 
 // DB holds connection with db colletions
 type DB struct {
-	TransactionCollection TransactionCollection
+	TransactionCollection  TransactionCollection
+	DailyRevenueCollection DailyRevenueCollection
+	ImportCollection       ImportCollection
+	ParkingCollection      ParkingCollection
+	ArchiveCollection      ArchiveCollection
 }
 
-// NewConnection starts the connection with database
+// NewConnection starts the connection with database configured in the environment
 func NewConnection() (*DB, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("LOTS_API_MONGO_CONN")))
+	return Connect(config.Mongo{
+		Conn: os.Getenv(config.EnvMongoConn),
+		DB:   os.Getenv(config.EnvMongoDB),
+	})
+}
+
+// Connect starts the connection with database
+func Connect(settings config.Mongo) (*DB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), settings.ConnectTimeout())
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(settings.Conn))
 
 	defer cancel()
 
@@ -26,9 +39,9 @@ func NewConnection() (*DB, error) {
 		return nil, errors.ErrorGettingDBConnection(err)
 	}
 
-	database := client.Database(os.Getenv("LOTS_API_MONGO_DB"))
+	database := client.Database(settings.DB)
 	if database == nil {
-		return nil, errors.ErrorDBNotFound(os.Getenv("LOTS_API_MONGO_DB"))
+		return nil, errors.ErrorDBNotFound(settings.DB)
 	}
 
 	transactionCol, err := NewTransactionCollection(ctx, database)
@@ -36,8 +49,32 @@ func NewConnection() (*DB, error) {
 		return nil, err
 	}
 
+	dailyRevenueCol, err := NewDailyRevenueCollection(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	importCol, err := NewImportCollection(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	parkingCol, err := NewParkingCollection(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveCol, err := NewArchiveCollection(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
 	return &DB{
-		TransactionCollection: *transactionCol,
+		TransactionCollection:  *transactionCol,
+		DailyRevenueCollection: *dailyRevenueCol,
+		ImportCollection:       *importCol,
+		ParkingCollection:      *parkingCol,
+		ArchiveCollection:      *archiveCol,
 	}, nil
 }
 
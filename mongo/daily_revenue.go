@@ -0,0 +1,208 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/csv-processor/errors"
+	"github.com/csv-processor/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const dailyRevenueCollection = "daily_revenue"
+
+// DailyRevenueCollection represents the daily revenue rollup collection
+type DailyRevenueCollection struct {
+	access       *mongo.Collection
+	transactions *mongo.Collection
+}
+
+// NewDailyRevenueCollection returns the daily revenue collection access
+func NewDailyRevenueCollection(ctx context.Context, database *mongo.Database) (*DailyRevenueCollection, error) {
+	revenueCol := database.Collection(dailyRevenueCollection)
+	if revenueCol == nil {
+		return nil, errors.ErrorCollectionNotFound(dailyRevenueCollection)
+	}
+
+	unique := true
+	_, err := revenueCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "parking_info.id", Value: 1},
+				{Key: "day", Value: 1},
+				{Key: "payment_method", Value: 1},
+			},
+			Options: &options.IndexOptions{Unique: &unique},
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrorCreatingIndexes(err)
+	}
+
+	return &DailyRevenueCollection{
+		access:       revenueCol,
+		transactions: database.Collection(transactionCollection),
+	}, nil
+}
+
+// Increment adds a persisted transaction to its day rollup
+func (ac DailyRevenueCollection) Increment(ctx context.Context, transaction *model.Transaction) error {
+	if transaction == nil {
+		return errors.ErrorModelCannotBeNil(dailyRevenueCollection)
+	}
+
+	filter := bson.M{
+		"parking_info.id": transaction.ParkingInfo.ID,
+		"day":             model.RevenueDay(*transaction),
+		"payment_method":  transaction.PaymentMethod,
+	}
+
+	update := bson.M{
+		"$inc": bson.M{
+			"tickets":        1,
+			"paid_total":     transaction.PaidAmount,
+			"discount_total": transaction.Discount,
+			"duration_total": transaction.Duration,
+		},
+		"$set": bson.M{
+			"parking_info": transaction.ParkingInfo,
+			"updated_at":   time.Now(),
+		},
+	}
+
+	upsert := true
+	_, err := ac.access.UpdateOne(ctx, filter, update, &options.UpdateOptions{Upsert: &upsert})
+	if err != nil {
+		return errors.ErrorUpdating(dailyRevenueCollection, err)
+	}
+
+	return nil
+}
+
+// Rebuild recomputes the rollups of a park from the raw transactions
+// checked out between from and to, both days included. Each rollup is
+// replaced whole, then the ones left from before, of days or payment
+// methods without transactions anymore, are removed, so the days are never
+// missing while rebuilt. Increments of transactions persisted while the
+// rebuild runs may be replaced, rebuilding again accounts for them
+func (ac DailyRevenueCollection) Rebuild(ctx context.Context, parking int64, from time.Time, to time.Time) (int64, error) {
+	from = truncateDay(from)
+	to = truncateDay(to).AddDate(0, 0, 1)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"parking_info.id": parking,
+			"checkout_date":   bson.M{"$gte": from, "$lt": to},
+			"deleted_at":      bson.M{"$exists": false},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"day": bson.M{"$dateFromParts": bson.M{
+					"year":  bson.M{"$year": "$checkout_date"},
+					"month": bson.M{"$month": "$checkout_date"},
+					"day":   bson.M{"$dayOfMonth": "$checkout_date"},
+				}},
+				"payment_method": "$payment_method",
+			},
+			"parking_info":   bson.M{"$last": "$parking_info"},
+			"tickets":        bson.M{"$sum": 1},
+			"paid_total":     bson.M{"$sum": "$paid_amount"},
+			"discount_total": bson.M{"$sum": "$discount"},
+			"duration_total": bson.M{"$sum": "$duration"},
+		}}},
+	}
+
+	cursor, err := ac.transactions.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, errors.ErrorListing(transactionCollection, err)
+	}
+
+	var groups []struct {
+		Key struct {
+			Day           time.Time `bson:"day"`
+			PaymentMethod string    `bson:"payment_method"`
+		} `bson:"_id"`
+		ParkingInfo   model.Parking `bson:"parking_info"`
+		Tickets       int64         `bson:"tickets"`
+		PaidTotal     float64       `bson:"paid_total"`
+		DiscountTotal float64       `bson:"discount_total"`
+		DurationTotal int64         `bson:"duration_total"`
+	}
+	err = cursor.All(ctx, &groups)
+	if err != nil {
+		return 0, errors.ErrorListing(transactionCollection, err)
+	}
+
+	// the rollups rebuilt are the ones updated at now, the ones written
+	// by imports meanwhile are updated later
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(groups))
+	for _, g := range groups {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{
+				"parking_info.id": parking,
+				"day":             g.Key.Day,
+				"payment_method":  g.Key.PaymentMethod,
+			}).
+			SetReplacement(model.DailyRevenue{
+				ParkingInfo:   g.ParkingInfo,
+				Day:           g.Key.Day,
+				PaymentMethod: g.Key.PaymentMethod,
+				Tickets:       g.Tickets,
+				PaidTotal:     g.PaidTotal,
+				DiscountTotal: g.DiscountTotal,
+				DurationTotal: g.DurationTotal,
+				UpdatedAt:     now,
+			}).
+			SetUpsert(true))
+	}
+
+	if len(writes) > 0 {
+		_, err = ac.access.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return 0, errors.ErrorUpdating(dailyRevenueCollection, err)
+		}
+	}
+
+	_, err = ac.access.DeleteMany(ctx, bson.M{
+		"parking_info.id": parking,
+		"day":             bson.M{"$gte": from, "$lt": to},
+		"updated_at":      bson.M{"$lt": now},
+	})
+	if err != nil {
+		return 0, errors.ErrorDeleting(dailyRevenueCollection, err)
+	}
+
+	return int64(len(groups)), nil
+}
+
+// List returns the rollups of a park between from and to, both days included
+func (ac DailyRevenueCollection) List(ctx context.Context, parking int64, from time.Time, to time.Time) ([]model.DailyRevenue, error) {
+	filter := bson.M{
+		"parking_info.id": parking,
+		"day":             bson.M{"$gte": truncateDay(from), "$lt": truncateDay(to).AddDate(0, 0, 1)},
+	}
+
+	sort := bson.D{{Key: "day", Value: 1}, {Key: "payment_method", Value: 1}}
+	cursor, err := ac.access.Find(ctx, filter, &options.FindOptions{Sort: sort})
+	if err != nil {
+		return nil, errors.ErrorListing(dailyRevenueCollection, err)
+	}
+
+	var revenues []model.DailyRevenue
+	err = cursor.All(ctx, &revenues)
+	if err != nil {
+		return nil, errors.ErrorListing(dailyRevenueCollection, err)
+	}
+
+	return revenues, nil
+}
+
+// truncateDay returns the start of the UTC day of t
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,127 @@
+package mongo
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyRevenue_Increment(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	parking := model.Parking{ID: 1, Name: "Monza", Slug: "monza"}
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	items := []*model.Transaction{
+		{
+			ParkingInfo:   parking,
+			CheckoutDate:  day.Add(10 * time.Hour),
+			PaymentMethod: "Dinheiro",
+			PaidAmount:    10,
+			Discount:      2,
+			Duration:      30,
+		},
+		{
+			ParkingInfo:   parking,
+			CheckoutDate:  day.Add(20 * time.Hour),
+			PaymentMethod: "Dinheiro",
+			PaidAmount:    20,
+			Duration:      90,
+		},
+		{
+			ParkingInfo:   parking,
+			CheckoutDate:  day.Add(20 * time.Hour),
+			PaymentMethod: "Creditcard",
+			PaidAmount:    5,
+			Duration:      15,
+		},
+	}
+
+	for _, item := range items {
+		require.Nil(t, db.DailyRevenueCollection.Increment(context.Background(), item))
+	}
+
+	result, err := db.DailyRevenueCollection.List(context.Background(), parking.ID, day, day)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(result))
+
+	require.Equal(t, "Creditcard", result[0].PaymentMethod)
+	require.Equal(t, int64(1), result[0].Tickets)
+
+	require.Equal(t, "Dinheiro", result[1].PaymentMethod)
+	require.Equal(t, int64(2), result[1].Tickets)
+	require.Equal(t, float64(30), result[1].PaidTotal)
+	require.Equal(t, float64(2), result[1].DiscountTotal)
+	require.Equal(t, float64(60), result[1].AverageStay())
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestDailyRevenue_Rebuild(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	parking := model.Parking{ID: 1, Name: "Monza", Slug: "monza"}
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	now := time.Now()
+	items := []*model.Transaction{
+		{
+			ParkingInfo:   parking,
+			CheckoutDate:  day.Add(10 * time.Hour),
+			PaymentMethod: "Dinheiro",
+			PaidAmount:    10,
+			Duration:      30,
+		},
+		{
+			ParkingInfo:   parking,
+			CheckoutDate:  day.Add(34 * time.Hour),
+			PaymentMethod: "Dinheiro",
+			PaidAmount:    20,
+			Duration:      90,
+		},
+		{
+			ParkingInfo:   parking,
+			CheckoutDate:  day.Add(11 * time.Hour),
+			PaymentMethod: "Dinheiro",
+			PaidAmount:    50,
+			DeletedAt:     &now,
+		},
+	}
+
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	// a stale rollup that must be replaced and one that must be removed
+	require.Nil(t, db.DailyRevenueCollection.Increment(context.Background(), items[2]))
+	stale := &model.Transaction{ParkingInfo: parking, CheckoutDate: day.Add(12 * time.Hour), PaymentMethod: "Creditcard", PaidAmount: 5}
+	require.Nil(t, db.DailyRevenueCollection.Increment(context.Background(), stale))
+	// the rollup of the stale one is older than the rebuild
+	time.Sleep(10 * time.Millisecond)
+
+	total, err := db.DailyRevenueCollection.Rebuild(context.Background(), parking.ID, day, day.AddDate(0, 0, 1))
+	require.Nil(t, err)
+	require.Equal(t, int64(2), total)
+
+	result, err := db.DailyRevenueCollection.List(context.Background(), parking.ID, day, day)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(result))
+	require.Equal(t, int64(1), result[0].Tickets)
+	require.Equal(t, float64(10), result[0].PaidTotal)
+	require.Equal(t, parking, result[0].ParkingInfo)
+
+	require.Nil(t, DropDB(nil, nil))
+}
@@ -0,0 +1,165 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/csv-processor/errors"
+	"github.com/csv-processor/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const importCollection = "imports"
+
+// ImportCollection represents the imports ledger collection
+type ImportCollection struct {
+	access *mongo.Collection
+}
+
+// NewImportCollection returns the import collection access
+func NewImportCollection(ctx context.Context, database *mongo.Database) (*ImportCollection, error) {
+	importCol := database.Collection(importCollection)
+	if importCol == nil {
+		return nil, errors.ErrorCollectionNotFound(importCollection)
+	}
+
+	_, err := importCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"checksum": 1,
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrorCreatingIndexes(err)
+	}
+
+	return &ImportCollection{access: importCol}, nil
+}
+
+// Create creates a new import
+func (ac ImportCollection) Create(ctx context.Context, item *model.Import) (*model.Import, error) {
+	if item == nil {
+		return nil, errors.ErrorModelCannotBeNil(importCollection)
+	}
+
+	item.Version = 1
+	item.CreatedAt = time.Now()
+
+	result, err := ac.access.InsertOne(ctx, item)
+	if err != nil {
+		return nil, errors.ErrorInserting(importCollection, err)
+	}
+
+	item.ID = result.InsertedID.(primitive.ObjectID)
+
+	return item, nil
+}
+
+// Update updates an import
+func (ac ImportCollection) Update(ctx context.Context, item *model.Import) (*model.Import, error) {
+	if item == nil {
+		return nil, errors.ErrorModelCannotBeNil(importCollection)
+	}
+
+	now := time.Now()
+	item.Version = item.Version + 1
+	item.UpdatedAt = &now
+
+	filterVersion := bson.M{
+		"_id":     item.ID,
+		"version": item.Version - 1,
+	}
+
+	result, err := ac.access.UpdateOne(ctx, filterVersion, bson.M{"$set": item})
+	if err != nil {
+		return nil, errors.ErrorUpdating(importCollection, err)
+	}
+
+	if result.ModifiedCount == 0 {
+		return nil, errors.ErrorUpdating(importCollection, errors.ErrorDocumentMismatch(importCollection, item.ID.Hex()))
+	}
+
+	return item, nil
+}
+
+// GetByID gets an import by id
+func (ac ImportCollection) GetByID(ctx context.Context, id string) (*model.Import, error) {
+	itemID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.ErrorGetting(importCollection, errors.ErrorParsingObjectID(id))
+	}
+
+	found := ac.access.FindOne(ctx, bson.M{"_id": itemID})
+	result := new(model.Import)
+
+	err = found.Decode(result)
+
+	if err != nil && err.Error() != errors.NoDocumentsInResult().Error() {
+		return nil, errors.ErrorGetting(importCollection, err)
+	}
+
+	return result, nil
+}
+
+// GetDoneByChecksum gets the finished import of a file content
+func (ac ImportCollection) GetDoneByChecksum(ctx context.Context, checksum string) (*model.Import, error) {
+	filter := bson.M{
+		"checksum": checksum,
+		"status":   model.ImportDone,
+	}
+
+	found := ac.access.FindOne(ctx, filter)
+	result := new(model.Import)
+
+	err := found.Decode(result)
+
+	if err != nil && err.Error() != errors.NoDocumentsInResult().Error() {
+		return nil, errors.ErrorGetting(importCollection, err)
+	}
+
+	return result, nil
+}
+
+// SaveCheckpoint stores how far an import went, without changing its version
+func (ac ImportCollection) SaveCheckpoint(ctx context.Context, id primitive.ObjectID, checkpoint model.Checkpoint) error {
+	update := bson.M{
+		"$set": bson.M{
+			"checkpoint": checkpoint,
+			"summary":    checkpoint.Summary,
+		},
+	}
+
+	_, err := ac.access.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return errors.ErrorUpdating(importCollection, err)
+	}
+
+	return nil
+}
+
+// GetResumable gets the latest unfinished import of a file content that
+// has a checkpoint to resume from
+func (ac ImportCollection) GetResumable(ctx context.Context, checksum string) (*model.Import, error) {
+	filter := bson.M{
+		"checksum":   checksum,
+		"status":     bson.M{"$in": bson.A{model.ImportRunning, model.ImportFailed}},
+		"checkpoint": bson.M{"$exists": true},
+	}
+
+	sort := bson.D{{Key: "started_at", Value: -1}}
+	found := ac.access.FindOne(ctx, filter, &options.FindOneOptions{Sort: sort})
+	result := new(model.Import)
+
+	err := found.Decode(result)
+
+	if err != nil && err.Error() != errors.NoDocumentsInResult().Error() {
+		return nil, errors.ErrorGetting(importCollection, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package mongo
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/csv-processor/errors"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestImport_Update(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	saved, err := db.ImportCollection.Create(context.Background(), &model.Import{Path: "file.csv", Status: model.ImportRunning})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	saved.Status = model.ImportDone
+	updated, err := db.ImportCollection.Update(context.Background(), saved)
+	require.Nil(t, err)
+	require.Equal(t, 2, updated.Version)
+
+	updated.Version = 0
+	_, err = db.ImportCollection.Update(context.Background(), updated)
+	require.Equal(t, errors.ErrorUpdating(importCollection, errors.ErrorDocumentMismatch(importCollection, updated.ID.Hex())), err)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestImport_GetByID(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	saved, err := db.ImportCollection.Create(context.Background(), &model.Import{Path: "file.csv"})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	result, err := db.ImportCollection.GetByID(context.Background(), saved.ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, "file.csv", result.Path)
+
+	_, err = db.ImportCollection.GetByID(context.Background(), "error")
+	require.Equal(t, errors.ErrorGetting(importCollection, errors.ErrorParsingObjectID("error")), err)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestImport_GetDoneByChecksum(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	type TestRun struct {
+		name     string
+		create   func(run *TestRun) *model.Import
+		checksum string
+		found    bool
+	}
+
+	tt := []TestRun{
+		{
+			name: "running is not done",
+			create: func(run *TestRun) *model.Import {
+				item, err := db.ImportCollection.Create(context.Background(), &model.Import{Path: "running.csv", Checksum: "running", Status: model.ImportRunning})
+				if err != nil {
+					log.Panic(err)
+				}
+				return item
+			},
+			checksum: "running",
+		},
+		{
+			name: "other checksum",
+			create: func(run *TestRun) *model.Import {
+				item, err := db.ImportCollection.Create(context.Background(), &model.Import{Path: "changed.csv", Checksum: "changed", Status: model.ImportDone})
+				if err != nil {
+					log.Panic(err)
+				}
+				return item
+			},
+			checksum: "other",
+		},
+		{
+			name: "success",
+			create: func(run *TestRun) *model.Import {
+				item, err := db.ImportCollection.Create(context.Background(), &model.Import{Path: "done.csv", Checksum: "done", Status: model.ImportDone})
+				if err != nil {
+					log.Panic(err)
+				}
+				return item
+			},
+			checksum: "done",
+			found:    true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			saved := tc.create(&tc)
+			result, err := db.ImportCollection.GetDoneByChecksum(context.Background(), tc.checksum)
+
+			require.Nil(t, err)
+			if tc.found {
+				require.Equal(t, saved.ID, result.ID)
+			} else {
+				require.Equal(t, primitive.NilObjectID, result.ID)
+			}
+		})
+	}
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestImport_GetResumable(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	older, err := db.ImportCollection.Create(context.Background(), &model.Import{Path: "file.csv", Checksum: "file", Status: model.ImportFailed, StartedAt: time.Now().Add(-time.Hour)})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	saved, err := db.ImportCollection.Create(context.Background(), &model.Import{Path: "file.csv", Checksum: "file", Status: model.ImportRunning, StartedAt: time.Now()})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// imports without a checkpoint can not be resumed
+	result, err := db.ImportCollection.GetResumable(context.Background(), "file")
+	require.Nil(t, err)
+	require.Equal(t, primitive.NilObjectID, result.ID)
+
+	checkpoint := model.Checkpoint{Line: 10, Offset: 200, Summary: model.ImportSummary{Rows: 10, Inserted: 9, Skipped: 1}}
+	require.Nil(t, db.ImportCollection.SaveCheckpoint(context.Background(), older.ID, model.Checkpoint{Line: 5}))
+	require.Nil(t, db.ImportCollection.SaveCheckpoint(context.Background(), saved.ID, checkpoint))
+
+	result, err = db.ImportCollection.GetResumable(context.Background(), "file")
+	require.Nil(t, err)
+	require.Equal(t, saved.ID, result.ID)
+	require.Equal(t, checkpoint, *result.Checkpoint)
+	require.Equal(t, checkpoint.Summary, result.Summary)
+
+	require.Nil(t, DropDB(nil, nil))
+}
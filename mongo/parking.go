@@ -0,0 +1,162 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/csv-processor/errors"
+	"github.com/csv-processor/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const parkingCollection = "parkings"
+
+// ParkingCollection represents the park registry collection
+type ParkingCollection struct {
+	access *mongo.Collection
+}
+
+// NewParkingCollection returns the parking collection access
+func NewParkingCollection(ctx context.Context, database *mongo.Database) (*ParkingCollection, error) {
+	parkingCol := database.Collection(parkingCollection)
+	if parkingCol == nil {
+		return nil, errors.ErrorCollectionNotFound(parkingCollection)
+	}
+
+	unique := true
+	_, err := parkingCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"slug": 1,
+			},
+			Options: &options.IndexOptions{Unique: &unique},
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrorCreatingIndexes(err)
+	}
+
+	return &ParkingCollection{access: parkingCol}, nil
+}
+
+// Create creates a new park, whose ID and slug are not taken
+func (ac ParkingCollection) Create(ctx context.Context, item *model.Park) (*model.Park, error) {
+	if item == nil {
+		return nil, errors.ErrorModelCannotBeNil(parkingCollection)
+	}
+
+	err := item.Validate()
+	if err != nil {
+		return nil, errors.ErrorValidating(parkingCollection, err)
+	}
+
+	item.Version = 1
+	item.CreatedAt = time.Now()
+
+	_, err = ac.access.InsertOne(ctx, item)
+	if err != nil {
+		return nil, errors.ErrorInserting(parkingCollection, err)
+	}
+
+	return item, nil
+}
+
+// Update updates a park
+func (ac ParkingCollection) Update(ctx context.Context, item *model.Park) (*model.Park, error) {
+	if item == nil {
+		return nil, errors.ErrorModelCannotBeNil(parkingCollection)
+	}
+
+	err := item.Validate()
+	if err != nil {
+		return nil, errors.ErrorValidating(parkingCollection, err)
+	}
+
+	now := time.Now()
+	item.Version = item.Version + 1
+	item.UpdatedAt = &now
+
+	filterVersion := bson.M{
+		"_id":     item.ID,
+		"version": item.Version - 1,
+	}
+
+	// the maps emptied are left out of $set, so they are removed
+	update := bson.M{"$set": item}
+	unset := bson.M{}
+	if len(item.Capacities) == 0 {
+		unset["capacities"] = ""
+	}
+	if len(item.Config) == 0 {
+		unset["config"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := ac.access.UpdateOne(ctx, filterVersion, update)
+	if err != nil {
+		return nil, errors.ErrorUpdating(parkingCollection, err)
+	}
+
+	if result.ModifiedCount == 0 {
+		return nil, errors.ErrorUpdating(parkingCollection, errors.ErrorDocumentMismatch(parkingCollection, item.Slug))
+	}
+
+	return item, nil
+}
+
+// GetBySlug gets a park by slug, an empty one when there is none
+func (ac ParkingCollection) GetBySlug(ctx context.Context, slug string) (*model.Park, error) {
+	return ac.get(ctx, bson.M{"slug": slug})
+}
+
+// GetByID gets a park by id, an empty one when there is none
+func (ac ParkingCollection) GetByID(ctx context.Context, id int64) (*model.Park, error) {
+	return ac.get(ctx, bson.M{"_id": id})
+}
+
+func (ac ParkingCollection) get(ctx context.Context, filter bson.M) (*model.Park, error) {
+	found := ac.access.FindOne(ctx, filter)
+	result := new(model.Park)
+
+	err := found.Decode(result)
+
+	if err != nil && err.Error() != errors.NoDocumentsInResult().Error() {
+		return nil, errors.ErrorGetting(parkingCollection, err)
+	}
+
+	return result, nil
+}
+
+// List returns every park ordered by slug
+func (ac ParkingCollection) List(ctx context.Context) ([]model.Park, error) {
+	sort := bson.D{{Key: "slug", Value: 1}}
+	cursor, err := ac.access.Find(ctx, bson.M{}, &options.FindOptions{Sort: sort})
+
+	if err != nil {
+		return nil, errors.ErrorListing(parkingCollection, err)
+	}
+
+	var parks []model.Park
+	err = cursor.All(ctx, &parks)
+	if err != nil {
+		return nil, errors.ErrorListing(parkingCollection, err)
+	}
+
+	return parks, nil
+}
+
+// Delete deletes a park from the registry, the transactions of the park
+// keep its identity, and returns whether it existed
+func (ac ParkingCollection) Delete(ctx context.Context, slug string) (bool, error) {
+	result, err := ac.access.DeleteOne(ctx, bson.M{"slug": slug})
+	if err != nil {
+		return false, errors.ErrorDeleting(parkingCollection, err)
+	}
+
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,146 @@
+package mongo
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/csv-processor/errors"
+	"github.com/csv-processor/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParking_Create(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	type TestRun struct {
+		name          string
+		park          *model.Park
+		expectedError bool
+	}
+
+	tt := []TestRun{
+		{
+			name: "success",
+			park: &model.Park{ID: 6, Name: "Monza", Slug: "monza", Timezone: "America/Sao_Paulo", Capacity: 120},
+		},
+		{
+			name:          "slug taken",
+			park:          &model.Park{ID: 7, Name: "Monza", Slug: "monza"},
+			expectedError: true,
+		},
+		{
+			name:          "id taken",
+			park:          &model.Park{ID: 6, Name: "Centro", Slug: "centro"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown timezone",
+			park:          &model.Park{ID: 8, Name: "Centro", Slug: "centro", Timezone: "Mars/Olympus"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := db.ParkingCollection.Create(context.Background(), tc.park)
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+
+			require.Nil(t, err)
+			found, err := db.ParkingCollection.GetBySlug(context.Background(), tc.park.Slug)
+			require.Nil(t, err)
+			require.Equal(t, tc.park.Parking(), found.Parking())
+		})
+	}
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestParking_Update(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	saved, err := db.ParkingCollection.Create(context.Background(), &model.Park{ID: 6, Name: "Monza", Slug: "monza"})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	saved.Capacity = 80
+	saved.Capacities = map[string]int64{"Mensalista": 40}
+	saved.Config = map[string]string{"grace": "15"}
+	updated, err := db.ParkingCollection.Update(context.Background(), saved)
+	require.Nil(t, err)
+	require.Equal(t, 2, updated.Version)
+
+	found, err := db.ParkingCollection.GetByID(context.Background(), 6)
+	require.Nil(t, err)
+	require.Equal(t, int64(80), found.Capacity)
+	require.Equal(t, map[string]int64{"Mensalista": 40}, found.Capacities)
+	require.Equal(t, map[string]string{"grace": "15"}, found.Config)
+
+	// removing the last capacity removes them all
+	updated.Capacities = nil
+	updated, err = db.ParkingCollection.Update(context.Background(), updated)
+	require.Nil(t, err)
+
+	found, err = db.ParkingCollection.GetByID(context.Background(), 6)
+	require.Nil(t, err)
+	require.Nil(t, found.Capacities)
+	require.Equal(t, map[string]string{"grace": "15"}, found.Config)
+
+	// so does removing the last setting
+	updated.Config = nil
+	updated, err = db.ParkingCollection.Update(context.Background(), updated)
+	require.Nil(t, err)
+
+	found, err = db.ParkingCollection.GetByID(context.Background(), 6)
+	require.Nil(t, err)
+	require.Nil(t, found.Config)
+
+	updated.Version = 0
+	_, err = db.ParkingCollection.Update(context.Background(), updated)
+	require.Equal(t, errors.ErrorUpdating(parkingCollection, errors.ErrorDocumentMismatch(parkingCollection, "monza")), err)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestParking_ListDelete(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	for _, park := range []*model.Park{{ID: 7, Name: "Monza", Slug: "monza"}, {ID: 6, Name: "Centro", Slug: "centro"}} {
+		_, err := db.ParkingCollection.Create(context.Background(), park)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	parks, err := db.ParkingCollection.List(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 2, len(parks))
+	require.Equal(t, "centro", parks[0].Slug)
+
+	removed, err := db.ParkingCollection.Delete(context.Background(), "centro")
+	require.Nil(t, err)
+	require.True(t, removed)
+
+	removed, err = db.ParkingCollection.Delete(context.Background(), "centro")
+	require.Nil(t, err)
+	require.False(t, removed)
+
+	found, err := db.ParkingCollection.GetBySlug(context.Background(), "centro")
+	require.Nil(t, err)
+	require.Equal(t, int64(0), found.ID)
+
+	require.Nil(t, DropDB(nil, nil))
+}
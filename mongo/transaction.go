@@ -33,6 +33,44 @@ func NewTransactionCollection(ctx context.Context, database *mongo.Database) (*T
 				"_id": 1,
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "parking_info.id", Value: 1},
+				{Key: "checkout_date", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "import_id", Value: 1},
+				{Key: "import_line", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "parking_info.id", Value: 1},
+				{Key: "sequence", Value: 1},
+				{Key: "matricula", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "parking_info.id", Value: 1},
+				{Key: "matricula", Value: 1},
+				{Key: "checkin_date", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "matricula", Value: 1},
+				{Key: "checkout_date", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "parking_info.id", Value: 1},
+				{Key: "deleted_at", Value: 1},
+			},
+		},
 	})
 	if err != nil {
 		return nil, errors.ErrorCreatingIndexes(err)
@@ -123,16 +161,33 @@ func (ac TransactionCollection) GetByID(ctx context.Context, id string) (*model.
 	return result, nil
 }
 
-// GetByTicketAndMatricula gets an transaction by ticket and matricula
-func (ac TransactionCollection) GetByTicketAndMatricula(ctx context.Context, ticket string, parking int64, matricula string) (*model.Transaction, error) {
+// GetByTicketAndMatricula gets an transaction by ticket and any of the
+// values its matricula may be stored as. Tickets are recycled, so the stay
+// with the open status is preferred, then the one checking in last
+func (ac TransactionCollection) GetByTicketAndMatricula(ctx context.Context, ticket string, parking int64, matriculas []string, open int) (*model.Transaction, error) {
 	filter := bson.M{
 		"sequence":        ticket,
-		"matricula":       matricula,
+		"matricula":       bson.M{"$in": matriculas},
 		"parking_info.id": parking,
+		"status":          open,
 		"deleted_at":      bson.M{"$exists": false},
 	}
 
-	found := ac.access.FindOne(ctx, filter)
+	latest := &options.FindOneOptions{Sort: bson.D{{Key: "checkin_date", Value: -1}}}
+	result, err := ac.findOne(ctx, filter, latest)
+	if err != nil || !result.ID.IsZero() {
+		return result, err
+	}
+
+	delete(filter, "status")
+
+	return ac.findOne(ctx, filter, latest)
+}
+
+// findOne gets the first transaction matching filter, an empty one when
+// there is none
+func (ac TransactionCollection) findOne(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*model.Transaction, error) {
+	found := ac.access.FindOne(ctx, filter, opts)
 	result := new(model.Transaction)
 
 	err := found.Decode(result)
@@ -188,20 +243,202 @@ func (ac TransactionCollection) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-// List returns a list of transactions
-func (ac TransactionCollection) List(ctx context.Context, page int64, size int64, filters map[string]interface{}) ([]model.Transaction, error) {
+// List returns a page, from 1, of the transactions matching filter,
+// ordered by checkout
+func (ac TransactionCollection) List(ctx context.Context, page int64, size int64, filter model.TransactionFilter) ([]model.Transaction, error) {
+	skip := (page - 1) * size
+	sort := bson.D{{Key: "checkout_date", Value: 1}, {Key: "_id", Value: 1}}
+	cursor, err := ac.access.Find(ctx, transactionFilter(filter), &options.FindOptions{Skip: &skip, Limit: &size, Sort: sort})
+
+	if err != nil {
+		return nil, errors.ErrorListing(transactionCollection, err)
+	}
+
+	var transactions []model.Transaction
+	err = cursor.All(ctx, &transactions)
+	if err != nil {
+		return nil, errors.ErrorListing(transactionCollection, err)
+	}
+
+	return transactions, nil
+}
+
+// Count returns how many transactions match filter
+func (ac TransactionCollection) Count(ctx context.Context, filter model.TransactionFilter) (int64, error) {
+	counter, err := ac.access.CountDocuments(ctx, transactionFilter(filter))
+
+	if err != nil {
+		return 0, errors.ErrorCounting(transactionCollection, err)
+	}
+
+	return counter, nil
+}
+
+// Occupancy returns how many vehicles, of each use type, were parked in a
+// park during each hour from from to to, excluded, the hours without any
+// left out
+func (ac TransactionCollection) Occupancy(ctx context.Context, parking int64, from time.Time, to time.Time) ([]model.HourOccupancy, error) {
+	hours := bson.M{"$gte": from, "$lt": to}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"parking_info.id":    parking,
+			"time_interval_hour": bson.M{"$elemMatch": hours},
+			"deleted_at":         bson.M{"$exists": false},
+		}}},
+		{{Key: "$unwind", Value: "$time_interval_hour"}},
+		{{Key: "$match", Value: bson.M{"time_interval_hour": hours}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"hour": "$time_interval_hour", "use_type": "$use_type"},
+			"vehicles": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$_id.hour",
+			"vehicles":  bson.M{"$sum": "$vehicles"},
+			"use_types": bson.M{"$push": bson.M{"k": "$_id.use_type", "v": "$vehicles"}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"use_types": bson.M{"$arrayToObject": "$use_types"}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := ac.access.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, errors.ErrorListing(transactionCollection, err)
+	}
+
+	occupancy := []model.HourOccupancy{}
+	err = cursor.All(ctx, &occupancy)
+	if err != nil {
+		return nil, errors.ErrorListing(transactionCollection, err)
+	}
+
+	return occupancy, nil
+}
+
+// Overlapping returns the stays of a plate, stored as any of matriculas, in
+// a park, but except, that overlap the stay from checkin to checkout, stays
+// without both dates left out
+func (ac TransactionCollection) Overlapping(ctx context.Context, parking int64, matriculas []string, checkin time.Time, checkout time.Time, except primitive.ObjectID) ([]model.Transaction, error) {
 	filter := bson.M{
-		"deleted_at": bson.M{"$exists": false},
+		"parking_info.id": parking,
+		"matricula":       bson.M{"$in": matriculas},
+		"checkin_date":    bson.M{"$gt": time.Time{}, "$lt": checkout},
+		"checkout_date":   bson.M{"$gt": checkin},
+		"_id":             bson.M{"$ne": except},
+		"deleted_at":      bson.M{"$exists": false},
 	}
 
-	skip := (page - 1) * size
-	cursor, err := ac.access.Find(ctx, filter, &options.FindOptions{Skip: &skip, Limit: &size})
+	sort := bson.D{{Key: "checkin_date", Value: 1}, {Key: "_id", Value: 1}}
+	cursor, err := ac.access.Find(ctx, filter, &options.FindOptions{Sort: sort})
+
+	if err != nil {
+		return nil, errors.ErrorCheckingOverlap(transactionCollection, err)
+	}
+
+	transactions := []model.Transaction{}
+	err = cursor.All(ctx, &transactions)
+	if err != nil {
+		return nil, errors.ErrorCheckingOverlap(transactionCollection, err)
+	}
+
+	return transactions, nil
+}
+
+// MarkOverlap sets the status of two overlapping stays and links each one
+// to the other, keeping the status they had before their first overlap
+func (ac TransactionCollection) MarkOverlap(ctx context.Context, first primitive.ObjectID, second primitive.ObjectID, status int) error {
+	now := time.Now()
+	for _, pair := range [][2]primitive.ObjectID{{first, second}, {second, first}} {
+		update := mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"prior_status": bson.M{"$ifNull": bson.A{"$prior_status", "$status"}},
+				"status":       status,
+				"overlaps_with": bson.M{"$setUnion": bson.A{
+					bson.M{"$ifNull": bson.A{"$overlaps_with", bson.A{}}},
+					bson.A{pair[1]},
+				}},
+				"updated_at": now,
+				"version":    bson.M{"$add": bson.A{"$version", 1}},
+			}}},
+		}
+
+		_, err := ac.access.UpdateOne(ctx, bson.M{"_id": pair[0]}, update)
+		if err != nil {
+			return errors.ErrorUpdating(transactionCollection, err)
+		}
+	}
+
+	return nil
+}
+
+// ErasePlates erases the plate, identity and overlap links of every
+// transaction, deleted or not, stored with any of plates, returning how
+// many were erased
+func (ac TransactionCollection) ErasePlates(ctx context.Context, plates []string) (int64, error) {
+	result, err := ac.access.UpdateMany(ctx, bson.M{"matricula": bson.M{"$in": plates}}, erasePlatesUpdate())
+	if err != nil {
+		return 0, errors.ErrorUpdating(transactionCollection, err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// erasePlatesUpdate is the update erasing the personal data of a
+// transaction, archived or not
+func erasePlatesUpdate() bson.M {
+	return bson.M{
+		"$set": bson.M{
+			"matricula":  "",
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{
+			"matricula_raw": "",
+			"plate_format":  "",
+			"identity":      "",
+			"key_id":        "",
+			"overlaps_with": "",
+		},
+		"$inc": bson.M{"version": 1},
+	}
+}
+
+// SetPlate sets the plate, its raw value and its format of a transaction
+func (ac TransactionCollection) SetPlate(ctx context.Context, transaction *model.Transaction) error {
+	update := bson.M{
+		"$set": bson.M{
+			"matricula":     transaction.Matricula,
+			"matricula_raw": transaction.MatriculaRaw,
+			"plate_format":  transaction.PlateFormat,
+			"updated_at":    time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	_, err := ac.access.UpdateOne(ctx, bson.M{"_id": transaction.ID}, update)
+	if err != nil {
+		return errors.ErrorUpdating(transactionCollection, err)
+	}
+
+	return nil
+}
+
+// ListPresent returns the transactions of the vehicles parked in a park
+// during an hour, ordered by checkin
+func (ac TransactionCollection) ListPresent(ctx context.Context, parking int64, hour time.Time) ([]model.Transaction, error) {
+	filter := bson.M{
+		"parking_info.id":    parking,
+		"time_interval_hour": hour,
+		"deleted_at":         bson.M{"$exists": false},
+	}
+
+	sort := bson.D{{Key: "checkin_date", Value: 1}, {Key: "_id", Value: 1}}
+	cursor, err := ac.access.Find(ctx, filter, &options.FindOptions{Sort: sort})
 
 	if err != nil {
 		return nil, errors.ErrorListing(transactionCollection, err)
 	}
 
-	var transactions []model.Transaction
+	transactions := []model.Transaction{}
 	err = cursor.All(ctx, &transactions)
 	if err != nil {
 		return nil, errors.ErrorListing(transactionCollection, err)
@@ -210,17 +447,431 @@ func (ac TransactionCollection) List(ctx context.Context, page int64, size int64
 	return transactions, nil
 }
 
-// Count returns total
-func (ac TransactionCollection) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
+// Iterate streams the transactions matching filter, ordered by checkout,
+// calling fn for each one until it returns an error
+func (ac TransactionCollection) Iterate(ctx context.Context, filter model.TransactionFilter, fn func(transaction *model.Transaction) error) error {
+	sort := bson.D{{Key: "checkout_date", Value: 1}, {Key: "_id", Value: 1}}
+	cursor, err := ac.access.Find(ctx, transactionFilter(filter), &options.FindOptions{Sort: sort})
+	if err != nil {
+		return errors.ErrorListing(transactionCollection, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		transaction := new(model.Transaction)
+		err = cursor.Decode(transaction)
+		if err != nil {
+			return errors.ErrorListing(transactionCollection, err)
+		}
+
+		err = fn(transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = cursor.Err(); err != nil {
+		return errors.ErrorListing(transactionCollection, err)
+	}
+
+	return nil
+}
+
+// transactionFilter builds the query of a filter, skipping deleted documents
+func transactionFilter(filter model.TransactionFilter) bson.M {
+	query := bson.M{
+		"deleted_at": bson.M{"$exists": false},
+	}
+
+	if filter.ParkingID != nil {
+		query["parking_info.id"] = *filter.ParkingID
+	}
+
+	if filter.From != nil || filter.To != nil {
+		checkout := bson.M{}
+		if filter.From != nil {
+			checkout["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			checkout["$lt"] = *filter.To
+		}
+		query["checkout_date"] = checkout
+	}
+
+	if filter.Status != nil {
+		query["status"] = *filter.Status
+	}
+
+	if len(filter.Matriculas) > 0 {
+		query["matricula"] = bson.M{"$in": filter.Matriculas}
+	}
+
+	if filter.PaymentMethod != nil {
+		query["payment_method"] = *filter.PaymentMethod
+	}
+
+	if filter.UseType != nil {
+		query["use_type"] = *filter.UseType
+	}
+
+	return query
+}
+
+// MigrateSchema upgrades the transactions stored with an older schema,
+// returning how many were upgraded
+func (ac TransactionCollection) MigrateSchema(ctx context.Context) (int64, error) {
+	// schema 1 stored the bucket count in duration, schema 2 keeps it in
+	// buckets and the stay in minutes in duration
 	filter := bson.M{
+		"schema": 1,
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"buckets": "$duration",
+			"duration": bson.M{"$toLong": bson.M{"$trunc": bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{"$checkout_date", "$checkin_date"}},
+				60000,
+			}}}},
+			"schema": 2,
+		}}},
+	}
+
+	result, err := ac.access.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, errors.ErrorUpdating(transactionCollection, err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// CountByImport returns how many transactions of an import are not deleted
+func (ac TransactionCollection) CountByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	filter := bson.M{
+		"import_id":  importID,
 		"deleted_at": bson.M{"$exists": false},
 	}
 
 	counter, err := ac.access.CountDocuments(ctx, filter)
-
 	if err != nil {
 		return 0, errors.ErrorCounting(transactionCollection, err)
 	}
 
 	return counter, nil
 }
+
+// ExistsByImportLine returns whether the transaction of a line of an import
+// was persisted, created by the line or closed by it
+func (ac TransactionCollection) ExistsByImportLine(ctx context.Context, importID primitive.ObjectID, line int64) (bool, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"import_id": importID, "import_line": line},
+			bson.M{"closed_import_id": importID, "closed_import_line": line},
+		},
+		"deleted_at": bson.M{"$exists": false},
+	}
+
+	limit := int64(1)
+	counter, err := ac.access.CountDocuments(ctx, filter, &options.CountOptions{Limit: &limit})
+	if err != nil {
+		return false, errors.ErrorCounting(transactionCollection, err)
+	}
+
+	return counter > 0, nil
+}
+
+// ExistsByStatus returns whether a park has a transaction with status
+func (ac TransactionCollection) ExistsByStatus(ctx context.Context, parking int64, status int) (bool, error) {
+	filter := bson.M{
+		"parking_info.id": parking,
+		"status":          status,
+		"deleted_at":      bson.M{"$exists": false},
+	}
+
+	limit := int64(1)
+	counter, err := ac.access.CountDocuments(ctx, filter, &options.CountOptions{Limit: &limit})
+	if err != nil {
+		return false, errors.ErrorCounting(transactionCollection, err)
+	}
+
+	return counter > 0, nil
+}
+
+// CheckoutRangeByImport returns the first and last checkout dates of the
+// transactions an import created or closed that are not deleted, open
+// stays left out
+func (ac TransactionCollection) CheckoutRangeByImport(ctx context.Context, importID primitive.ObjectID) (time.Time, time.Time, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"$or": bson.A{
+				bson.M{"import_id": importID},
+				bson.M{"closed_import_id": importID},
+			},
+			"checkout_date": bson.M{"$gt": time.Time{}},
+			"deleted_at":    bson.M{"$exists": false},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  nil,
+			"from": bson.M{"$min": "$checkout_date"},
+			"to":   bson.M{"$max": "$checkout_date"},
+		}}},
+	}
+
+	cursor, err := ac.access.Aggregate(ctx, pipeline)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.ErrorListing(transactionCollection, err)
+	}
+
+	var result []struct {
+		From time.Time `bson:"from"`
+		To   time.Time `bson:"to"`
+	}
+	err = cursor.All(ctx, &result)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.ErrorListing(transactionCollection, err)
+	}
+
+	if len(result) == 0 {
+		return time.Time{}, time.Time{}, nil
+	}
+
+	return result[0].From, result[0].To, nil
+}
+
+// DeleteByImport deletes every transaction of an import (logically),
+// unlinking the stays they overlapped, and returns how many were deleted
+func (ac TransactionCollection) DeleteByImport(ctx context.Context, importID primitive.ObjectID) (int64, error) {
+	filter := bson.M{
+		"import_id":  importID,
+		"deleted_at": bson.M{"$exists": false},
+	}
+
+	ids, err := ac.listIDs(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{"deleted_at": now, "updated_at": now},
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := ac.access.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update)
+	if err != nil {
+		return 0, errors.ErrorDeleting(transactionCollection, err)
+	}
+
+	err = ac.unlinkOverlaps(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// listIDs returns the IDs of the transactions matching filter
+func (ac TransactionCollection) listIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	cursor, err := ac.access.Find(ctx, filter, &options.FindOptions{Projection: bson.M{"_id": 1}})
+	if err != nil {
+		return nil, errors.ErrorListing(transactionCollection, err)
+	}
+
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err = cursor.All(ctx, &found)
+	if err != nil {
+		return nil, errors.ErrorListing(transactionCollection, err)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(found))
+	for _, f := range found {
+		ids = append(ids, f.ID)
+	}
+
+	return ids, nil
+}
+
+// unlinkOverlaps drops the links of the other stays to the stays of ids,
+// setting back the status the ones overlapping no stay anymore had before
+// being flagged
+func (ac TransactionCollection) unlinkOverlaps(ctx context.Context, ids []primitive.ObjectID) error {
+	now := time.Now()
+	_, err := ac.access.UpdateMany(ctx, bson.M{"overlaps_with": bson.M{"$in": ids}}, bson.M{
+		"$pull": bson.M{"overlaps_with": bson.M{"$in": ids}},
+		"$set":  bson.M{"updated_at": now},
+		"$inc":  bson.M{"version": 1},
+	})
+	if err != nil {
+		return errors.ErrorUpdating(transactionCollection, err)
+	}
+
+	filter := bson.M{
+		"prior_status":  bson.M{"$exists": true},
+		"overlaps_with": bson.M{"$size": 0},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"status": "$prior_status", "updated_at": now}}},
+		{{Key: "$unset", Value: bson.A{"prior_status", "overlaps_with"}}},
+	}
+
+	_, err = ac.access.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return errors.ErrorUpdating(transactionCollection, err)
+	}
+
+	return nil
+}
+
+// ReopenByImport sets the stays an import closed back to status, open,
+// dropping what their checkout set and the overlap links to them, and
+// returns how many were reopened
+func (ac TransactionCollection) ReopenByImport(ctx context.Context, importID primitive.ObjectID, status int) (int64, error) {
+	filter := bson.M{
+		"closed_import_id": importID,
+		"deleted_at":       bson.M{"$exists": false},
+	}
+
+	ids, err := ac.listIDs(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":             status,
+			"checkout_date":      time.Time{},
+			"duration":           0,
+			"time_interval_hour": nil,
+			"buckets":            0,
+			"fare_amount":        0,
+			"paid_amount":        0,
+			"payment_method":     "",
+			"updated_at":         time.Now(),
+		},
+		"$unset": bson.M{
+			"closed_import_id":   "",
+			"closed_import_line": "",
+			"overlaps_with":      "",
+			"prior_status":       "",
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := ac.access.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update)
+	if err != nil {
+		return 0, errors.ErrorUpdating(transactionCollection, err)
+	}
+
+	err = ac.unlinkOverlaps(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// expiredFilter builds the query of the transactions of a park checked out
+// before checkoutBefore or deleted before deletedBefore.
+// A zero time leaves its transactions out
+func expiredFilter(parking int64, checkoutBefore time.Time, deletedBefore time.Time) bson.M {
+	expired := []bson.M{}
+	if !checkoutBefore.IsZero() {
+		expired = append(expired, checkedOutBefore(checkoutBefore))
+	}
+	if !deletedBefore.IsZero() {
+		expired = append(expired, bson.M{"deleted_at": bson.M{"$lt": deletedBefore}})
+	}
+	if len(expired) == 0 {
+		// nothing expires
+		expired = append(expired, bson.M{"_id": bson.M{"$exists": false}})
+	}
+
+	return bson.M{"parking_info.id": parking, "$or": expired}
+}
+
+// checkedOutBefore builds the query of the transactions not deleted checked
+// out before checkoutBefore, open stays, without checkout, are kept
+func checkedOutBefore(checkoutBefore time.Time) bson.M {
+	return bson.M{
+		"deleted_at":    bson.M{"$exists": false},
+		"checkout_date": bson.M{"$gt": time.Time{}, "$lt": checkoutBefore},
+	}
+}
+
+// CountExpired counts the transactions of a park checked out before
+// checkoutBefore and the ones deleted before deletedBefore, a zero time
+// counting none
+func (ac TransactionCollection) CountExpired(ctx context.Context, parking int64, checkoutBefore time.Time, deletedBefore time.Time) (int64, int64, error) {
+	var expired, deleted int64
+
+	if !checkoutBefore.IsZero() {
+		filter := checkedOutBefore(checkoutBefore)
+		filter["parking_info.id"] = parking
+
+		count, err := ac.access.CountDocuments(ctx, filter)
+		if err != nil {
+			return 0, 0, errors.ErrorCounting(transactionCollection, err)
+		}
+		expired = count
+	}
+
+	if !deletedBefore.IsZero() {
+		filter := bson.M{"parking_info.id": parking, "deleted_at": bson.M{"$lt": deletedBefore}}
+
+		count, err := ac.access.CountDocuments(ctx, filter)
+		if err != nil {
+			return 0, 0, errors.ErrorCounting(transactionCollection, err)
+		}
+		deleted = count
+	}
+
+	return expired, deleted, nil
+}
+
+// IterateExpired streams the transactions of a park CountExpired counts,
+// deleted or not, calling fn for each one until it returns an error
+func (ac TransactionCollection) IterateExpired(ctx context.Context, parking int64, checkoutBefore time.Time, deletedBefore time.Time, fn func(transaction *model.Transaction) error) error {
+	cursor, err := ac.access.Find(ctx, expiredFilter(parking, checkoutBefore, deletedBefore))
+	if err != nil {
+		return errors.ErrorListing(transactionCollection, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		transaction := new(model.Transaction)
+		err = cursor.Decode(transaction)
+		if err != nil {
+			return errors.ErrorListing(transactionCollection, err)
+		}
+
+		err = fn(transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = cursor.Err(); err != nil {
+		return errors.ErrorListing(transactionCollection, err)
+	}
+
+	return nil
+}
+
+// Purge removes the transactions of ids for good, unlike Delete, and
+// returns how many were removed
+func (ac TransactionCollection) Purge(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	result, err := ac.access.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, errors.ErrorDeleting(transactionCollection, err)
+	}
+
+	return result.DeletedCount, nil
+}
@@ -377,7 +377,7 @@ func TestTransaction_List(t *testing.T) {
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.create(&tc)
-			result, err := db.TransactionCollection.List(context.Background(), 1, 1, nil)
+			result, err := db.TransactionCollection.List(context.Background(), 1, 1, model.TransactionFilter{})
 
 			require.Equal(t, nil, err)
 			require.Equal(t, tc.total, len(result))
@@ -445,7 +445,7 @@ func TestTransaction_Count(t *testing.T) {
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.create(&tc)
-			result, err := db.TransactionCollection.Count(context.Background(), nil)
+			result, err := db.TransactionCollection.Count(context.Background(), model.TransactionFilter{})
 
 			require.Equal(t, nil, err)
 			require.Equal(t, tc.total, result)
@@ -454,3 +454,345 @@ func TestTransaction_Count(t *testing.T) {
 
 	require.Nil(t, DropDB(nil, nil))
 }
+
+func TestTransaction_ListFiltered(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	checkout := time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)
+	items := []*model.Transaction{
+		{ParkingInfo: model.Parking{ID: 6}, Matricula: "ABC1234", CheckoutDate: checkout.Add(2 * time.Hour)},
+		{ParkingInfo: model.Parking{ID: 6}, Matricula: "ABC1234", CheckoutDate: checkout},
+		{ParkingInfo: model.Parking{ID: 6}, Matricula: "DEF4567", CheckoutDate: checkout},
+		{ParkingInfo: model.Parking{ID: 7}, Matricula: "ABC1234", CheckoutDate: checkout},
+	}
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	parking := int64(6)
+	filter := model.TransactionFilter{ParkingID: &parking, Matriculas: []string{"ABC1234"}}
+
+	total, err := db.TransactionCollection.Count(context.Background(), filter)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), total)
+
+	first, err := db.TransactionCollection.List(context.Background(), 1, 1, filter)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(first))
+	require.Equal(t, items[1].ID, first[0].ID)
+
+	second, err := db.TransactionCollection.List(context.Background(), 2, 1, filter)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(second))
+	require.Equal(t, items[0].ID, second[0].ID)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestTransaction_Occupancy(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time {
+		return day.Add(time.Duration(h) * time.Hour)
+	}
+
+	items := []*model.Transaction{
+		{ParkingInfo: model.Parking{ID: 6}, UseType: "Avulso", TimeIntervalHour: []time.Time{hour(10), hour(11)}},
+		{ParkingInfo: model.Parking{ID: 6}, UseType: "Mensalista", TimeIntervalHour: []time.Time{hour(11)}},
+		{ParkingInfo: model.Parking{ID: 6}, UseType: "Avulso", TimeIntervalHour: []time.Time{hour(23), hour(24)}},
+		{ParkingInfo: model.Parking{ID: 7}, UseType: "Avulso", TimeIntervalHour: []time.Time{hour(11)}},
+	}
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	result, err := db.TransactionCollection.Occupancy(context.Background(), 6, day, day.AddDate(0, 0, 1))
+	require.Nil(t, err)
+	require.Equal(t, []model.HourOccupancy{
+		{Hour: hour(10), Vehicles: 1, UseTypes: map[string]int64{"Avulso": 1}},
+		{Hour: hour(11), Vehicles: 2, UseTypes: map[string]int64{"Avulso": 1, "Mensalista": 1}},
+		{Hour: hour(23), Vehicles: 1, UseTypes: map[string]int64{"Avulso": 1}},
+	}, result)
+
+	present, err := db.TransactionCollection.ListPresent(context.Background(), 6, hour(11))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(present))
+	require.Equal(t, items[0].ID, present[0].ID)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestTransaction_DeleteByImport(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	importID := primitive.NewObjectID()
+	checkout := time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	items := []*model.Transaction{
+		{ImportID: importID, CheckoutDate: checkout},
+		{ImportID: importID, CheckoutDate: checkout.AddDate(0, 0, 2)},
+		{ImportID: primitive.NewObjectID(), CheckoutDate: checkout},
+		// open, without checkout
+		{ImportID: importID, Status: 3},
+		// closed by the import
+		{ImportID: primitive.NewObjectID(), ClosedImportID: importID, ClosedImportLine: 3, CheckoutDate: checkout.AddDate(0, 0, 3), PaidAmount: 5},
+	}
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	// the stay of another import overlapping one of the import
+	require.Nil(t, db.TransactionCollection.MarkOverlap(context.Background(), items[0].ID, items[2].ID, 1))
+
+	total, err := db.TransactionCollection.CountByImport(context.Background(), importID)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), total)
+
+	from, to, err := db.TransactionCollection.CheckoutRangeByImport(context.Background(), importID)
+	require.Nil(t, err)
+	require.Equal(t, checkout, from.UTC())
+	require.Equal(t, checkout.AddDate(0, 0, 3), to.UTC())
+
+	deleted, err := db.TransactionCollection.DeleteByImport(context.Background(), importID)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), deleted)
+
+	flagged, err := db.TransactionCollection.GetByID(context.Background(), items[2].ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, 0, flagged.Status)
+	require.Nil(t, flagged.OverlapsWith)
+	require.Nil(t, flagged.PriorStatus)
+
+	reopened, err := db.TransactionCollection.ReopenByImport(context.Background(), importID, 3)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), reopened)
+
+	open, err := db.TransactionCollection.GetByID(context.Background(), items[4].ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, 3, open.Status)
+	require.True(t, open.CheckoutDate.IsZero())
+	require.Equal(t, float64(0), open.PaidAmount)
+	require.Equal(t, primitive.NilObjectID, open.ClosedImportID)
+
+	total, err = db.TransactionCollection.CountByImport(context.Background(), importID)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), total)
+
+	found, err := db.TransactionCollection.GetByID(context.Background(), items[0].ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, primitive.NilObjectID, found.ID)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestTransaction_ExistsByStatus(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	opened := primitive.NewObjectID()
+	closing := primitive.NewObjectID()
+
+	items := []*model.Transaction{
+		{ParkingInfo: model.Parking{ID: 6}, Status: 3, ImportID: opened, ImportLine: 1, ClosedImportID: closing, ClosedImportLine: 4},
+		{ParkingInfo: model.Parking{ID: 7}, Status: 0, ImportID: opened, ImportLine: 2},
+	}
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	exists, err := db.TransactionCollection.ExistsByStatus(context.Background(), 6, 3)
+	require.Nil(t, err)
+	require.True(t, exists)
+
+	exists, err = db.TransactionCollection.ExistsByStatus(context.Background(), 7, 3)
+	require.Nil(t, err)
+	require.False(t, exists)
+
+	exists, err = db.TransactionCollection.ExistsByImportLine(context.Background(), closing, 4)
+	require.Nil(t, err)
+	require.True(t, exists)
+
+	exists, err = db.TransactionCollection.ExistsByImportLine(context.Background(), closing, 1)
+	require.Nil(t, err)
+	require.False(t, exists)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestTransaction_Overlapping(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	stay := func(plate string, from int, to int) *model.Transaction {
+		return &model.Transaction{
+			ParkingInfo:  model.Parking{ID: 6},
+			Matricula:    plate,
+			CheckinDate:  day.Add(time.Duration(from) * time.Hour),
+			CheckoutDate: day.Add(time.Duration(to) * time.Hour),
+		}
+	}
+
+	items := []*model.Transaction{
+		stay("ABC1234", 10, 12),
+		stay("ABC1234", 12, 13),
+		stay("DEF4567", 10, 12),
+		{ParkingInfo: model.Parking{ID: 6}, Matricula: "ABC1234", CheckoutDate: day.Add(11 * time.Hour)},
+	}
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	incoming := stay("ABC1234", 11, 14)
+	_, err = db.TransactionCollection.Create(context.Background(), incoming)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	result, err := db.TransactionCollection.Overlapping(context.Background(), 6, []string{"ABC1234"}, incoming.CheckinDate, incoming.CheckoutDate, incoming.ID)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(result))
+	require.Equal(t, items[0].ID, result[0].ID)
+	require.Equal(t, items[1].ID, result[1].ID)
+
+	require.Nil(t, db.TransactionCollection.MarkOverlap(context.Background(), incoming.ID, items[0].ID, 1))
+
+	found, err := db.TransactionCollection.GetByID(context.Background(), items[0].ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, 1, found.Status)
+	require.Equal(t, []primitive.ObjectID{incoming.ID}, found.OverlapsWith)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestTransaction_ErasePlates(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	items := []*model.Transaction{
+		{ParkingInfo: model.Parking{ID: 6}, Matricula: "ABC1234", MatriculaRaw: "abc-1234", PlateFormat: "old", Identity: "12345678901"},
+		{ParkingInfo: model.Parking{ID: 7}, Matricula: "5f0e", KeyID: "2020", Identity: "9a1c"},
+		{ParkingInfo: model.Parking{ID: 6}, Matricula: "DEF4567"},
+	}
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	erased, err := db.TransactionCollection.ErasePlates(context.Background(), []string{"5f0e", "ABC1234"})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), erased)
+
+	for _, item := range items[:2] {
+		stored, err := db.TransactionCollection.GetByID(context.Background(), item.ID.Hex())
+		require.Nil(t, err)
+		require.Equal(t, "", stored.Matricula)
+		require.Equal(t, "", stored.MatriculaRaw)
+		require.Equal(t, "", stored.Identity)
+		require.Equal(t, "", stored.KeyID)
+	}
+
+	stored, err := db.TransactionCollection.GetByID(context.Background(), items[2].ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, "DEF4567", stored.Matricula)
+
+	require.Nil(t, DropDB(nil, nil))
+}
+
+func TestTransaction_Expired(t *testing.T) {
+	db, err := StartDB(nil, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	now := time.Now()
+	old := now.AddDate(-2, 0, 0)
+	deleted := now.AddDate(0, -2, 0)
+
+	items := []*model.Transaction{
+		{ParkingInfo: model.Parking{ID: 6}, CheckoutDate: old, Matricula: "ABC1234"},
+		{ParkingInfo: model.Parking{ID: 6}, CheckoutDate: now},
+		{ParkingInfo: model.Parking{ID: 6}, Status: 3},
+		{ParkingInfo: model.Parking{ID: 6}, CheckoutDate: old, DeletedAt: &deleted, Matricula: "DEF4567"},
+		{ParkingInfo: model.Parking{ID: 7}, CheckoutDate: old},
+	}
+	for _, item := range items {
+		_, err := db.TransactionCollection.Create(context.Background(), item)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	checkoutBefore, deletedBefore := now.AddDate(-1, 0, 0), now.AddDate(0, -1, 0)
+
+	expired, deletedCount, err := db.TransactionCollection.CountExpired(context.Background(), 6, checkoutBefore, deletedBefore)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), expired)
+	require.Equal(t, int64(1), deletedCount)
+
+	expired, deletedCount, err = db.TransactionCollection.CountExpired(context.Background(), 6, checkoutBefore, time.Time{})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), expired)
+	require.Equal(t, int64(0), deletedCount)
+
+	found := []model.Transaction{}
+	err = db.TransactionCollection.IterateExpired(context.Background(), 6, checkoutBefore, deletedBefore, func(transaction *model.Transaction) error {
+		found = append(found, *transaction)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 2, len(found))
+
+	require.Nil(t, db.ArchiveCollection.Archive(context.Background(), found))
+	require.Nil(t, db.ArchiveCollection.Archive(context.Background(), found))
+	archived, err := db.ArchiveCollection.Count(context.Background(), 6)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), archived)
+
+	erased, err := db.ArchiveCollection.ErasePlates(context.Background(), []string{"ABC1234"})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), erased)
+
+	purged, err := db.TransactionCollection.Purge(context.Background(), []primitive.ObjectID{found[0].ID, found[1].ID})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), purged)
+
+	expired, deletedCount, err = db.TransactionCollection.CountExpired(context.Background(), 6, checkoutBefore, deletedBefore)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), expired+deletedCount)
+
+	require.Nil(t, DropDB(nil, nil))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/csv-processor/business"
+)
+
+func occupancyCheckCommand() *command {
+	cmd := newCommand("occupancy-check", "lists the hours a park held more vehicles than its capacity, with the transactions likely to be wrong")
+
+	park := cmd.flags.String("park", "", "slug of the park in the registry")
+	from := cmd.flags.String("from", "", "first day (YYYY-MM-DD) to check, in the time zone of the park")
+	to := cmd.flags.String("to", "", "last day (YYYY-MM-DD) to check, in the time zone of the park")
+	maxStay := cmd.flags.Duration("max-stay", 24*time.Hour, "stays longer than this are suspected of a missing checkout, 0 disables it")
+
+	cmd.required = []string{"park", "from", "to"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		fromDay, err := parseDay("from", *from)
+		if err != nil {
+			return err
+		}
+
+		toDay, err := parseDay("to", *to)
+		if err != nil {
+			return err
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		found, err := business.FindPark(ctx, db.ParkingCollection, *park)
+		if err != nil {
+			return err
+		}
+
+		// the dates are stored as the wall clock of the park, written as UTC
+		start := fromDay
+		end := toDay.AddDate(0, 0, 1)
+
+		over, err := business.NewOccupancyCheck(db, *maxStay).OverOccupied(ctx, found, start, end)
+		if err != nil {
+			return err
+		}
+
+		if len(over) == 0 {
+			fmt.Printf("park %s was within its capacity from %s to %s\n", found.Slug, *from, *to)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "hour\tuse type\tvehicles\tcapacity\tticket\tplate\tcheckin\tcheckout\treasons\t")
+		for _, o := range over {
+			useType := o.UseType
+			if useType == "" {
+				useType = "all"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t\t\t\t\t\t\n", o.Hour.Format("2006-01-02 15:04"), useType, o.Vehicles, o.Capacity)
+
+			for _, suspect := range o.Suspects {
+				transaction := suspect.Transaction
+				fmt.Fprintf(w, "\t\t\t\t%s\t%s\t%s\t%s\t%s\t\n", transaction.Sequence, transaction.Matricula,
+					transaction.CheckinDate.Format("2006-01-02 15:04"),
+					transaction.CheckoutDate.Format("2006-01-02 15:04"),
+					strings.Join(suspect.Reasons, "; "))
+			}
+		}
+
+		return w.Flush()
+	}
+
+	return cmd
+}
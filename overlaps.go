@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/csv-processor/business"
+)
+
+func overlapsCommand() *command {
+	cmd := newCommand("overlaps", "lists the pairs of overlapping stays of the same plate in a park")
+
+	park := cmd.flags.String("park", "", "slug of the park in the registry")
+	from := cmd.flags.String("from", "", "first checkout day (YYYY-MM-DD) to list, in the time zone of the park")
+	to := cmd.flags.String("to", "", "last checkout day (YYYY-MM-DD) to list, in the time zone of the park")
+
+	cmd.required = []string{"park", "from", "to"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		fromDay, err := parseDay("from", *from)
+		if err != nil {
+			return err
+		}
+
+		toDay, err := parseDay("to", *to)
+		if err != nil {
+			return err
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		found, err := business.FindPark(ctx, db.ParkingCollection, *park)
+		if err != nil {
+			return err
+		}
+
+		// the dates are stored as the wall clock of the park, written as UTC
+		start := fromDay
+		end := toDay.AddDate(0, 0, 1)
+
+		pairs, err := business.NewOverlapReport(db).Overlaps(ctx, found.ID, start, end)
+		if err != nil {
+			return err
+		}
+
+		if len(pairs) == 0 {
+			fmt.Printf("park %s has no overlapping stays from %s to %s\n", found.Slug, *from, *to)
+			return nil
+		}
+
+		format := func(t time.Time) string {
+			return t.Format("2006-01-02 15:04")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "plate\tticket\tcheckin\tcheckout\tticket\tcheckin\tcheckout\t")
+		for _, pair := range pairs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", pair.First.Matricula,
+				pair.First.Sequence, format(pair.First.CheckinDate), format(pair.First.CheckoutDate),
+				pair.Second.Sequence, format(pair.Second.CheckinDate), format(pair.Second.CheckoutDate))
+		}
+
+		return w.Flush()
+	}
+
+	return cmd
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/model"
+)
+
+// settingsFlag is a repeated key=value flag, an empty value removing the key
+type settingsFlag map[string]string
+
+func (f settingsFlag) String() string {
+	settings := []string{}
+	for key, value := range f {
+		settings = append(settings, key+"="+value)
+	}
+	sort.Strings(settings)
+
+	return strings.Join(settings, ",")
+}
+
+func (f settingsFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("setting [%s] is not key=value", value)
+	}
+	f[parts[0]] = parts[1]
+
+	return nil
+}
+
+// capacitiesFlag is a repeated use-type=capacity flag, 0 removing the use type
+type capacitiesFlag map[string]int64
+
+func (f capacitiesFlag) String() string {
+	capacities := []string{}
+	for useType, capacity := range f {
+		capacities = append(capacities, useType+"="+strconv.FormatInt(capacity, 10))
+	}
+	sort.Strings(capacities)
+
+	return strings.Join(capacities, ",")
+}
+
+func (f capacitiesFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("capacity [%s] is not use-type=capacity", value)
+	}
+
+	capacity, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("error parsing capacity [%s]: [%s]", value, err.Error())
+	}
+	f[parts[0]] = capacity
+
+	return nil
+}
+
+// parkFlags are the fields of a park of the registry
+type parkFlags struct {
+	id         *int64
+	name       *string
+	timezone   *string
+	capacity   *int64
+	capacities capacitiesFlag
+	address    *string
+	settings   settingsFlag
+	// retention and deletedRetention are the days the transactions and
+	// the deleted transactions are kept
+	retention        *int64
+	deletedRetention *int64
+}
+
+func addParkFlags(cmd *command) *parkFlags {
+	f := &parkFlags{
+		id:         cmd.flags.Int64("id", 0, "the id of the park"),
+		name:       cmd.flags.String("name", "", "the name of the park, defaults to its slug"),
+		timezone:   cmd.flags.String("timezone", "", "IANA time zone of the park, such as America/Sao_Paulo, UTC when empty"),
+		capacity:   cmd.flags.Int64("capacity", 0, "how many vehicles fit in the park, 0 when unknown"),
+		address:    cmd.flags.String("address", "", "the address of the park"),
+		capacities: capacitiesFlag{},
+		settings:   settingsFlag{},
+
+		retention:        cmd.flags.Int64("retention-days", 0, "days the transactions are kept after their checkout, forever when 0"),
+		deletedRetention: cmd.flags.Int64("deleted-retention-days", 0, "days the deleted transactions are kept, forever when 0"),
+	}
+	cmd.flags.Var(f.capacities, "use-capacity", "use-type=capacity spaces kept for a use type, such as Mensalista=40, repeated for more, 0 removes it")
+	cmd.flags.Var(f.settings, "set", "key=value setting of the park, repeated for more, an empty value removes it")
+
+	return f
+}
+
+// apply sets the fields given on the command line on park
+func (f *parkFlags) apply(cmd *command, park *model.Park) {
+	if cmd.seen["id"] {
+		park.ID = *f.id
+	}
+	if cmd.seen["name"] {
+		park.Name = *f.name
+	}
+	if cmd.seen["timezone"] {
+		park.Timezone = *f.timezone
+	}
+	if cmd.seen["capacity"] {
+		park.Capacity = *f.capacity
+	}
+	if cmd.seen["address"] {
+		park.Address = *f.address
+	}
+	if cmd.seen["retention-days"] {
+		park.RetentionDays = *f.retention
+	}
+	if cmd.seen["deleted-retention-days"] {
+		park.DeletedRetentionDays = *f.deletedRetention
+	}
+
+	for useType, capacity := range f.capacities {
+		if park.Capacities == nil {
+			park.Capacities = map[string]int64{}
+		}
+		if capacity == 0 {
+			delete(park.Capacities, useType)
+			continue
+		}
+		park.Capacities[useType] = capacity
+	}
+	if len(park.Capacities) == 0 {
+		park.Capacities = nil
+	}
+
+	for key, value := range f.settings {
+		if park.Config == nil {
+			park.Config = map[string]string{}
+		}
+		if value == "" {
+			delete(park.Config, key)
+			continue
+		}
+		park.Config[key] = value
+	}
+	if len(park.Config) == 0 {
+		park.Config = nil
+	}
+}
+
+func parksCommand() *command {
+	cmd := newCommand("parks", "lists the parks of the registry")
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		parks, err := db.ParkingCollection.List(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing parks: [%s]", err.Error())
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "id\tslug\tname\ttimezone\tcapacity\taddress\t")
+		for _, p := range parks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t\n", p.ID, p.Slug, p.Name, p.Timezone, p.Capacity, p.Address)
+		}
+
+		return w.Flush()
+	}
+
+	return cmd
+}
+
+func parkShowCommand() *command {
+	cmd := newCommand("park-show", "prints a park of the registry as JSON")
+	cmd.arguments = "<slug>"
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		park, err := business.FindPark(ctx, db.ParkingCollection, cmd.flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(park)
+	}
+
+	return cmd
+}
+
+func parkAddCommand() *command {
+	cmd := newCommand("park-add", "adds a park to the registry")
+	cmd.arguments = "<slug>"
+
+	fields := addParkFlags(cmd)
+	cmd.required = []string{"id"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		park := &model.Park{Slug: cmd.flags.Arg(0)}
+		fields.apply(cmd, park)
+		if park.Name == "" {
+			park.Name = park.Slug
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		_, err = db.ParkingCollection.Create(ctx, park)
+		if err != nil {
+			return fmt.Errorf("error adding park [%s]: [%s]", park.Slug, err.Error())
+		}
+
+		log.Sugar().Infow("Park added", "id", park.ID, "slug", park.Slug, "name", park.Name)
+
+		return nil
+	}
+
+	return cmd
+}
+
+func parkUpdateCommand() *command {
+	cmd := newCommand("park-update", "changes the fields of a park of the registry given as flags")
+	cmd.arguments = "<slug>"
+
+	fields := addParkFlags(cmd)
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		if cmd.seen["id"] {
+			return fmt.Errorf("the id of a park can not change, transactions refer to it")
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		park, err := business.FindPark(ctx, db.ParkingCollection, cmd.flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		fields.apply(cmd, park)
+		_, err = db.ParkingCollection.Update(ctx, park)
+		if err != nil {
+			return fmt.Errorf("error updating park [%s]: [%s]", park.Slug, err.Error())
+		}
+
+		log.Sugar().Infow("Park updated", "id", park.ID, "slug", park.Slug, "version", park.Version)
+
+		return nil
+	}
+
+	return cmd
+}
+
+func parkRemoveCommand() *command {
+	cmd := newCommand("park-remove", "removes a park from the registry, its transactions are kept")
+	cmd.arguments = "<slug>"
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		slug := cmd.flags.Arg(0)
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		removed, err := db.ParkingCollection.Delete(ctx, slug)
+		if err != nil {
+			return fmt.Errorf("error removing park [%s]: [%s]", slug, err.Error())
+		}
+		if !removed {
+			return fmt.Errorf("unknown park [%s]", slug)
+		}
+
+		log.Sugar().Infow("Park removed", "slug", slug)
+
+		return nil
+	}
+
+	return cmd
+}
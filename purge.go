@@ -0,0 +1,98 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/model"
+)
+
+func purgeCommand() *command {
+	cmd := newCommand("purge", "removes the transactions past the retention policy of their park for good")
+
+	park := cmd.flags.String("park", "", "slug of the park in the registry, every park when empty")
+	archive := cmd.flags.String("archive", business.ArchiveNone, "where the transactions are archived before being removed: none, jsonl or collection")
+	archiveFile := cmd.flags.String("archive-file", "", "gzipped JSONL file the transactions are archived to with -archive jsonl")
+	dryRun := cmd.flags.Bool("dry-run", false, "only count the expired transactions")
+
+	cmd.run = func(ctx context.Context, cmd *command) (err error) {
+		switch *archive {
+		case business.ArchiveNone, business.ArchiveCollection:
+		case business.ArchiveJSONL:
+			if *archiveFile == "" {
+				return fmt.Errorf("-archive-file is required to archive to jsonl")
+			}
+		default:
+			return fmt.Errorf("archive [%s] must be none, jsonl or collection", *archive)
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		parks := []model.Park{}
+		if *park != "" {
+			found, err := business.FindPark(ctx, db.ParkingCollection, *park)
+			if err != nil {
+				return err
+			}
+			parks = append(parks, *found)
+		} else {
+			parks, err = db.ParkingCollection.List(ctx)
+			if err != nil {
+				return fmt.Errorf("error listing parks: [%s]", err.Error())
+			}
+		}
+
+		var archiver business.Archiver
+		switch {
+		case *dryRun:
+		case *archive == business.ArchiveCollection:
+			archiver = business.NewCollectionArchive(db)
+		case *archive == business.ArchiveJSONL:
+			// each purge appends a gzip member, the file still reads as one
+			// stream and the archives of earlier purges are kept
+			var file *os.File
+			file, err = os.OpenFile(*archiveFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				return fmt.Errorf("error opening file [%s]: [%s]", *archiveFile, err.Error())
+			}
+
+			gz := gzip.NewWriter(file)
+			archiver = business.NewJSONLArchive(gz)
+
+			// the purged transactions were flushed already, the trailer is
+			// written even when the purge fails
+			defer func() {
+				closeErr := gz.Close()
+				if fileErr := file.Close(); closeErr == nil {
+					closeErr = fileErr
+				}
+				if closeErr != nil && err == nil {
+					err = fmt.Errorf("error closing file [%s]: [%s]", *archiveFile, closeErr.Error())
+				}
+			}()
+		}
+
+		purge := business.NewPurge(db, archiver)
+		now := time.Now()
+		for _, p := range parks {
+			summary, err := purge.Purge(ctx, p, now, *dryRun)
+			if err != nil {
+				return err
+			}
+
+			log.Sugar().Infow("Park purged", "park", p.Slug, "expired", summary.Expired, "deleted", summary.Deleted,
+				"purged", summary.Purged, "dry_run", *dryRun)
+		}
+
+		return nil
+	}
+
+	return cmd
+}
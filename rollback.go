@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/csv-processor/business"
+)
+
+func rollbackCommand() *command {
+	cmd := newCommand("rollback", "deletes every transaction created by an import")
+	cmd.arguments = "<import-id>"
+
+	dryRun := cmd.flags.Bool("dry-run", false, "only count the transactions that would be deleted")
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		importID := cmd.flags.Arg(0)
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		record, total, err := business.NewRollback(db).Rollback(ctx, importID, *dryRun)
+		if err != nil {
+			return fmt.Errorf("error rolling back import [%s]: [%s]", importID, err.Error())
+		}
+
+		if *dryRun {
+			fmt.Printf("import %s of %s (%s) would delete %d transactions\n", importID, record.Path, record.ParkingInfo.Slug, total)
+			return nil
+		}
+
+		fmt.Printf("import %s of %s (%s) rolled back, %d transactions deleted, %d reopened\n", importID, record.Path, record.ParkingInfo.Slug, total, record.Reopened)
+
+		return nil
+	}
+
+	return cmd
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/csv-processor/business"
+)
+
+func rebuildRollupsCommand() *command {
+	cmd := newCommand("rebuild-rollups", "recomputes the daily revenue of a park from its transactions")
+
+	parkid := cmd.flags.Int64("parkid", 0, "the id of park to rebuild")
+	from := cmd.flags.String("from", "", "first day (YYYY-MM-DD) to rebuild")
+	to := cmd.flags.String("to", "", "last day (YYYY-MM-DD) to rebuild")
+
+	cmd.required = []string{"parkid", "from", "to"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		fromDay, err := parseDay("from", *from)
+		if err != nil {
+			return err
+		}
+
+		toDay, err := parseDay("to", *to)
+		if err != nil {
+			return err
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		park, err := db.ParkingCollection.GetByID(ctx, *parkid)
+		if err != nil {
+			return fmt.Errorf("error getting park [%d]: [%s]", *parkid, err.Error())
+		}
+
+		// the rollups of the days purge may have removed transactions of
+		// would lose their revenue
+		kept := business.RebuildFrom(*park, fromDay, time.Now())
+		if kept.After(toDay) {
+			return fmt.Errorf("transactions of park [%d] before [%s] may have been purged, their rollups can not be rebuilt", *parkid, kept.Format("2006-01-02"))
+		}
+		if kept.After(fromDay) {
+			log.Sugar().Warnw("Rollups of purged days not rebuilt", "parkid", *parkid, "from", *from, "until", kept.Format("2006-01-02"))
+			fromDay = kept
+		}
+
+		total, err := db.DailyRevenueCollection.Rebuild(ctx, *parkid, fromDay, toDay)
+		if err != nil {
+			return fmt.Errorf("error rebuilding rollups: [%s]", err.Error())
+		}
+
+		log.Sugar().Infow("Rollups rebuilt", "parkid", *parkid, "from", *from, "to", *to, "rollups", total)
+
+		return nil
+	}
+
+	return cmd
+}
+
+func reportCommand() *command {
+	cmd := newCommand("report", "prints the daily revenue of a park per payment method")
+
+	parkid := cmd.flags.Int64("parkid", 0, "the id of park to report")
+	from := cmd.flags.String("from", "", "first day (YYYY-MM-DD) to report")
+	to := cmd.flags.String("to", "", "last day (YYYY-MM-DD) to report")
+
+	cmd.required = []string{"parkid", "from", "to"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		fromDay, err := parseDay("from", *from)
+		if err != nil {
+			return err
+		}
+
+		toDay, err := parseDay("to", *to)
+		if err != nil {
+			return err
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		revenues, err := db.DailyRevenueCollection.List(ctx, *parkid, fromDay, toDay)
+		if err != nil {
+			return fmt.Errorf("error listing rollups: [%s]", err.Error())
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "day\tpayment method\ttickets\tpaid\tdiscount\taverage stay (min)\t")
+		for _, r := range revenues {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.2f\t%.2f\t%.1f\t\n",
+				r.Day.Format("2006-01-02"), r.PaymentMethod, r.Tickets, r.PaidTotal, r.DiscountTotal, r.AverageStay())
+		}
+
+		return w.Flush()
+	}
+
+	return cmd
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/csv-processor/api"
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/ingest"
+	"google.golang.org/grpc"
+)
+
+// shutdownTimeout is how long the requests in flight are waited for on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// jobsDir is where serve keeps the files of the jobs unless told otherwise
+var jobsDir = filepath.Join(os.TempDir(), "csv-processor")
+
+func serveCommand() *command {
+	cmd := newCommand("serve", "serves the HTTP API uploading files of parks, importing them in the background, and querying transactions")
+
+	addr := cmd.flags.String("addr", ":8080", "address to listen on")
+	grpcAddr := cmd.flags.String("grpc-addr", "", "address the gRPC ingestion service listens on, disabled when empty")
+	dir := cmd.flags.String("dir", jobsDir, "directory keeping the uploaded files and the rejected rows of the jobs")
+	queueSize := cmd.flags.Int("queue", 16, "jobs waiting for a worker, uploads are refused once full")
+	workers := cmd.flags.Int("workers", 2, "jobs imported at the same time")
+	retention := cmd.flags.Duration("retention", 24*time.Hour, "how long finished jobs and their rejected rows are kept, 0 keeps them")
+	maxUpload := cmd.flags.Int64("max-upload", 100, "largest file uploaded, in megabytes")
+	bucketing := addBucketingFlags(cmd)
+	operator := cmd.flags.String("operator", "serve", "who the imports are recorded as run by in the import ledger")
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		bucket, err := bucketing.bucketing(cmd)
+		if err != nil {
+			return err
+		}
+
+		if *queueSize < 1 || *workers < 1 {
+			return fmt.Errorf("queue [%d] and workers [%d] must be at least 1", *queueSize, *workers)
+		}
+
+		if *retention < 0 {
+			return fmt.Errorf("retention [%s] can not be negative", retention.String())
+		}
+
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		pseudonymizer := business.NewPseudonymizer(cmd.cfg.Privacy)
+		importer := business.NewImporter(db, bucket, business.ImportOptions{Operator: *operator, Pseudonymizer: pseudonymizer})
+		queue := business.NewJobQueue(importer, *dir, *queueSize, *workers, *retention)
+
+		// canceling the command cancels the running jobs, whose imports
+		// can be resumed from the CLI
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			queue.Run(ctx)
+		}()
+
+		server := &http.Server{
+			Addr:    *addr,
+			Handler: api.NewHandler(queue, db.TransactionCollection, db.ParkingCollection, business.NewVisitHistory(db, cmd.cfg.Privacy), cmd.cfg, api.Options{MaxUpload: *maxUpload << 20}),
+		}
+
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		if *grpcAddr != "" {
+			listener, err := net.Listen("tcp", *grpcAddr)
+			if err != nil {
+				return fmt.Errorf("error listening on [%s]: [%s]", *grpcAddr, err.Error())
+			}
+
+			grpcServer := grpc.NewServer()
+			ingest.RegisterIngestServer(grpcServer, ingest.NewServer(business.NewIngester(db, bucket, pseudonymizer)))
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := grpcServer.Serve(listener); err != nil {
+					log.Sugar().Errorw("error serving gRPC", "addr", *grpcAddr, "error", err.Error())
+					cancel()
+				}
+			}()
+			go func() {
+				<-ctx.Done()
+				grpcServer.GracefulStop()
+			}()
+		}
+
+		log.Sugar().Infow("Serving", "addr", *addr, "grpc-addr", *grpcAddr, "dir", *dir, "queue", *queueSize, "workers", *workers, "retention", *retention)
+
+		err = server.ListenAndServe()
+		cancel()
+		wg.Wait()
+		if err != http.ErrServerClosed {
+			return fmt.Errorf("error serving [%s]: [%s]", *addr, err.Error())
+		}
+
+		return nil
+	}
+
+	return cmd
+}
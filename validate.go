@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/csv-processor/business"
+	"github.com/csv-processor/config"
+	"github.com/csv-processor/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func validateCommand() *command {
+	cmd := newCommand("validate", "checks a file would import cleanly, without touching the database")
+
+	processFile := cmd.flags.String("csvFile", "", "path to file with CSV to check, gzip, zstd and zip compressed files and xlsx spreadsheets included, - reads the standard input")
+	filetype := cmd.flags.String("filetype", "transactions", "file type to be checked")
+	parkslug := cmd.flags.String("parkslug", "", "the slug of the park whose configured dialect the file is read in")
+	dialect := addDialectFlags(cmd)
+	bucketing := addBucketingFlags(cmd)
+
+	cmd.required = []string{"csvFile"}
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		bucket, err := bucketing.bucketing(cmd)
+		if err != nil {
+			return err
+		}
+
+		inputs, err := business.Inputs(*processFile, os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		invalid := int64(0)
+		for _, input := range inputs {
+			report, err := validateInput(ctx, input, *filetype, dialect.apply(cmd, cmd.cfg.DialectOf(*parkslug)), bucket)
+			if err != nil {
+				return err
+			}
+
+			if len(inputs) > 1 {
+				fmt.Printf("%s\n", input.Path)
+			}
+			fmt.Printf("rows: %d\nvalid: %d\nskipped: %d\ninvalid: %d\n", report.Rows, report.Valid, report.Skipped, report.Invalid)
+			for _, e := range report.Errors {
+				fmt.Println(e)
+			}
+
+			invalid += report.Invalid
+		}
+
+		if invalid > 0 {
+			return fmt.Errorf("file [%s] has %d invalid rows", *processFile, invalid)
+		}
+
+		return nil
+	}
+
+	return cmd
+}
+
+func validateInput(ctx context.Context, input business.Input, filetype string, dialect config.Dialect, bucket business.Bucketing) (*business.ValidationReport, error) {
+	reader, in, err := input.Rows(dialect)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	processor := business.NewVP(nil, reader, filetype, model.Parking{}, bucket, primitive.NilObjectID)
+	report, err := processor.Validate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error validating file [%s]: [%s]", input.Path, err.Error())
+	}
+
+	return report, nil
+}
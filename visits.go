@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/csv-processor/business"
+)
+
+func visitsCommand() *command {
+	cmd := newCommand("visits", "prints the visits of a plate across the parks as JSON")
+	cmd.arguments = "<plate>"
+
+	cmd.run = func(ctx context.Context, cmd *command) error {
+		db, err := cmd.connect()
+		if err != nil {
+			return err
+		}
+
+		profile, err := business.NewVisitHistory(db, cmd.cfg.Privacy).Profile(ctx, cmd.flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		if profile.Visits == 0 {
+			return fmt.Errorf("plate [%s] has no visits", profile.Plate)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(profile)
+	}
+
+	return cmd
+}